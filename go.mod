@@ -8,6 +8,7 @@ require (
 	github.com/a-h/templ v0.3.937 // indirect
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/r3labs/sse/v2 v2.10.0 // indirect
-	golang.org/x/net v0.42.0 // indirect
+	golang.org/x/net v0.42.0
+	golang.org/x/text v0.27.0
 	gopkg.in/cenkalti/backoff.v1 v1.1.0 // indirect
 )
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"mesh/src"
 	"mesh/src/components"
+	"mesh/src/services"
 	"net/http"
 	"os"
 )
@@ -13,8 +14,20 @@ func main() {
 	// Create logger
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
+	// Load configuration from the environment
+	config := services.LoadConfig()
+
 	// Create registry with all handlers
-	registry := components.NewRegistry(logger)
+	registry := components.NewRegistry(logger, config)
+
+	// Reload the blacklist when it changes or on SIGHUP
+	registry.WordService.Start()
+
+	// Purge expired cards from the trash in the background
+	registry.RetentionService.Start()
+	registry.SchedulerService.Start()
+	registry.AutomationService.Start()
+	registry.WebhookService.Start()
 
 	// Serve static files
 	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("./static"))))
@@ -25,8 +38,35 @@ func main() {
 	// Route handlers with registry context middleware
 	http.Handle("/app", registry.AppHandler)
 	http.Handle("/board", registry.BoardHandler)
+	http.Handle("/board/export", registry.BoardHandler)
 	http.Handle("/column", registry.ColumnHandler)
+	http.Handle("/cell", registry.CellHandler)
+	http.Handle("/lane", registry.LaneHandler)
 	http.Handle("/card", registry.CardHandler)
+	http.Handle("/attachment", registry.AttachmentHandler)
+	http.Handle("/comment", registry.CommentHandler)
+	http.Handle("/activity", registry.ActivityHandler)
+	http.Handle("/undo", registry.UndoHandler)
+	http.Handle("/redo", registry.UndoHandler)
+	http.Handle("/trash", registry.TrashHandler)
+	http.Handle("/archive", registry.ArchiveHandler)
+	http.Handle("/moderation", registry.ModerationHandler)
+	http.Handle("/admin", registry.AdminHandler)
+	http.Handle("/admin/export", registry.AdminHandler)
+	http.Handle("/admin/import", registry.AdminHandler)
+	http.Handle("/admin/blacklist", registry.AdminHandler)
+	http.Handle("/search", registry.SearchHandler)
+	http.Handle("/filter", registry.FilterHandler)
+	http.Handle("/templates", registry.TemplatesHandler)
+	http.Handle("/recurrence", registry.RecurrenceHandler)
+	http.Handle("/automations", registry.AutomationsHandler)
+	http.Handle("/webhooks", registry.WebhooksHandler)
+	http.Handle("/inbound", registry.InboundHandler)
+	http.Handle("/inbound/cards", registry.InboundHandler)
+	http.Handle("/analytics", registry.AnalyticsHandler)
+	http.Handle("/analytics/report", registry.AnalyticsHandler)
+	http.Handle("/analytics/cfd.csv", registry.AnalyticsHandler)
+	http.Handle("/analytics/throughput.csv", registry.AnalyticsHandler)
 
 	// SSE endpoint for real-time updates
 	http.HandleFunc("/sse", registry.SSEService.ServeSSE)
@@ -0,0 +1,74 @@
+package activity
+
+import (
+	"log/slog"
+	"mesh/src/components/base"
+	"mesh/src/services"
+	"net/http"
+	"strconv"
+
+	"github.com/a-h/templ"
+)
+
+const pageSize = 10
+
+type Handler struct {
+	*base.BaseHandler
+	*services.ActivityService
+}
+
+func New(
+	log *slog.Logger,
+	eventService *services.EventService,
+	sessionService *services.SessionService,
+	activityService *services.ActivityService,
+) *Handler {
+	return &Handler{
+		BaseHandler:     base.NewBaseHandler(log, "activity", eventService, sessionService),
+		ActivityService: activityService,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.BaseHandler.ServeHTTP(w, r, map[string]http.HandlerFunc{
+		http.MethodGet: h.Get,
+	})
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	// A missing card ID means the board-wide feed
+	boardID := h.BoardID(r)
+	cardID, _ := strconv.Atoi(r.FormValue("cardID"))
+	page, err := strconv.Atoi(r.FormValue("page"))
+	if err != nil {
+		page = 1
+	}
+
+	if r.FormValue("open") != "1" {
+		h.RenderTemplate(r.Context(), w, h.RenderComponent(boardID, cardID))
+		return
+	}
+
+	h.RenderTemplate(r.Context(), w, h.RenderComponentForPage(boardID, cardID, page))
+}
+
+// RenderComponent renders the collapsed panel, which loads its entries when opened
+func (h *Handler) RenderComponent(boardID, cardID int) templ.Component {
+	return Activity(ActivityProps{BoardID: boardID, CardID: cardID})
+}
+
+func (h *Handler) RenderComponentForPage(boardID, cardID, page int) templ.Component {
+	var activityPage services.ActivityPage
+	if cardID == 0 {
+		activityPage = h.ActivityService.GetFeed(boardID, page, pageSize)
+	} else {
+		activityPage = h.ActivityService.GetCardHistory(cardID, page, pageSize)
+	}
+
+	return Activity(ActivityProps{
+		BoardID: boardID,
+		CardID:  cardID,
+		Open:   true,
+		Page:   activityPage,
+	})
+}
@@ -0,0 +1,392 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mesh/src/components/base"
+	"mesh/src/services"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+)
+
+// maxDocumentSize is the largest file that can be imported
+const maxDocumentSize = 10 << 20
+
+// Handler serves the admin panel on /admin, board documents on /admin/export, imports board documents, Trello
+// exports and CSV files as new boards on /admin/import, and reloads the blacklist on /admin/blacklist
+type Handler struct {
+	*base.BaseHandler
+	*services.CardService
+	WordService *services.WordService
+}
+
+func New(
+	log *slog.Logger,
+	eventService *services.EventService,
+	sessionService *services.SessionService,
+	cardService *services.CardService,
+	wordService *services.WordService,
+) *Handler {
+	return &Handler{
+		BaseHandler: base.NewBaseHandler(log, "admin", eventService, sessionService),
+		CardService: cardService,
+		WordService: wordService,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/export"):
+		h.BaseHandler.ServeHTTP(w, r, map[string]http.HandlerFunc{
+			http.MethodGet: h.GetExport,
+		})
+		return
+	case strings.HasSuffix(r.URL.Path, "/import"):
+		h.BaseHandler.ServeHTTP(w, r, map[string]http.HandlerFunc{
+			http.MethodPost: h.PostImport,
+		})
+		return
+	case strings.HasSuffix(r.URL.Path, "/blacklist"):
+		h.BaseHandler.ServeHTTP(w, r, map[string]http.HandlerFunc{
+			http.MethodPost: h.PostBlacklist,
+		})
+		return
+	}
+
+	h.BaseHandler.ServeHTTP(w, r, map[string]http.HandlerFunc{
+		http.MethodGet:    h.Get,
+		http.MethodPost:   h.Post,
+		http.MethodPatch:  h.Patch,
+		http.MethodDelete: h.Delete,
+	})
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	session := h.SessionService.Session(w, r)
+	boardID := h.BoardID(r)
+
+	if r.FormValue("open") != "1" {
+		h.RenderTemplate(r.Context(), w, h.RenderComponent(boardID))
+		return
+	}
+	h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(session, boardID, ""))
+}
+
+// Post signs the session in as an admin
+func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
+	session := h.SessionService.Session(w, r)
+	boardID := h.BoardID(r)
+
+	if !h.SessionService.Elevate(session, r.FormValue("token")) {
+		h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(session, boardID, "Invalid admin token"))
+		return
+	}
+	h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(session, boardID, ""))
+}
+
+// Patch changes a column's WIP limit
+func (h *Handler) Patch(w http.ResponseWriter, r *http.Request) {
+	session := h.SessionService.Session(w, r)
+	boardID := h.BoardID(r)
+
+	if !session.IsAdmin {
+		http.Error(w, "Admin access required", http.StatusForbidden)
+		return
+	}
+
+	columnID, err := strconv.Atoi(r.FormValue("columnID"))
+	if err != nil {
+		http.Error(w, "Invalid column ID", http.StatusBadRequest)
+		return
+	}
+	if column, err := h.CardService.GetColumn(columnID); err != nil || column.Column.BoardID != boardID {
+		http.Error(w, "Column not found", http.StatusNotFound)
+		return
+	}
+
+	limit := 0
+	if value := r.FormValue("wipLimit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil {
+			h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(session, boardID, "WIP limit must be a number"))
+			return
+		}
+	}
+
+	mode := services.WIPMode(r.FormValue("wipMode"))
+	if err := h.CardService.SetWIPLimit(columnID, limit, mode); err != nil {
+		h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(session, boardID, err.Error()))
+		return
+	}
+
+	h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(session, boardID, ""))
+	h.EventService.PublishColumnChanged(session.Name, columnID)
+}
+
+// GetExport downloads the board as a board document
+func (h *Handler) GetExport(w http.ResponseWriter, r *http.Request) {
+	session := h.SessionService.Session(w, r)
+	boardID := h.BoardID(r)
+
+	if !session.IsAdmin {
+		http.Error(w, "Admin access required", http.StatusForbidden)
+		return
+	}
+
+	document, err := h.CardService.ExportBoard(boardID, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("board-%d.json", boardID)))
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		h.Log.Error("Failed to write board document", "boardID", boardID, "error", err)
+	}
+}
+
+// PostImport creates a new board from an uploaded file: a board document, a Trello export, or a CSV file, which
+// comes back first with its headers so that each card field can be given a column
+func (h *Handler) PostImport(w http.ResponseWriter, r *http.Request) {
+	session := h.SessionService.Session(w, r)
+
+	if !session.IsAdmin {
+		http.Error(w, "Admin access required", http.StatusForbidden)
+		return
+	}
+
+	// The body is limited before any of the form is read, so that a file that's too large is reported as such
+	r.Body = http.MaxBytesReader(w, r.Body, maxDocumentSize)
+	err := r.ParseMultipartForm(32 << 10)
+	if errors.Is(err, http.ErrNotMultipart) {
+		err = r.ParseForm()
+	}
+	boardID := h.BoardID(r)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			message := fmt.Sprintf("The file is too large to import, the limit is %d MB", maxDocumentSize>>20)
+			h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(session, boardID, message))
+			return
+		}
+		h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(session, boardID, "Could not read the upload"))
+		return
+	}
+	format := r.FormValue("format")
+
+	// The mapping step sends the CSV file back as a field rather than a file
+	if format == "csv" && r.FormValue("csv") != "" {
+		h.importCSV(w, r, session, boardID, []byte(r.FormValue("csv")))
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(session, boardID, "Choose a file to import"))
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(session, boardID, "The file is too large to import"))
+		return
+	}
+
+	summary := &services.ImportSummary{}
+	switch format {
+	case "trello":
+		var document *services.BoardDocument
+		if document, err = services.ConvertTrelloBoard(data, summary); err == nil {
+			err = h.CardService.ImportConverted(document, summary)
+		}
+	case "csv":
+		headers, err := services.ReadCSVHeader(data)
+		if err != nil {
+			h.renderImportError(w, r, session, boardID, err)
+			return
+		}
+		props := h.getProps(session, boardID, "")
+		props.CSVImport = &CSVImport{
+			Title:   strings.TrimSuffix(header.Filename, filepath.Ext(header.Filename)),
+			Data:    string(data),
+			Headers: headers,
+			Mapping: guessMapping(headers),
+		}
+		h.RenderTemplate(r.Context(), w, Admin(props))
+		return
+	default:
+		var document *services.BoardDocument
+		if document, err = services.ParseBoardDocument(data); err == nil {
+			summary.Board, err = h.CardService.ImportBoard(document)
+			summary.Imported = len(document.Board.Cards)
+		}
+	}
+	if err != nil {
+		h.renderImportError(w, r, session, boardID, err)
+		return
+	}
+
+	props := h.getProps(session, boardID, "")
+	props.ImportSummary = summary
+	h.RenderTemplate(r.Context(), w, Admin(props))
+}
+
+// importCSV imports a CSV file with the mapping chosen for it
+func (h *Handler) importCSV(w http.ResponseWriter, r *http.Request, session *services.Session, boardID int, data []byte) {
+	mapping := services.CSVMapping{
+		Title:    r.FormValue("mapTitle"),
+		Content:  r.FormValue("mapContent"),
+		Column:   r.FormValue("mapColumn"),
+		Lane:     r.FormValue("mapLane"),
+		Labels:   r.FormValue("mapLabels"),
+		Assignee: r.FormValue("mapAssignee"),
+		DueAt:    r.FormValue("mapDueAt"),
+	}
+
+	summary := &services.ImportSummary{}
+	document, err := services.ConvertCSV(data, r.FormValue("title"), mapping, summary)
+	if err == nil {
+		err = h.CardService.ImportConverted(document, summary)
+	}
+	if err != nil {
+		props := h.getProps(session, boardID, err.Error())
+		headers, _ := services.ReadCSVHeader(data)
+		props.CSVImport = &CSVImport{Title: r.FormValue("title"), Data: string(data), Headers: headers, Mapping: mapping}
+		h.RenderTemplate(r.Context(), w, Admin(props))
+		return
+	}
+
+	props := h.getProps(session, boardID, "")
+	props.ImportSummary = summary
+	h.RenderTemplate(r.Context(), w, Admin(props))
+}
+
+// renderImportError shows why a file couldn't be imported, with the path to each problem in a board document
+func (h *Handler) renderImportError(w http.ResponseWriter, r *http.Request, session *services.Session, boardID int, err error) {
+	props := h.getProps(session, boardID, "The file could not be imported")
+	var documentErrors services.DocumentErrors
+	if errors.As(err, &documentErrors) {
+		for _, documentError := range documentErrors {
+			props.ImportErrors = append(props.ImportErrors, documentError.Error())
+		}
+	} else {
+		props.ImportErrors = []string{err.Error()}
+	}
+	h.RenderTemplate(r.Context(), w, Admin(props))
+}
+
+// guessMapping picks the CSV column for each card field by its header, as exported by the usual tools
+func guessMapping(headers []string) services.CSVMapping {
+	var mapping services.CSVMapping
+	guesses := []struct {
+		field *string
+		names []string
+	}{
+		{&mapping.Title, []string{"title", "name", "summary", "card", "card name", "task"}},
+		{&mapping.Content, []string{"content", "description", "desc", "details", "notes"}},
+		{&mapping.Column, []string{"column", "status", "list", "list name", "state", "stage"}},
+		{&mapping.Lane, []string{"lane", "swimlane", "team", "category"}},
+		{&mapping.Labels, []string{"labels", "label", "tags"}},
+		{&mapping.Assignee, []string{"assignee", "owner", "assigned to", "members"}},
+		{&mapping.DueAt, []string{"due", "due date", "due at", "deadline"}},
+	}
+	for _, guess := range guesses {
+		for _, header := range headers {
+			if slices.Contains(guess.names, strings.ToLower(strings.TrimSpace(header))) {
+				*guess.field = header
+				break
+			}
+		}
+	}
+	return mapping
+}
+
+// PostBlacklist reloads the blacklist from its file. Scripts can call it with the admin token as a bearer token
+// and get JSON back; from the panel it takes an admin session.
+func (h *Handler) PostBlacklist(w http.ResponseWriter, r *http.Request) {
+	if bearer, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); found {
+		if !h.SessionService.IsAdminToken(bearer) {
+			h.writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "invalid admin token"})
+			return
+		}
+		if err := h.WordService.ReloadBlacklist(); err != nil {
+			h.writeJSON(w, http.StatusUnprocessableEntity, map[string]any{
+				"error":   err.Error(),
+				"entries": h.WordService.EntryCount(),
+			})
+			return
+		}
+		h.writeJSON(w, http.StatusOK, map[string]any{"entries": h.WordService.EntryCount()})
+		return
+	}
+
+	session := h.SessionService.Session(w, r)
+	boardID := h.BoardID(r)
+
+	if !session.IsAdmin {
+		http.Error(w, "Admin access required", http.StatusForbidden)
+		return
+	}
+
+	if err := h.WordService.ReloadBlacklist(); err != nil {
+		h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(session, boardID, "Kept the previous blacklist: "+err.Error()))
+		return
+	}
+	props := h.getProps(session, boardID, "")
+	props.Message = fmt.Sprintf("Reloaded the blacklist with %d entries", props.BlacklistEntries)
+	h.RenderTemplate(r.Context(), w, Admin(props))
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		h.Log.Error("Failed to write admin response", "error", err)
+	}
+}
+
+// Delete signs the session out of admin
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	session := h.SessionService.Session(w, r)
+
+	h.SessionService.Demote(session)
+	h.RenderTemplate(r.Context(), w, h.RenderComponent(h.BoardID(r)))
+}
+
+// RenderComponent renders the collapsed panel, which loads its settings when opened
+func (h *Handler) RenderComponent(boardID int) templ.Component {
+	return Admin(AdminProps{BoardID: boardID})
+}
+
+func (h *Handler) RenderOpenComponent(session *services.Session, boardID int, errorMessage string) templ.Component {
+	return Admin(h.getProps(session, boardID, errorMessage))
+}
+
+func (h *Handler) getProps(session *services.Session, boardID int, errorMessage string) AdminProps {
+	props := AdminProps{
+		BoardID: boardID,
+		Open:    true,
+		IsAdmin: session.IsAdmin,
+		Error:   errorMessage,
+	}
+	if session.IsAdmin {
+		for _, column := range h.CardService.GetColumns(boardID) {
+			props.Columns = append(props.Columns, column.Column)
+		}
+		props.BlacklistEntries = h.WordService.EntryCount()
+	}
+	return props
+}
@@ -0,0 +1,288 @@
+package analytics
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"mesh/src/components/base"
+	"mesh/src/services"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+)
+
+// defaultRange is how far back the analytics look when no dates are given
+const defaultRange = 30 * 24 * time.Hour
+
+// Handler serves the analytics panel on /analytics, the same figures as JSON on /analytics/report, and the
+// cumulative flow and throughput as CSV on /analytics/cfd.csv and /analytics/throughput.csv
+type Handler struct {
+	*base.BaseHandler
+	AnalyticsService *services.AnalyticsService
+	FlowService      *services.FlowService
+	CardService      *services.CardService
+}
+
+func New(
+	log *slog.Logger,
+	eventService *services.EventService,
+	sessionService *services.SessionService,
+	analyticsService *services.AnalyticsService,
+	flowService *services.FlowService,
+	cardService *services.CardService,
+) *Handler {
+	return &Handler{
+		BaseHandler:      base.NewBaseHandler(log, "analytics", eventService, sessionService),
+		AnalyticsService: analyticsService,
+		FlowService:      flowService,
+		CardService:      cardService,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var get http.HandlerFunc
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/report"):
+		get = h.GetReport
+	case strings.HasSuffix(r.URL.Path, "/cfd.csv"):
+		get = h.GetCumulativeFlowCSV
+	case strings.HasSuffix(r.URL.Path, "/throughput.csv"):
+		get = h.GetThroughputCSV
+	}
+	if get != nil {
+		h.BaseHandler.ServeHTTP(w, r, map[string]http.HandlerFunc{
+			http.MethodGet: get,
+		})
+		return
+	}
+
+	h.BaseHandler.ServeHTTP(w, r, map[string]http.HandlerFunc{
+		http.MethodGet: h.Get,
+	})
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	boardID := h.BoardID(r)
+	if r.FormValue("open") != "1" {
+		h.RenderTemplate(r.Context(), w, h.RenderComponent(boardID))
+		return
+	}
+	h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(boardID, r.FormValue("from"), r.FormValue("to")))
+}
+
+// parseRange reads the inclusive dates to report on, defaulting to the last 30 days. The range it returns ends
+// at the start of the day after the last one.
+func parseRange(fromValue, toValue string, now time.Time) (time.Time, time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	from, to := today.Add(-defaultRange), today
+
+	var err error
+	if fromValue != "" {
+		if from, err = time.ParseInLocation(services.DueDateLayout, fromValue, time.Local); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid from date %q", fromValue)
+		}
+	}
+	if toValue != "" {
+		if to, err = time.ParseInLocation(services.DueDateLayout, toValue, time.Local); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid to date %q", toValue)
+		}
+	}
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("the from date must be before the to date")
+	}
+	return from, to.AddDate(0, 0, 1), nil
+}
+
+type percentilesJSON struct {
+	Count      int   `json:"count"`
+	P50Seconds int64 `json:"p50Seconds"`
+	P85Seconds int64 `json:"p85Seconds"`
+	P95Seconds int64 `json:"p95Seconds"`
+}
+
+type columnJSON struct {
+	ID             int    `json:"id"`
+	Title          string `json:"title"`
+	AverageSeconds int64  `json:"averageSeconds"`
+}
+
+type cardJSON struct {
+	ID               int           `json:"id"`
+	Title            string        `json:"title"`
+	CreatedAt        time.Time     `json:"createdAt"`
+	StartedAt        *time.Time    `json:"startedAt,omitempty"`
+	DoneAt           *time.Time    `json:"doneAt,omitempty"`
+	LeadTimeSeconds  *int64        `json:"leadTimeSeconds,omitempty"`
+	CycleTimeSeconds *int64        `json:"cycleTimeSeconds,omitempty"`
+	InColumnSeconds  map[int]int64 `json:"inColumnSeconds"`
+}
+
+type reportJSON struct {
+	BoardID    int             `json:"boardID"`
+	From       string          `json:"from"`
+	To         string          `json:"to"`
+	LeadTime   percentilesJSON `json:"leadTime"`
+	CycleTime  percentilesJSON `json:"cycleTime"`
+	Columns    []columnJSON    `json:"columns"`
+	Done       []cardJSON      `json:"done"`
+	InProgress []cardJSON      `json:"inProgress"`
+}
+
+// GetReport returns the board's analytics as JSON, with durations in whole seconds
+func (h *Handler) GetReport(w http.ResponseWriter, r *http.Request) {
+	boardID := h.BoardID(r)
+	from, to, err := parseRange(r.FormValue("from"), r.FormValue("to"), time.Now())
+	if err != nil {
+		h.writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+
+	report := h.AnalyticsService.GetReport(boardID, from, to)
+	body := reportJSON{
+		BoardID:    report.BoardID,
+		From:       report.From.Format(services.DueDateLayout),
+		To:         report.To.AddDate(0, 0, -1).Format(services.DueDateLayout),
+		LeadTime:   toPercentilesJSON(report.LeadTime),
+		CycleTime:  toPercentilesJSON(report.CycleTime),
+		Columns:    []columnJSON{},
+		Done:       []cardJSON{},
+		InProgress: []cardJSON{},
+	}
+	for _, column := range report.Columns {
+		body.Columns = append(body.Columns, columnJSON{
+			ID:             column.ID,
+			Title:          column.Title,
+			AverageSeconds: seconds(report.AverageInColumn[column.ID]),
+		})
+	}
+	for _, times := range report.Done {
+		body.Done = append(body.Done, toCardJSON(times))
+	}
+	for _, times := range report.InProgress {
+		body.InProgress = append(body.InProgress, toCardJSON(times))
+	}
+	h.writeJSON(w, http.StatusOK, body)
+}
+
+func toPercentilesJSON(p services.Percentiles) percentilesJSON {
+	return percentilesJSON{
+		Count:      p.Count,
+		P50Seconds: seconds(p.P50),
+		P85Seconds: seconds(p.P85),
+		P95Seconds: seconds(p.P95),
+	}
+}
+
+func toCardJSON(times services.CardTimes) cardJSON {
+	card := cardJSON{
+		ID:              times.CardID,
+		Title:           times.Title,
+		CreatedAt:       times.CreatedAt,
+		InColumnSeconds: make(map[int]int64),
+	}
+	if times.IsStarted() {
+		card.StartedAt = &times.StartedAt
+	}
+	if times.IsDone() {
+		lead, cycle := seconds(times.LeadTime), seconds(times.CycleTime)
+		card.DoneAt = &times.DoneAt
+		card.LeadTimeSeconds = &lead
+		card.CycleTimeSeconds = &cycle
+	}
+	for columnID, spent := range times.InColumn {
+		card.InColumnSeconds[columnID] = seconds(spent)
+	}
+	return card
+}
+
+func seconds(d time.Duration) int64 {
+	return int64(d / time.Second)
+}
+
+// GetCumulativeFlowCSV returns a row for each day with the cards in each column at the end of it
+func (h *Handler) GetCumulativeFlowCSV(w http.ResponseWriter, r *http.Request) {
+	boardID := h.BoardID(r)
+	from, to, err := parseRange(r.FormValue("from"), r.FormValue("to"), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	columns := h.CardService.GetColumns(boardID)
+	header := []string{"day"}
+	for _, column := range columns {
+		header = append(header, column.Column.Title)
+	}
+	rows := [][]string{header}
+	for _, snapshot := range h.FlowService.GetSnapshots(boardID, from, to) {
+		row := []string{snapshot.Day.Format(services.DueDateLayout)}
+		for _, column := range columns {
+			row = append(row, strconv.Itoa(snapshot.Counts[column.Column.ID]))
+		}
+		rows = append(rows, row)
+	}
+	h.writeCSV(w, fmt.Sprintf("board-%d-cumulative-flow.csv", boardID), rows)
+}
+
+// GetThroughputCSV returns a row for each week with the cards that reached the last column in it
+func (h *Handler) GetThroughputCSV(w http.ResponseWriter, r *http.Request) {
+	boardID := h.BoardID(r)
+	from, to, err := parseRange(r.FormValue("from"), r.FormValue("to"), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rows := [][]string{{"week", "done"}}
+	for _, week := range h.FlowService.GetThroughput(boardID, from, to) {
+		rows = append(rows, []string{week.Week.Format(services.DueDateLayout), strconv.Itoa(week.Done)})
+	}
+	h.writeCSV(w, fmt.Sprintf("board-%d-throughput.csv", boardID), rows)
+}
+
+func (h *Handler) writeCSV(w http.ResponseWriter, filename string, rows [][]string) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	if err := csv.NewWriter(w).WriteAll(rows); err != nil {
+		h.Log.Error("Failed to write analytics CSV", "filename", filename, "error", err)
+	}
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		h.Log.Error("Failed to write analytics response", "error", err)
+	}
+}
+
+// RenderComponent renders the collapsed panel
+func (h *Handler) RenderComponent(boardID int) templ.Component {
+	return Analytics(AnalyticsProps{BoardID: boardID})
+}
+
+// RenderOpenComponent renders the board's analytics for the dates given, which may be empty for the defaults
+func (h *Handler) RenderOpenComponent(boardID int, fromValue, toValue string) templ.Component {
+	props := AnalyticsProps{
+		BoardID: boardID,
+		Open:    true,
+		From:    fromValue,
+		To:      toValue,
+	}
+
+	from, to, err := parseRange(fromValue, toValue, time.Now())
+	if err != nil {
+		props.Error = err.Error()
+		return Analytics(props)
+	}
+	props.Report = h.AnalyticsService.GetReport(boardID, from, to)
+	props.Snapshots = h.FlowService.GetSnapshots(boardID, from, to)
+	props.Throughput = h.FlowService.GetThroughput(boardID, from, to)
+	props.From = from.Format(services.DueDateLayout)
+	props.To = to.AddDate(0, 0, -1).Format(services.DueDateLayout)
+	return Analytics(props)
+}
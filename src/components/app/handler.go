@@ -19,10 +19,11 @@ type Handler struct {
 func New(
 	log *slog.Logger,
 	eventService *services.EventService,
+	sessionService *services.SessionService,
 	boardHandler *board.Handler,
 ) *Handler {
 	return &Handler{
-		BaseHandler:  base.NewBaseHandler(log, "app", eventService),
+		BaseHandler:  base.NewBaseHandler(log, "app", eventService, sessionService),
 		BoardHandler: boardHandler,
 	}
 }
@@ -34,11 +35,12 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
-	h.RenderTemplate(r.Context(), w, h.RenderComponent())
+	session := h.SessionService.Session(w, r)
+	h.RenderTemplate(r.Context(), w, h.RenderComponent(h.BoardID(r), session.ID))
 }
 
-func (h *Handler) RenderComponent() templ.Component {
-	boardComponent := h.BoardHandler.RenderComponent()
+func (h *Handler) RenderComponent(boardID int, sessionID string) templ.Component {
+	boardComponent := h.BoardHandler.RenderComponentForBoard(boardID, sessionID)
 	props := AppProps{
 		BoardComponent: boardComponent,
 	}
@@ -0,0 +1,98 @@
+package archive
+
+import (
+	"errors"
+	"log/slog"
+	"mesh/src/components/base"
+	"mesh/src/services"
+	"net/http"
+	"strconv"
+
+	"github.com/a-h/templ"
+)
+
+type Handler struct {
+	*base.BaseHandler
+	*services.CardService
+}
+
+func New(
+	log *slog.Logger,
+	eventService *services.EventService,
+	sessionService *services.SessionService,
+	cardService *services.CardService,
+) *Handler {
+	return &Handler{
+		BaseHandler: base.NewBaseHandler(log, "archive", eventService, sessionService),
+		CardService: cardService,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.BaseHandler.ServeHTTP(w, r, map[string]http.HandlerFunc{
+		http.MethodGet:  h.Get,
+		http.MethodPost: h.Post,
+	})
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	boardID := h.BoardID(r)
+	if r.FormValue("open") != "1" {
+		h.RenderTemplate(r.Context(), w, h.RenderComponent(boardID))
+		return
+	}
+	h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(boardID, r.FormValue("q"), ""))
+}
+
+// Post returns an archived card to its column
+func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
+	actor := h.Actor(w, r)
+	boardID := h.BoardID(r)
+	query := r.FormValue("q")
+
+	cardID, err := strconv.Atoi(r.FormValue("cardID"))
+	if err != nil {
+		http.Error(w, "Invalid card ID", http.StatusBadRequest)
+		return
+	}
+
+	card, err := h.CardService.GetCard(cardID)
+	if err != nil {
+		http.Error(w, "Card not found", http.StatusNotFound)
+		return
+	}
+	if column, err := h.CardService.GetColumn(card.ColumnID); err != nil || column.Column.BoardID != boardID {
+		http.Error(w, "Card not found", http.StatusNotFound)
+		return
+	}
+
+	err = h.CardService.UnarchiveCard(cardID)
+	var limitErr *services.WIPLimitError
+	if errors.As(err, &limitErr) {
+		h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(boardID, query, "Could not unarchive card: "+limitErr.Error()))
+		return
+	}
+	if err != nil {
+		h.Log.Error("Failed to unarchive card", "cardID", cardID, "error", err)
+		h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(boardID, query, "Could not unarchive card"))
+		return
+	}
+
+	h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(boardID, query, ""))
+	h.EventService.PublishCardRestored(actor, card.ID, card.Cell())
+}
+
+// RenderComponent renders the collapsed panel, which loads its cards when opened
+func (h *Handler) RenderComponent(boardID int) templ.Component {
+	return Archive(ArchiveProps{BoardID: boardID})
+}
+
+func (h *Handler) RenderOpenComponent(boardID int, query, errorMessage string) templ.Component {
+	return Archive(ArchiveProps{
+		BoardID: boardID,
+		Open:    true,
+		Query:   query,
+		Cards:   h.CardService.GetArchive(boardID, query),
+		Error:   errorMessage,
+	})
+}
@@ -0,0 +1,174 @@
+package attachment
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"mesh/src/components/base"
+	"mesh/src/components/card"
+	"mesh/src/services"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+type Handler struct {
+	*base.BaseHandler
+	*services.AttachmentService
+	CardService *services.CardService
+	CardHandler *card.Handler
+}
+
+func New(
+	log *slog.Logger,
+	eventService *services.EventService,
+	sessionService *services.SessionService,
+	attachmentService *services.AttachmentService,
+	cardService *services.CardService,
+	cardHandler *card.Handler,
+) *Handler {
+	return &Handler{
+		BaseHandler:       base.NewBaseHandler(log, "attachment", eventService, sessionService),
+		AttachmentService: attachmentService,
+		CardService:       cardService,
+		CardHandler:       cardHandler,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.BaseHandler.ServeHTTP(w, r, map[string]http.HandlerFunc{
+		http.MethodGet:    h.Get,
+		http.MethodPost:   h.Post,
+		http.MethodDelete: h.Delete,
+	})
+}
+
+func (h *Handler) getAttachmentFromRequest(r *http.Request) (*services.Attachment, error) {
+	attachmentIDString := r.FormValue("attachmentID")
+	if attachmentIDString == "" {
+		return nil, fmt.Errorf("missing attachment ID")
+	}
+
+	attachmentID, err := strconv.Atoi(attachmentIDString)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attachment ID %s", attachmentIDString)
+	}
+
+	attachment, err := h.AttachmentService.GetAttachment(attachmentID)
+	if err != nil {
+		return nil, fmt.Errorf("attachment not found %d", attachmentID)
+	}
+
+	return attachment, nil
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	attachment, err := h.getAttachmentFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	file, err := h.AttachmentService.Open(attachment)
+	if err != nil {
+		h.Log.Error("Failed to open attachment", "attachmentID", attachment.ID, "error", err)
+		http.Error(w, "Attachment not available", http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+
+	// Only images are safe to display inline - everything else is downloaded
+	disposition := "attachment"
+	if attachment.IsImage() {
+		disposition = "inline"
+	}
+
+	w.Header().Set("Content-Type", attachment.MimeType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{
+		"filename": attachment.Name,
+	}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, attachment.Name, attachment.CreatedAt, file)
+}
+
+func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
+	actor := h.Actor(w, r)
+
+	// Leave some headroom for the other multipart fields
+	r.Body = http.MaxBytesReader(w, r.Body, h.AttachmentService.MaxSize()+1<<20)
+
+	if err := r.ParseMultipartForm(32 << 10); err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			http.Error(w, "File is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Invalid upload", http.StatusBadRequest)
+		return
+	}
+
+	cardID, err := strconv.Atoi(r.FormValue("cardID"))
+	if err != nil {
+		http.Error(w, "Invalid card ID", http.StatusNotFound)
+		return
+	}
+
+	card, err := h.CardService.GetCard(cardID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		h.RenderTemplate(r.Context(), w, h.CardHandler.RenderComponentWithAttachmentError(card, "Please choose a file"))
+		return
+	}
+	defer file.Close()
+
+	_, err = h.AttachmentService.AddAttachment(card.ID, header.Filename, file)
+	if err != nil {
+		var message string
+		switch {
+		case errors.Is(err, services.ErrAttachmentTooLarge):
+			message = fmt.Sprintf("File must be smaller than %d MB", h.AttachmentService.MaxSize()>>20)
+		case errors.Is(err, services.ErrAttachmentType):
+			message = "That type of file can't be attached"
+		default:
+			h.Log.Error("Failed to add attachment", "cardID", card.ID, "error", err)
+			message = "Something went wrong attaching that file"
+		}
+		h.RenderTemplate(r.Context(), w, h.CardHandler.RenderComponentWithAttachmentError(card, message))
+		return
+	}
+
+	h.RenderTemplate(r.Context(), w, h.CardHandler.RenderComponent(card))
+
+	h.EventService.PublishCardChanged(actor, *card)
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	actor := h.Actor(w, r)
+
+	attachment, err := h.getAttachmentFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	card, err := h.CardService.GetCard(attachment.CardID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	err = h.AttachmentService.DeleteAttachment(attachment.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.RenderTemplate(r.Context(), w, h.CardHandler.RenderComponent(card))
+
+	h.EventService.PublishCardChanged(actor, *card)
+}
@@ -0,0 +1,179 @@
+package automations
+
+import (
+	"log/slog"
+	"mesh/src/components/base"
+	"mesh/src/services"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/a-h/templ"
+)
+
+// runLimit is how many recent executions the panel shows
+const runLimit = 20
+
+type Handler struct {
+	*base.BaseHandler
+	AutomationService *services.AutomationService
+	CardService       *services.CardService
+	WordService       *services.WordService
+}
+
+func New(
+	log *slog.Logger,
+	eventService *services.EventService,
+	sessionService *services.SessionService,
+	automationService *services.AutomationService,
+	cardService *services.CardService,
+	wordService *services.WordService,
+) *Handler {
+	return &Handler{
+		BaseHandler:       base.NewBaseHandler(log, "automations", eventService, sessionService),
+		AutomationService: automationService,
+		CardService:       cardService,
+		WordService:       wordService,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.BaseHandler.ServeHTTP(w, r, map[string]http.HandlerFunc{
+		http.MethodGet:    h.Get,
+		http.MethodPost:   h.Post,
+		http.MethodPatch:  h.Patch,
+		http.MethodDelete: h.Delete,
+	})
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	boardID := h.BoardID(r)
+	if r.FormValue("open") != "1" {
+		h.RenderTemplate(r.Context(), w, h.RenderComponent(boardID))
+		return
+	}
+	h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(boardID, ""))
+}
+
+// validate reads a rule from the form, returning a message explaining the first problem with it
+func (h *Handler) validate(r *http.Request) (services.AutomationRule, string) {
+	formInt := func(name string) int {
+		value, _ := strconv.Atoi(r.FormValue(name))
+		return value
+	}
+
+	rule := services.AutomationRule{
+		BoardID: h.BoardID(r),
+		Name:    strings.TrimSpace(r.FormValue("name")),
+		Trigger: services.Trigger{
+			Kind:     services.TriggerKind(r.FormValue("trigger")),
+			ColumnID: formInt("triggerColumnID"),
+			Label:    strings.TrimSpace(r.FormValue("triggerLabel")),
+		},
+		Condition: services.Condition{
+			Label:    strings.TrimSpace(r.FormValue("conditionLabel")),
+			Assignee: strings.TrimSpace(r.FormValue("conditionAssignee")),
+			LaneID:   formInt("conditionLaneID"),
+		},
+		Action: services.Action{
+			Kind:      services.ActionKind(r.FormValue("action")),
+			ColumnID:  formInt("actionColumnID"),
+			Assignee:  strings.TrimSpace(r.FormValue("assignee")),
+			Label:     strings.TrimSpace(r.FormValue("label")),
+			DueInDays: formInt("dueInDays"),
+			Comment:   strings.TrimSpace(r.FormValue("comment")),
+		},
+		Enabled: true,
+	}
+
+	switch {
+	case rule.Name == "":
+		return rule, "Name is required"
+	case len(rule.Name) > 50:
+		return rule, "Name must be less than 50 characters"
+	case rule.Action.DueInDays < 0 || rule.Action.DueInDays > 365:
+		return rule, "Due date must be within a year"
+	case len(rule.Action.Comment) > 500:
+		return rule, "Comment must be less than 500 characters"
+	}
+
+	text := strings.Join([]string{
+		rule.Name, rule.Trigger.Label, rule.Action.Assignee, rule.Action.Label, rule.Action.Comment,
+	}, " ")
+	if blacklistedWord := h.WordService.Filter(text); blacklistedWord != "" {
+		return rule, "Let's keep it light shall we"
+	}
+	return rule, ""
+}
+
+func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
+	rule, message := h.validate(r)
+	if message == "" {
+		if _, err := h.AutomationService.AddRule(rule); err != nil {
+			message = err.Error()
+		}
+	}
+	h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(rule.BoardID, message))
+}
+
+// Patch switches a rule on or off
+func (h *Handler) Patch(w http.ResponseWriter, r *http.Request) {
+	rule, ok := h.rule(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.AutomationService.SetRuleEnabled(rule.ID, r.FormValue("enabled") == "1"); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(rule.BoardID, ""))
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	rule, ok := h.rule(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.AutomationService.DeleteRule(rule.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(rule.BoardID, ""))
+}
+
+func (h *Handler) rule(w http.ResponseWriter, r *http.Request) (*services.AutomationRule, bool) {
+	ruleID, err := strconv.Atoi(r.FormValue("ruleID"))
+	if err != nil {
+		http.Error(w, "Invalid rule ID", http.StatusBadRequest)
+		return nil, false
+	}
+	rule, err := h.AutomationService.GetRule(ruleID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	return rule, true
+}
+
+// RenderComponent renders the collapsed panel
+func (h *Handler) RenderComponent(boardID int) templ.Component {
+	return Automations(AutomationsProps{BoardID: boardID})
+}
+
+// RenderOpenComponent renders the board's rules and their most recent runs
+func (h *Handler) RenderOpenComponent(boardID int, errorMessage string) templ.Component {
+	props := AutomationsProps{
+		BoardID: boardID,
+		Open:    true,
+		Rules:   h.AutomationService.GetRules(boardID),
+		Runs:    h.AutomationService.GetRuns(boardID, runLimit),
+		Lanes:   h.CardService.GetLanes(boardID),
+		Error:   errorMessage,
+	}
+	for _, column := range h.CardService.GetColumns(boardID) {
+		props.Columns = append(props.Columns, column.Column)
+	}
+	return Automations(props)
+}
@@ -5,21 +5,29 @@ import (
 	"log/slog"
 	"mesh/src/services"
 	"net/http"
+	"strconv"
 
 	"github.com/a-h/templ"
 )
 
 type BaseHandler struct {
-	Log          *slog.Logger
-	name         string
-	EventService *services.EventService
+	Log            *slog.Logger
+	name           string
+	EventService   *services.EventService
+	SessionService *services.SessionService
 }
 
-func NewBaseHandler(log *slog.Logger, name string, eventService *services.EventService) *BaseHandler {
+func NewBaseHandler(
+	log *slog.Logger,
+	name string,
+	eventService *services.EventService,
+	sessionService *services.SessionService,
+) *BaseHandler {
 	return &BaseHandler{
-		Log:          log,
-		name:         name,
-		EventService: eventService,
+		Log:            log,
+		name:           name,
+		EventService:   eventService,
+		SessionService: sessionService,
 	}
 }
 
@@ -31,6 +39,20 @@ func (h *BaseHandler) ServeHTTP(w http.ResponseWriter, r *http.Request, f map[st
 	}
 }
 
+// Actor names whoever is making the request, for attributing events
+func (h *BaseHandler) Actor(w http.ResponseWriter, r *http.Request) string {
+	return h.SessionService.Session(w, r).Name
+}
+
+// BoardID returns the board the request refers to, defaulting to the default board
+func (h *BaseHandler) BoardID(r *http.Request) int {
+	boardID, err := strconv.Atoi(r.FormValue("boardID"))
+	if err != nil {
+		return services.DefaultBoardID
+	}
+	return boardID
+}
+
 func (h *BaseHandler) RenderTemplate(
 	ctx context.Context,
 	w http.ResponseWriter,
@@ -1,5 +1,16 @@
 import {MeshElement} from "../base/mesh-element.ts";
 
 export class Board extends MeshElement {
+    // Moving the history slider shows where it is; letting go of it loads the board as it was then
+    scrub(e: Event) {
+        const slider = e.target as HTMLInputElement;
+        const at = new Date(Number(slider.value));
+        this.one('.time-travel [name="at"]', el => (el as HTMLInputElement).value = at.toISOString());
+        this.one('.time-travel output', el => el.textContent = at.toLocaleString());
+    }
+
+    travel(e: Event) {
+        (e.target as HTMLInputElement).form?.requestSubmit();
+    }
 }
-window.customElements.define('mesh-board', Board);
\ No newline at end of file
+window.customElements.define('mesh-board', Board);
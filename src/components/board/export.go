@@ -0,0 +1,240 @@
+package board
+
+import (
+	"encoding/csv"
+	"fmt"
+	"mesh/src/services"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportedCard is a card as it appears in an export, with where it sits on the board
+type exportedCard struct {
+	services.Card
+	Column   string
+	Lane     string
+	Position int // from 1, within its cell; archived cards have none
+}
+
+// GetExport writes the board as Markdown or CSV for pasting elsewhere, through the session's active filter. Like
+// the board, it leaves out archived cards unless archived=1, when they're listed after the rest.
+func (h *Handler) GetExport(w http.ResponseWriter, r *http.Request) {
+	session := h.SessionService.Session(w, r)
+	boardID := h.BoardID(r)
+
+	board, err := h.CardService.GetBoard(boardID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	format := r.FormValue("format")
+	if format != "md" && format != "csv" {
+		http.Error(w, fmt.Sprintf("unsupported format %q, expected md or csv", format), http.StatusBadRequest)
+		return
+	}
+
+	filter := h.FilterService.GetActive(session.ID, boardID)
+	cards, archived := h.exportCards(boardID, filter, r.FormValue("archived") == "1")
+	filename := fmt.Sprintf("board-%d.%s", boardID, format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		if err := csv.NewWriter(w).WriteAll(exportCSV(append(cards, archived...))); err != nil {
+			h.Log.Error("Failed to write board export", "boardID", boardID, "format", format, "error", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	markdown := exportMarkdown(board, h.CardService.GetColumns(boardID), len(h.CardService.GetLanes(boardID)) > 1, filter, cards, archived, time.Now())
+	if _, err := w.Write([]byte(markdown)); err != nil {
+		h.Log.Error("Failed to write board export", "boardID", boardID, "format", format, "error", err)
+	}
+}
+
+// exportCards returns the board's cards matching the filter in board order, column by column and lane by lane,
+// and its archived cards, if wanted, in the same column order and most recently archived first
+func (h *Handler) exportCards(boardID int, filter *services.Filter, withArchived bool) ([]exportedCard, []exportedCard) {
+	columns := h.CardService.GetColumns(boardID)
+	lanes := h.CardService.GetLanes(boardID)
+	laneTitles := make(map[int]string) // laneID -> title
+	for _, lane := range lanes {
+		laneTitles[lane.ID] = lane.Title
+	}
+
+	var cards []exportedCard
+	for _, column := range columns {
+		for _, lane := range lanes {
+			cell, err := h.CardService.GetCell(column.Column.ID, lane.ID)
+			if err != nil {
+				h.Log.Error("Failed to get cell", "columnID", column.Column.ID, "laneID", lane.ID, "error", err)
+				continue
+			}
+			matching := h.FilterService.Apply(filter, boardID, cell.Cards)
+			for position, card := range cell.Cards {
+				if slices.ContainsFunc(matching, func(match services.Card) bool { return match.ID == card.ID }) {
+					cards = append(cards, exportedCard{Card: card, Column: column.Column.Title, Lane: lane.Title, Position: position + 1})
+				}
+			}
+		}
+	}
+
+	var archived []exportedCard
+	if withArchived {
+		matching := h.FilterService.Apply(filter, boardID, h.CardService.GetArchive(boardID, ""))
+		for _, column := range columns {
+			for _, card := range matching {
+				if card.ColumnID == column.Column.ID {
+					archived = append(archived, exportedCard{Card: card, Column: column.Column.Title, Lane: laneTitles[card.LaneID]})
+				}
+			}
+		}
+	}
+	return cards, archived
+}
+
+// exportCSV is a row for each card with its column, lane, position and details
+func exportCSV(cards []exportedCard) [][]string {
+	rows := [][]string{{"id", "title", "column", "lane", "position", "labels", "assignee", "due", "archived", "content"}}
+	for _, card := range cards {
+		row := []string{
+			strconv.Itoa(card.ID),
+			csvText(card.Title),
+			csvText(card.Column),
+			csvText(card.Lane),
+			"",
+			csvText(strings.Join(card.Labels, ", ")),
+			csvText(card.Assignee),
+			"",
+			"",
+			csvText(card.Content),
+		}
+		if card.Position > 0 {
+			row[4] = strconv.Itoa(card.Position)
+		}
+		if card.HasDueDate() {
+			row[7] = card.DueAt.Format(services.DueDateLayout)
+		}
+		if card.IsArchived() {
+			row[8] = card.ArchivedAt.Format(services.DueDateLayout)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// csvText keeps a spreadsheet from reading text that starts like a formula as one, by prefixing it with a quote
+func csvText(text string) string {
+	if text != "" && strings.ContainsRune("=+-@", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+// exportMarkdown is a heading for each column with a bullet for each card in it, then the archived cards. Lanes
+// are only named when the board has more than one.
+func exportMarkdown(
+	board *services.Board,
+	columns []services.ColumnWithCards,
+	showLanes bool,
+	filter *services.Filter,
+	cards []exportedCard,
+	archived []exportedCard,
+	now time.Time,
+) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", board.Title)
+	fmt.Fprintf(&b, "_Exported %s", now.Format(services.DueDateLayout))
+	if description := describeFilter(filter); description != "" {
+		fmt.Fprintf(&b, ", showing cards %s", description)
+	}
+	b.WriteString("_\n")
+
+	for _, column := range columns {
+		var inColumn []exportedCard
+		for _, card := range cards {
+			if card.ColumnID == column.Column.ID {
+				inColumn = append(inColumn, card)
+			}
+		}
+		fmt.Fprintf(&b, "\n## %s (%d)\n\n", column.Column.Title, len(inColumn))
+		if len(inColumn) == 0 {
+			b.WriteString("_No cards_\n")
+		}
+		for _, card := range inColumn {
+			writeMarkdownCard(&b, card, showLanes, "")
+		}
+	}
+
+	if len(archived) > 0 {
+		fmt.Fprintf(&b, "\n## Archived (%d)\n\n", len(archived))
+		for _, card := range archived {
+			writeMarkdownCard(&b, card, showLanes, card.Column)
+		}
+	}
+	return b.String()
+}
+
+// writeMarkdownCard writes the card as a bullet with its details after the title and its content indented below
+func writeMarkdownCard(b *strings.Builder, card exportedCard, showLanes bool, column string) {
+	details := []string{}
+	if column != "" {
+		details = append(details, column)
+	}
+	if showLanes {
+		details = append(details, card.Lane)
+	}
+	for _, label := range card.Labels {
+		details = append(details, "`"+label+"`")
+	}
+	if card.Assignee != "" {
+		details = append(details, "@"+card.Assignee)
+	}
+	if card.HasDueDate() {
+		details = append(details, "due "+card.DueAt.Format(services.DueDateLayout))
+	}
+	if card.IsArchived() {
+		details = append(details, "archived "+card.ArchivedAt.Format(services.DueDateLayout))
+	}
+
+	fmt.Fprintf(b, "- **%s**", card.Title)
+	if len(details) > 0 {
+		fmt.Fprintf(b, " (%s)", strings.Join(details, ", "))
+	}
+	b.WriteString("\n")
+	for _, line := range strings.Split(strings.TrimSpace(card.Content), "\n") {
+		if line = strings.TrimRight(line, " \t\r"); line != "" {
+			fmt.Fprintf(b, "  %s\n", line)
+		}
+	}
+}
+
+// describeFilter says what the filter matches, for the top of an export, or nothing for an empty filter
+func describeFilter(filter *services.Filter) string {
+	if filter == nil || filter.IsEmpty() {
+		return ""
+	}
+	var parts []string
+	if filter.Text != "" {
+		parts = append(parts, fmt.Sprintf("matching %q", filter.Text))
+	}
+	if len(filter.Labels) > 0 {
+		parts = append(parts, "labelled "+strings.Join(filter.Labels, " and "))
+	}
+	if filter.Assignee != "" {
+		parts = append(parts, "assigned to "+filter.Assignee)
+	}
+	switch filter.Due {
+	case services.DueAny:
+	case services.DueNone:
+		parts = append(parts, "with no due date")
+	default:
+		parts = append(parts, strings.ToLower(filter.Due.Label()))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -1,11 +1,16 @@
 package board
 
 import (
+	"fmt"
 	"log/slog"
 	"mesh/src/components/base"
+	"mesh/src/components/cell"
 	"mesh/src/components/column"
+	"mesh/src/components/filter"
 	"mesh/src/services"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/a-h/templ"
 )
@@ -14,35 +19,217 @@ type Handler struct {
 	*base.BaseHandler
 	CardService   *services.CardService
 	ColumnHandler *column.Handler
+	CellHandler   *cell.Handler
+	FilterHandler *filter.Handler
+	FilterService *services.FilterService
+	SSEService    *services.SSEService
 }
 
-func New(log *slog.Logger, eventService *services.EventService, cardService *services.CardService, columnHandler *column.Handler) *Handler {
-	return &Handler{
-		BaseHandler:   base.NewBaseHandler(log, "board", eventService),
+func New(
+	log *slog.Logger,
+	eventService *services.EventService,
+	sessionService *services.SessionService,
+	cardService *services.CardService,
+	columnHandler *column.Handler,
+	cellHandler *cell.Handler,
+	filterHandler *filter.Handler,
+	filterService *services.FilterService,
+	sseService *services.SSEService,
+) *Handler {
+	h := &Handler{
+		BaseHandler:   base.NewBaseHandler(log, "board", eventService, sessionService),
 		CardService:   cardService,
 		ColumnHandler: columnHandler,
+		CellHandler:   cellHandler,
+		FilterHandler: filterHandler,
+		FilterService: filterService,
+		SSEService:    sseService,
 	}
+	eventService.SubscribeLaneChanged("board", h.OnLaneChanged)
+	eventService.SubscribeFilterChanged("board", h.OnFilterChanged)
+	eventService.SubscribeTemplateChanged("board", h.OnTemplateChanged)
+	return h
+}
+
+// OnLaneChanged redraws the whole board, since adding or removing a lane changes the grid
+func (h *Handler) OnLaneChanged(event *services.LaneChangedEvent) {
+	h.SSEService.BroadcastOOBUpdatePerSession(func(sessionID string) templ.Component {
+		props := h.getProps(event.BoardID, sessionID)
+		props.OOB = true
+		return Board(props)
+	})
+}
+
+// OnTemplateChanged redraws the board so every cell's "Add new" form offers the current templates
+func (h *Handler) OnTemplateChanged(event *services.TemplateChangedEvent) {
+	h.SSEService.BroadcastOOBUpdatePerSession(func(sessionID string) templ.Component {
+		props := h.getProps(event.BoardID, sessionID)
+		props.OOB = true
+		return Board(props)
+	})
+}
+
+// OnFilterChanged redraws the board for the session whose filter changed
+func (h *Handler) OnFilterChanged(event *services.FilterChangedEvent) {
+	props := h.getProps(event.BoardID, event.SessionID)
+	props.OOB = true
+	h.SSEService.SendOOBUpdate(event.SessionID, Board(props))
 }
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	get := h.Get
+	if strings.HasSuffix(r.URL.Path, "/export") {
+		get = h.GetExport
+	}
 	h.BaseHandler.ServeHTTP(w, r, map[string]http.HandlerFunc{
-		http.MethodGet: h.Get,
+		http.MethodGet: get,
 	})
 }
 
 func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
-	h.RenderTemplate(r.Context(), w, h.RenderComponent())
+	session := h.SessionService.Session(w, r)
+
+	// With a time the board is shown as it was then, read-only, with a slider to move through its history
+	if r.FormValue("at") != "" {
+		at, err := parseAt(r.FormValue("at"), time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		props, err := h.getPropsAsOf(h.BoardID(r), at)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		h.RenderTemplate(r.Context(), w, Board(props))
+		return
+	}
+
+	h.RenderTemplate(r.Context(), w, h.RenderComponentForBoard(h.BoardID(r), session.ID))
+}
+
+// parseAt reads a time as RFC 3339, as a datetime-local input gives it, or "now"
+func parseAt(value string, now time.Time) (time.Time, error) {
+	if value == "now" {
+		return now, nil
+	}
+	if at, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return at, nil
+	}
+	if at, err := time.ParseInLocation("2006-01-02T15:04", value, time.Local); err == nil {
+		return at, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected something like 2006-01-02T15:04:05Z", value)
+}
+
+// RenderComponent renders the default board as the session sees it, through its active filter
+func (h *Handler) RenderComponent(sessionID string) templ.Component {
+	return h.RenderComponentForBoard(services.DefaultBoardID, sessionID)
+}
+
+func (h *Handler) RenderComponentForBoard(boardID int, sessionID string) templ.Component {
+	return Board(h.getProps(boardID, sessionID))
+}
+
+func (h *Handler) RenderComponentWithLaneError(boardID int, sessionID string, message string) templ.Component {
+	props := h.getProps(boardID, sessionID)
+	props.LaneError = message
+	return Board(props)
 }
 
-func (h *Handler) RenderComponent() templ.Component {
-	columnsWithCards := h.CardService.GetColumns()
+func (h *Handler) getProps(boardID int, sessionID string) BoardProps {
+	board, err := h.CardService.GetBoard(boardID)
+	if err != nil {
+		h.Log.Error("Failed to get board", "boardID", boardID, "error", err)
+		board = &services.Board{ID: boardID}
+	}
+
+	columnsWithCards := h.CardService.GetColumns(board.ID)
 	var columnComponents []templ.Component
 	for _, columnWithCards := range columnsWithCards {
 		columnComponent := h.ColumnHandler.RenderComponent(&columnWithCards, false)
 		columnComponents = append(columnComponents, columnComponent)
 	}
-	props := BoardProps{
+
+	filter := h.FilterService.GetActive(sessionID, board.ID)
+	lanes := h.CardService.GetLanes(board.ID)
+	var rows []LaneRow
+	for _, lane := range lanes {
+		row := LaneRow{Lane: lane, CanDelete: len(lanes) > 1}
+		for _, columnWithCards := range columnsWithCards {
+			cellWithCards, err := h.CardService.GetCell(columnWithCards.Column.ID, lane.ID)
+			if err != nil {
+				h.Log.Error("Failed to get cell", "columnID", columnWithCards.Column.ID, "laneID", lane.ID, "error", err)
+				continue
+			}
+			if len(cellWithCards.Cards) > 0 {
+				row.CanDelete = false
+			}
+			row.Cells = append(row.Cells, h.CellHandler.RenderComponent(cellWithCards, filter, false))
+		}
+		rows = append(rows, row)
+	}
+
+	return BoardProps{
+		Board:   board,
+		Filter:  h.FilterHandler.RenderComponent(sessionID, board.ID),
 		Columns: columnComponents,
+		Lanes:   rows,
 	}
-	return Board(props)
+}
+
+// getPropsAsOf shows the board as it was at the given time by replaying its history up to then
+func (h *Handler) getPropsAsOf(boardID int, at time.Time) (BoardProps, error) {
+	asOf, err := h.CardService.AsOf(at)
+	if err != nil {
+		return BoardProps{}, err
+	}
+	board, err := asOf.GetBoard(boardID)
+	if err != nil {
+		return BoardProps{}, fmt.Errorf("board with ID %d didn't exist at %s", boardID, at.Format(time.RFC3339))
+	}
+
+	columnsWithCards := asOf.GetColumns(board.ID)
+	var columnComponents []templ.Component
+	for _, columnWithCards := range columnsWithCards {
+		columnComponents = append(columnComponents, h.ColumnHandler.RenderReadOnlyComponent(&columnWithCards))
+	}
+
+	var rows []LaneRow
+	for _, lane := range asOf.GetLanes(board.ID) {
+		row := LaneRow{Lane: lane}
+		for _, columnWithCards := range columnsWithCards {
+			cellWithCards, err := asOf.GetCell(columnWithCards.Column.ID, lane.ID)
+			if err != nil {
+				h.Log.Error("Failed to get cell", "columnID", columnWithCards.Column.ID, "laneID", lane.ID, "error", err)
+				continue
+			}
+			row.Cells = append(row.Cells, h.CellHandler.RenderReadOnlyComponent(asOf, cellWithCards))
+		}
+		rows = append(rows, row)
+	}
+
+	// The slider runs from the board's first change to now, marking each of its changes along the way
+	history := h.CardService.BoardHistory(boardID)
+	now := time.Now()
+	timeTravel := &TimeTravel{At: at, From: at, To: now}
+	if at.After(now) {
+		timeTravel.At = now
+	}
+	for _, recorded := range history {
+		if len(timeTravel.Changes) == 0 || !recorded.Time.Equal(timeTravel.Changes[len(timeTravel.Changes)-1]) {
+			timeTravel.Changes = append(timeTravel.Changes, recorded.Time)
+		}
+	}
+	if len(history) > 0 {
+		timeTravel.From = history[0].Time
+	}
+
+	return BoardProps{
+		Board:      board,
+		Columns:    columnComponents,
+		Lanes:      rows,
+		TimeTravel: timeTravel,
+	}, nil
 }
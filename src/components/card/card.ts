@@ -1,14 +1,22 @@
 import {MeshElement} from "../base/mesh-element.ts";
 
-import {ArrowLeft, ArrowRight, CircleX, Pencil, Grip} from 'lucide';
+import {Archive, ArrowLeft, ArrowRight, CircleX, Pencil, Grip, Paperclip, Send, User, X} from 'lucide';
 
 export class Card extends MeshElement {
+    // Drop targets can't read the drag data until the drop, so they check the card being dragged
+    static dragging: Card | null = null;
+
     protected icons = {
+        Archive,
         ArrowLeft,
         ArrowRight,
         CircleX,
         Pencil,
         Grip,
+        Paperclip,
+        Send,
+        User,
+        X,
     };
 
     edit() {
@@ -21,6 +29,13 @@ export class Card extends MeshElement {
         this.show('[data-view]');
     }
 
+    upload(e: Event) {
+        const input = e.target as HTMLInputElement;
+        if (input.files?.length) {
+            input.form?.requestSubmit();
+        }
+    }
+
     connectedCallback() {
         super.connectedCallback();
         this.setupDragAndDrop();
@@ -40,11 +55,13 @@ export class Card extends MeshElement {
 
         e.dataTransfer.setData('text/plain', this.dataset.id);
         this.classList.add('dragging');
+        Card.dragging = this;
         e.dataTransfer.effectAllowed = 'move';
     }
 
     handleDragEnd() {
         this.classList.remove('dragging');
+        Card.dragging = null;
     }
 
     createDragImage() {
@@ -1,14 +1,19 @@
 package card
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"mesh/src/services"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"mesh/src/components/base"
+	"mesh/src/components/undo"
 
 	"github.com/a-h/templ"
 )
@@ -17,18 +22,37 @@ type Handler struct {
 	*base.BaseHandler
 	*services.CardService
 	*services.WordService
+	ModerationService *services.ModerationService
+	AttachmentService *services.AttachmentService
+	MarkdownService   *services.MarkdownService
+	TemplateService   *services.TemplateService
+	UndoService       *services.UndoService
+	UndoHandler       *undo.Handler
 }
 
 func New(
 	log *slog.Logger,
 	eventService *services.EventService,
+	sessionService *services.SessionService,
 	cardService *services.CardService,
 	wordService *services.WordService,
+	moderationService *services.ModerationService,
+	attachmentService *services.AttachmentService,
+	markdownService *services.MarkdownService,
+	templateService *services.TemplateService,
+	undoService *services.UndoService,
+	undoHandler *undo.Handler,
 ) *Handler {
 	return &Handler{
-		BaseHandler: base.NewBaseHandler(log, "card", eventService),
-		CardService: cardService,
-		WordService: wordService,
+		BaseHandler:       base.NewBaseHandler(log, "card", eventService, sessionService),
+		CardService:       cardService,
+		WordService:       wordService,
+		ModerationService: moderationService,
+		AttachmentService: attachmentService,
+		MarkdownService:   markdownService,
+		TemplateService:   templateService,
+		UndoService:       undoService,
+		UndoHandler:       undoHandler,
 	}
 }
 
@@ -62,7 +86,10 @@ func (h *Handler) getCardFromRequest(r *http.Request) (*services.Card, error) {
 }
 
 func (h *Handler) getColumnFromRequest(r *http.Request) (*services.Column, error) {
-	columnIDString := r.FormValue("columnID")
+	return h.getColumn(r.FormValue("columnID"))
+}
+
+func (h *Handler) getColumn(columnIDString string) (*services.Column, error) {
 	if columnIDString == "" {
 		return nil, fmt.Errorf("missing column ID")
 	}
@@ -80,6 +107,28 @@ func (h *Handler) getColumnFromRequest(r *http.Request) (*services.Column, error
 	return &column.Column, nil
 }
 
+func (h *Handler) getLaneFromRequest(r *http.Request) (*services.Lane, error) {
+	return h.getLane(r.FormValue("laneID"))
+}
+
+func (h *Handler) getLane(laneIDString string) (*services.Lane, error) {
+	if laneIDString == "" {
+		return nil, fmt.Errorf("missing lane ID")
+	}
+
+	laneID, err := strconv.Atoi(laneIDString)
+	if err != nil {
+		return nil, fmt.Errorf("invalid lane ID %s", laneIDString)
+	}
+
+	lane, err := h.CardService.GetLane(laneID)
+	if err != nil {
+		return nil, fmt.Errorf("lane not found %d", laneID)
+	}
+
+	return lane, nil
+}
+
 func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 	card, err := h.getCardFromRequest(r)
 	if err != nil {
@@ -91,11 +140,18 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) validate(r *http.Request) (Data, Errors) {
+	// Parses urlencoded bodies as well, only complaining that they aren't multipart
+	_ = r.ParseMultipartForm(32 << 20)
+	return h.Validate(r.Form)
+}
+
+// Validate checks card fields given as form values, so cards that don't come from the card form get the same limits
+func (h *Handler) Validate(form url.Values) (Data, Errors) {
 	errors := Errors{}
 
 	var data = Data{
-		Title:   strings.TrimSpace(r.FormValue("title")),
-		Content: strings.TrimSpace(r.FormValue("content")),
+		Title:   strings.TrimSpace(form.Get("title")),
+		Content: strings.TrimSpace(form.Get("content")),
 	}
 
 	if data.Title == "" {
@@ -110,26 +166,85 @@ func (h *Handler) validate(r *http.Request) (Data, Errors) {
 		errors.Content = "Content must be less than 1000 characters"
 	}
 
-	if blacklistedWord := h.WordService.Filter(data.Title); blacklistedWord != "" {
-		errors.Title = "Let's keep it light shall we"
+	data.Labels, errors.Labels = ParseLabels(form.Get("labels"))
+
+	data.Assignee = strings.TrimSpace(form.Get("assignee"))
+	if len(data.Assignee) > 50 {
+		errors.Assignee = "Assignee must be less than 50 characters"
 	}
-	if blacklistedWord := h.WordService.Filter(data.Content); blacklistedWord != "" {
-		errors.Content = "Let's keep it light shall we"
+
+	if dueAt := form.Get("dueAt"); dueAt != "" {
+		var err error
+		data.DueAt, err = time.ParseInLocation(services.DueDateLayout, dueAt, time.Local)
+		if err != nil {
+			errors.DueAt = "Due date must be a date"
+		}
 	}
 
-	if r.FormValue("columnID") != "" {
-		var column, err = h.getColumnFromRequest(r)
+	if form.Get("columnID") != "" {
+		var column, err = h.getColumn(form.Get("columnID"))
 		if err != nil {
 			errors.ColumnID = err.Error()
 		} else {
 			data.ColumnID = column.ID
 		}
+
+		lane, err := h.getLane(form.Get("laneID"))
+		if err != nil {
+			errors.ColumnID = err.Error()
+		} else {
+			data.LaneID = lane.ID
+		}
+	}
+
+	// Blacklisted words are only refused on boards that block them; the others mask them or hold the card
+	policy := services.ModerationPolicy{Mode: services.ModerationBlock}
+	if boardID, ok := h.boardIDOf(form, data); ok {
+		policy = h.ModerationService.GetPolicy(boardID)
+	}
+	if policy.Mode == services.ModerationBlock {
+		if blacklistedWord := h.WordService.FilterAllowing(data.Title, policy.Allowed); blacklistedWord != "" {
+			errors.Title = "Let's keep it light shall we"
+		}
+		if blacklistedWord := h.WordService.FilterAllowing(data.Content, policy.Allowed); blacklistedWord != "" {
+			errors.Content = "Let's keep it light shall we"
+		}
+		if blacklistedWord := h.WordService.FilterAllowing(strings.Join(data.Labels, " "), policy.Allowed); blacklistedWord != "" {
+			errors.Labels = "Let's keep it light shall we"
+		}
+		if blacklistedWord := h.WordService.FilterAllowing(data.Assignee, policy.Allowed); blacklistedWord != "" {
+			errors.Assignee = "Let's keep it light shall we"
+		}
 	}
 
 	return data, errors
 }
 
+// boardIDOf finds the board a card form is for, from the column of a new card or the card being edited
+func (h *Handler) boardIDOf(form url.Values, data Data) (int, bool) {
+	columnID := data.ColumnID
+	if columnID == 0 {
+		cardID, err := strconv.Atoi(form.Get("cardID"))
+		if err != nil {
+			return 0, false
+		}
+		card, err := h.CardService.GetCard(cardID)
+		if err != nil {
+			return 0, false
+		}
+		columnID = card.ColumnID
+	}
+
+	column, err := h.CardService.GetColumn(columnID)
+	if err != nil {
+		return 0, false
+	}
+	return column.Column.BoardID, true
+}
+
 func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	session := h.SessionService.Session(w, r)
+
 	card, err := h.getCardFromRequest(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
@@ -141,11 +256,22 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	h.UndoService.Record(session.ID, &services.CardDeletedOperation{Card: *card})
+
+	// The card is replaced with a toast offering to bring it back
+	h.RenderTemplate(r.Context(), w, h.UndoHandler.RenderComponent(session, fmt.Sprintf("Deleted “%s”", card.Title)))
 
-	h.EventService.PublishCardDeleted(card.ColumnID)
+	h.EventService.PublishCardDeleted(session.Name, card.ID, card.Cell())
 }
 
 func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
+	if r.FormValue("templateID") != "" {
+		h.postFromTemplate(w, r)
+		return
+	}
+
+	session := h.SessionService.Session(w, r)
+
 	var data, errors = h.validate(r)
 	if errors.Any() {
 		var props = h.getPropsWithData(&services.Card{}, data, errors)
@@ -153,23 +279,82 @@ func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	card, err := h.CardService.AddCard(
-		data.Title,
-		data.Content,
-		data.ColumnID,
-	)
+	card, err := h.CardService.AddCard(data.CardDetails(), data.ColumnID, data.LaneID)
+	if limitErr, ok := asWIPLimitError(err); ok {
+		props := h.getPropsWithData(&services.Card{ColumnID: data.ColumnID, LaneID: data.LaneID}, data, Errors{ColumnID: limitErr.Error()})
+		h.RenderTemplate(r.Context(), w, Card(props))
+		return
+	}
+	if held, ok := asHeld(err); ok {
+		h.Hold(session.Name, held, 0, data.ColumnID, data.LaneID, data.CardDetails())
+		props := h.getPropsForNew(services.Cell{ColumnID: data.ColumnID, LaneID: data.LaneID})
+		props.Notice = "Your card is waiting for a board admin to review it"
+		h.RenderTemplate(r.Context(), w, Card(props))
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.UndoService.Record(session.ID, &services.CardAddedOperation{Card: *card})
+
+	h.RenderTemplate(r.Context(), w, h.RenderComponent(card))
+	h.RenderTemplate(r.Context(), w, h.RenderComponentForNew(card.Cell()))
+
+	h.EventService.PublishCardChanged(session.Name, *card)
+}
+
+// postFromTemplate adds a card built from one of the board's templates
+func (h *Handler) postFromTemplate(w http.ResponseWriter, r *http.Request) {
+	session := h.SessionService.Session(w, r)
+
+	templateID, err := strconv.Atoi(r.FormValue("templateID"))
+	if err != nil {
+		http.Error(w, "Invalid template ID", http.StatusBadRequest)
+		return
+	}
+	column, err := h.getColumnFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	lane, err := h.getLaneFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	now := time.Now()
+	card, err := h.TemplateService.CreateFromTemplate(templateID, column.ID, lane.ID, now)
+	if limitErr, ok := asWIPLimitError(err); ok {
+		props := h.getPropsWithData(&services.Card{ColumnID: column.ID, LaneID: lane.ID}, Data{}, Errors{ColumnID: limitErr.Error()})
+		h.RenderTemplate(r.Context(), w, Card(props))
+		return
+	}
+	if held, ok := asHeld(err); ok {
+		if template, err := h.TemplateService.GetTemplate(templateID); err == nil {
+			h.Hold(session.Name, held, 0, column.ID, lane.ID, template.Instantiate(now))
+		}
+		props := h.getPropsForNew(services.Cell{ColumnID: column.ID, LaneID: lane.ID})
+		props.Notice = "Your card is waiting for a board admin to review it"
+		h.RenderTemplate(r.Context(), w, Card(props))
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	h.UndoService.Record(session.ID, &services.CardAddedOperation{Card: *card})
 
 	h.RenderTemplate(r.Context(), w, h.RenderComponent(card))
-	h.RenderTemplate(r.Context(), w, h.RenderComponentForNew(card.ColumnID))
+	h.RenderTemplate(r.Context(), w, h.RenderComponentForNew(card.Cell()))
 
-	h.EventService.PublishCardChanged(card.ID)
+	h.EventService.PublishCardChanged(session.Name, *card)
 }
 
 func (h *Handler) Patch(w http.ResponseWriter, r *http.Request) {
+	session := h.SessionService.Session(w, r)
+
 	card, err := h.getCardFromRequest(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
@@ -184,49 +369,87 @@ func (h *Handler) Patch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.CardService.UpdateCard(
-		card.ID,
-		data.Title,
-		data.Content,
-	)
+	before := *card
+	err = h.CardService.UpdateCard(card.ID, data.CardDetails())
+	if held, ok := asHeld(err); ok {
+		h.Hold(session.Name, held, card.ID, card.ColumnID, card.LaneID, data.CardDetails())
+		props := h.getProps(card)
+		props.Notice = "Your changes are waiting for a board admin to review them"
+		h.RenderTemplate(r.Context(), w, Card(props))
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if card, err = h.CardService.GetCard(card.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	h.UndoService.Record(session.ID, &services.CardUpdatedOperation{Before: before, After: *card})
 
 	h.RenderTemplate(r.Context(), w, h.RenderComponent(card))
 
-	h.EventService.PublishCardChanged(card.ID)
+	h.EventService.PublishCardChanged(session.Name, *card)
 }
 
 func (h *Handler) Put(w http.ResponseWriter, r *http.Request) {
+	session := h.SessionService.Session(w, r)
+	actor := session.Name
+
 	card, err := h.getCardFromRequest(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
+	fromPosition, err := h.CardService.GetCardPosition(card.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	recordMove := func(from, to services.Cell) {
+		toPosition, _ := h.CardService.GetCardPosition(card.ID)
+		h.UndoService.Record(session.ID, &services.CardMovedOperation{
+			CardID:       card.ID,
+			Title:        card.Title,
+			From:         from,
+			FromPosition: fromPosition,
+			To:           to,
+			ToPosition:   toPosition,
+		})
+	}
 	action := r.FormValue("action")
 	switch action {
 	case PutActionDemote:
-		fromColumn, toColumn, err := h.CardService.Demote(card.ID)
+		from, to, err := h.CardService.Demote(card.ID)
+		if limitErr, ok := asWIPLimitError(err); ok {
+			h.RenderTemplate(r.Context(), w, h.RenderComponentWithMoveError(card, limitErr.Error()))
+			return
+		}
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		recordMove(from, to)
 		updatedCard, err := h.CardService.GetCard(card.ID)
 		if err == nil {
 			props := h.getProps(updatedCard)
 			props.OOB = true
 			h.RenderTemplate(r.Context(), w, Card(props))
 		}
-		h.EventService.PublishCardMoved(card.ID, fromColumn.ID, toColumn.ID)
+		h.EventService.PublishCardMoved(actor, card.ID, from, to)
 		break
 	case PutActionPromote:
-		fromColumn, toColumn, err := h.CardService.Promote(card.ID)
+		from, to, err := h.CardService.Promote(card.ID)
+		if limitErr, ok := asWIPLimitError(err); ok {
+			h.RenderTemplate(r.Context(), w, h.RenderComponentWithMoveError(card, limitErr.Error()))
+			return
+		}
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		recordMove(from, to)
 		// Get the updated card after the move
 		updatedCard, err := h.CardService.GetCard(card.ID)
 		if err == nil {
@@ -235,7 +458,7 @@ func (h *Handler) Put(w http.ResponseWriter, r *http.Request) {
 			props.OOB = true
 			h.RenderTemplate(r.Context(), w, Card(props))
 		}
-		h.EventService.PublishCardMoved(card.ID, fromColumn.ID, toColumn.ID)
+		h.EventService.PublishCardMoved(actor, card.ID, from, to)
 		break
 	case PutActionMove:
 		columnID, err := strconv.Atoi(r.FormValue("columnID"))
@@ -248,11 +471,26 @@ func (h *Handler) Put(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		fromColumn, toColumn, err := h.CardService.MoveCard(card.ID, columnID, position)
+		// Without a lane the card stays in its current one
+		laneID := card.LaneID
+		if r.FormValue("laneID") != "" {
+			laneID, err = strconv.Atoi(r.FormValue("laneID"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		from, to, err := h.CardService.MoveCard(card.ID, columnID, laneID, position)
+		if limitErr, ok := asWIPLimitError(err); ok {
+			// Moves come from drag and drop, which shows the message on the column
+			http.Error(w, limitErr.Error(), http.StatusConflict)
+			return
+		}
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		recordMove(from, to)
 		// Get the updated card after the move
 		updatedCard, err := h.CardService.GetCard(card.ID)
 		if err == nil {
@@ -261,7 +499,15 @@ func (h *Handler) Put(w http.ResponseWriter, r *http.Request) {
 			props.OOB = true
 			h.RenderTemplate(r.Context(), w, Card(props))
 		}
-		h.EventService.PublishCardMoved(card.ID, fromColumn.ID, toColumn.ID)
+		h.EventService.PublishCardMoved(actor, card.ID, from, to)
+	case PutActionArchive:
+		err := h.CardService.ArchiveCard(card.ID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// The archived card simply disappears from its column
+		h.EventService.PublishCardArchived(actor, card.ID, card.Cell())
 	}
 }
 
@@ -270,14 +516,43 @@ func (h *Handler) RenderComponent(card *services.Card) templ.Component {
 	return Card(props)
 }
 
-func (h *Handler) RenderComponentForNew(columnID int) templ.Component {
-	props := h.getPropsForNew(columnID)
+func (h *Handler) RenderComponentWithAttachmentError(card *services.Card, message string) templ.Component {
+	props := h.getProps(card)
+	props.AttachmentError = message
 	return Card(props)
 }
 
-func (h *Handler) getPropsForNew(columnID int) CardProps {
+func (h *Handler) RenderComponentWithCommentError(card *services.Card, message string) templ.Component {
+	props := h.getProps(card)
+	props.CommentError = message
+	return Card(props)
+}
+
+func (h *Handler) RenderComponentWithMoveError(card *services.Card, message string) templ.Component {
+	props := h.getProps(card)
+	props.MoveError = message
+	return Card(props)
+}
+
+func (h *Handler) RenderComponentForNew(cell services.Cell) templ.Component {
+	props := h.getPropsForNew(cell)
+	return Card(props)
+}
+
+// RenderReadOnlyComponent renders the card as it was at some point in the past, with the comments it had then.
+// Attachments aren't part of the board's history, so they're left out rather than shown as they are now.
+func (h *Handler) RenderReadOnlyComponent(card *services.Card, comments []services.Comment) templ.Component {
+	return Card(CardProps{
+		Card:        card,
+		ContentHTML: h.MarkdownService.Render(card.Content),
+		Comments:    comments,
+		ReadOnly:    true,
+	})
+}
+
+func (h *Handler) getPropsForNew(cell services.Cell) CardProps {
 	return h.getPropsWithData(
-		&services.Card{ColumnID: columnID},
+		&services.Card{ColumnID: cell.ColumnID, LaneID: cell.LaneID},
 		Data{},
 		Errors{},
 	)
@@ -285,19 +560,102 @@ func (h *Handler) getPropsForNew(columnID int) CardProps {
 
 func (h *Handler) getProps(card *services.Card) CardProps {
 	return h.getPropsWithData(card, Data{
-		ID:      card.ID,
-		Title:   card.Title,
-		Content: card.Content,
+		ID:       card.ID,
+		Title:    card.Title,
+		Content:  card.Content,
+		Labels:   card.Labels,
+		Assignee: card.Assignee,
+		DueAt:    card.DueAt,
 	}, Errors{})
 }
 
 func (h *Handler) getPropsWithData(card *services.Card, data Data, errors Errors) CardProps {
 	return CardProps{
-		Card:       card,
-		Data:       data,
-		Errors:     errors,
-		IsEditing:  errors.Any(),
-		CanDemote:  h.CardService.CanDemote(card.ID),
-		CanPromote: h.CardService.CanPromote(card.ID),
+		Card:        card,
+		Data:        data,
+		Errors:      errors,
+		ContentHTML: h.MarkdownService.Render(card.Content),
+		Attachments: h.AttachmentService.GetAttachments(card.ID),
+		Comments:    h.CardService.GetComments(card.ID),
+		IsEditing:   errors.Any(),
+		CanDemote:   h.CardService.CanDemote(card.ID),
+		CanPromote:  h.CardService.CanPromote(card.ID),
+		Templates:   h.getTemplates(card),
+	}
+}
+
+// getTemplates returns the templates a new card can be created from, which existing cards don't need
+func (h *Handler) getTemplates(card *services.Card) []services.CardTemplate {
+	if card.ID != 0 || card.ColumnID == 0 {
+		return nil
+	}
+
+	column, err := h.CardService.GetColumn(card.ColumnID)
+	if err != nil {
+		return nil
+	}
+	return h.TemplateService.GetTemplates(column.Column.BoardID)
+}
+
+// asWIPLimitError picks out moves refused by a full column, so they can be explained rather than failed
+func asWIPLimitError(err error) (*services.WIPLimitError, bool) {
+	var limitErr *services.WIPLimitError
+	if errors.As(err, &limitErr) {
+		return limitErr, true
+	}
+	return nil, false
+}
+
+// asHeld picks out cards the board's moderation policy holds for review, so they can be queued rather than failed
+func asHeld(err error) (*services.ModerationError, bool) {
+	var moderationErr *services.ModerationError
+	if errors.As(err, &moderationErr) && moderationErr.Held {
+		return moderationErr, true
+	}
+	return nil, false
+}
+
+// Hold puts a new card, or changes to the card with cardID, in the board's moderation queue
+func (h *Handler) Hold(author string, held *services.ModerationError, cardID, columnID, laneID int, details services.CardDetails) {
+	h.ModerationService.Hold(services.HeldCard{
+		BoardID:  held.BoardID,
+		CardID:   cardID,
+		ColumnID: columnID,
+		LaneID:   laneID,
+		Details:  details,
+		Field:    held.Field,
+		Word:     held.Word,
+		Author:   author,
+	})
+}
+
+// ParseLabels splits a comma-separated list of labels, dropping blanks and duplicates, and explains any that
+// aren't allowed
+func ParseLabels(input string) ([]string, string) {
+	var labels []string
+	for _, label := range strings.Split(input, ",") {
+		label = strings.TrimSpace(label)
+		if label == "" || slices.Contains(labels, label) {
+			continue
+		}
+		if len(label) > 30 {
+			return labels, "Labels must be less than 30 characters"
+		}
+		labels = append(labels, label)
+	}
+
+	if len(labels) > 10 {
+		return labels, "Cards can have at most 10 labels"
+	}
+	return labels, ""
+}
+
+func (d *Data) CardDetails() services.CardDetails {
+	return services.CardDetails{
+		Title:    d.Title,
+		Content:  d.Content,
+		Labels:   d.Labels,
+		Assignee: d.Assignee,
+		DueAt:    d.DueAt,
 	}
 }
@@ -0,0 +1,162 @@
+import {MeshElement} from "../base/mesh-element.ts";
+import {Card} from "../card/card.ts";
+
+export class Cell extends MeshElement {
+    private dropIndicator: HTMLElement | null = null;
+
+    connectedCallback() {
+        super.connectedCallback();
+        this.setupDropTarget();
+    }
+
+    setupDropTarget() {
+        // A cell from the board's history only shows how it was
+        if (this.dataset.readOnly) {
+            return;
+        }
+
+        this.addEventListener('dragover', this.handleDragOver.bind(this));
+        this.addEventListener('drop', this.handleDrop.bind(this));
+        this.addEventListener('dragenter', this.handleDragEnter.bind(this));
+        this.addEventListener('dragleave', this.handleDragLeave.bind(this));
+    }
+
+    handleDragOver(e: any) {
+        // Not calling preventDefault tells the browser the drop isn't allowed
+        if (this.refusesDrop()) {
+            e.dataTransfer.dropEffect = 'none';
+            return;
+        }
+
+        e.preventDefault();
+        e.dataTransfer.dropEffect = 'move';
+
+        this.updateDropIndicator(e);
+    }
+
+    handleDragEnter() {
+        if (this.refusesDrop()) {
+            this.classList.add('refuse-drop');
+            return;
+        }
+
+        this.classList.add('drag-over');
+
+        this.createDropIndicator();
+    }
+
+    handleDragLeave(e: any) {
+        // Only remove if we're actually leaving the cell
+        if (!this.contains(e.relatedTarget)) {
+            this.classList.remove('drag-over', 'refuse-drop');
+
+            this.removeDropIndicator();
+        }
+    }
+
+    // A column at a hard WIP limit only accepts cards it already holds, i.e. moves between its lanes
+    refusesDrop() {
+        const root = this.getRootNode() as ShadowRoot;
+        const column = root.getElementById('column-' + this.dataset.columnId);
+        return column?.dataset.refuses === 'true' && Card.dragging?.dataset.columnId !== this.dataset.columnId;
+    }
+
+    showRefusal(message: string) {
+        this.one('.refusal', el => {
+            el.textContent = message;
+            el.classList.remove('hide');
+            setTimeout(() => el.classList.add('hide'), 4000);
+        });
+    }
+
+    handleDrop(e: any) {
+        e.preventDefault();
+        this.classList.remove('drag-over', 'refuse-drop');
+        this.removeDropIndicator();
+        if (this.refusesDrop()) {
+            return;
+        }
+
+        const cardId = e.dataTransfer.getData('text/plain');
+        const columnId = this.dataset.columnId;
+        const laneId = this.dataset.laneId;
+        if (!cardId || !columnId || !laneId) {
+            throw new Error('Missing card, column or lane ID');
+        }
+
+        // Calculate position within cell
+        const position = this.calculateDropPosition(e);
+
+        this.moveCard(cardId, +columnId, +laneId, position);
+    }
+
+    createDropIndicator() {
+        if (this.dropIndicator) return;
+
+        this.dropIndicator = document.createElement('div');
+        this.dropIndicator.className = 'drop-indicator';
+        this.dropIndicator.style.cssText = `
+            height: 4px;
+            background: #007acc;
+            border: 2px dashed #0056b3;
+            border-radius: 4px;
+            margin: 8px 0;
+            opacity: 0.8;
+            position: relative;
+            transition: all 0.15s ease;
+        `;
+    }
+
+    updateDropIndicator(e: any) {
+        if (!this.dropIndicator) return;
+
+        const cardsContainer = this.shadowRoot!.querySelector('.cards'); // Adjust selector as needed
+        if (!cardsContainer) return;
+
+        // Find where to insert the indicator
+        const afterElement = this.getAfterElement(e);
+        cardsContainer.insertBefore(this.dropIndicator, afterElement || cardsContainer.lastChild!);
+    }
+
+    removeDropIndicator() {
+        if (this.dropIndicator) {
+            this.dropIndicator.remove();
+            this.dropIndicator = null;
+        }
+    }
+
+    calculateDropPosition(e: any) {
+        const afterElement = this.getAfterElement(e);
+
+        if (!afterElement) {
+            return -1;
+        }
+
+        return this.getCards().indexOf(afterElement);
+    }
+
+    private getCards() {
+        return Array.from(this.shadowRoot!.querySelectorAll('mesh-card:not(.create)'));
+    }
+
+    private getAfterElement(e: any) {
+        return this.getCards().find(card => {
+            const rect = card.getBoundingClientRect();
+            return e.clientY < rect.top + rect.height / 2;
+        });
+    }
+
+    async moveCard(cardId: number, columnId: number, laneId: number, position: number) {
+        const formData = new FormData();
+        formData.append('action', 'move');
+        formData.append('cardID', cardId.toString());
+        formData.append('columnID', columnId.toString());
+        formData.append('laneID', laneId.toString());
+        formData.append('position', position.toString());
+        const response = await this.makeRequest('PUT', '/card', formData);
+        if (response.status === 409) {
+            this.showRefusal(await response.text());
+        }
+    }
+}
+window.customElements.define('mesh-cell', Cell);
\ No newline at end of file
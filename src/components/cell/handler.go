@@ -0,0 +1,164 @@
+package cell
+
+import (
+	"log/slog"
+	"mesh/src/components/base"
+	"mesh/src/components/card"
+	"mesh/src/services"
+	"net/http"
+	"strconv"
+
+	"github.com/a-h/templ"
+)
+
+type Handler struct {
+	*base.BaseHandler
+	CardHandler *card.Handler
+	*services.CardService
+	FilterService *services.FilterService
+	SSEService    *services.SSEService
+}
+
+func New(
+	log *slog.Logger,
+	cardService *services.CardService,
+	eventService *services.EventService,
+	sessionService *services.SessionService,
+	cardHandler *card.Handler,
+	filterService *services.FilterService,
+	sseService *services.SSEService,
+) *Handler {
+	h := &Handler{
+		BaseHandler:   base.NewBaseHandler(log, "cell", eventService, sessionService),
+		CardHandler:   cardHandler,
+		CardService:   cardService,
+		FilterService: filterService,
+		SSEService:    sseService,
+	}
+	eventService.SubscribeCardDeleted("cell", h.OnCardDeleted)
+	eventService.SubscribeCardChanged("cell", h.OnCardChanged)
+	eventService.SubscribeCardMoved("cell", h.OnCardMoved)
+	eventService.SubscribeCardArchived("cell", h.OnCardArchived)
+	eventService.SubscribeCardRestored("cell", h.OnCardRestored)
+	eventService.SubscribeCardCommented("cell", h.OnCardCommented)
+	return h
+}
+
+func (h *Handler) OnCardDeleted(event *services.CardDeletedEvent) {
+	h.broadcastCell(services.Cell{ColumnID: event.ColumnID, LaneID: event.LaneID})
+}
+
+func (h *Handler) OnCardChanged(event *services.CardChangedEvent) {
+	card, err := h.CardService.GetCard(event.CardID)
+	if err != nil {
+		h.Log.Error("Failed to get card for card changed event", "cardID", event.CardID, "error", err)
+		return
+	}
+	h.broadcastCell(card.Cell())
+}
+
+func (h *Handler) OnCardMoved(event *services.CardMovedEvent) {
+	to := services.Cell{ColumnID: event.ToColumnID, LaneID: event.ToLaneID}
+	from := services.Cell{ColumnID: event.FromColumnID, LaneID: event.FromLaneID}
+
+	h.broadcastCell(to)
+	if from != to {
+		h.broadcastCell(from)
+	}
+}
+
+func (h *Handler) OnCardArchived(event *services.CardArchivedEvent) {
+	h.broadcastCell(services.Cell{ColumnID: event.ColumnID, LaneID: event.LaneID})
+}
+
+func (h *Handler) OnCardRestored(event *services.CardRestoredEvent) {
+	h.broadcastCell(services.Cell{ColumnID: event.ColumnID, LaneID: event.LaneID})
+}
+
+func (h *Handler) OnCardCommented(event *services.CardCommentedEvent) {
+	card, err := h.CardService.GetCard(event.CardID)
+	if err != nil {
+		h.Log.Error("Failed to get card for card commented event", "cardID", event.CardID, "error", err)
+		return
+	}
+	h.broadcastCell(card.Cell())
+}
+
+func (h *Handler) broadcastCell(cell services.Cell) {
+	cellWithCards, err := h.CardService.GetCell(cell.ColumnID, cell.LaneID)
+	if err != nil {
+		h.Log.Error("Failed to get cell for SSE broadcast", "columnID", cell.ColumnID, "laneID", cell.LaneID, "error", err)
+		return
+	}
+
+	// Each session sees the cell through its own filter, so a card that stops matching disappears
+	boardID := cellWithCards.Column.Column.BoardID
+	h.SSEService.BroadcastOOBUpdatePerSession(func(sessionID string) templ.Component {
+		return h.RenderComponent(cellWithCards, h.FilterService.GetActive(sessionID, boardID), true)
+	})
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.BaseHandler.ServeHTTP(w, r, map[string]http.HandlerFunc{
+		http.MethodGet: h.Get,
+	})
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	session := h.SessionService.Session(w, r)
+
+	columnID, err := strconv.Atoi(r.FormValue("columnID"))
+	if err != nil {
+		http.Error(w, "Invalid column ID", http.StatusNotFound)
+		return
+	}
+
+	laneID, err := strconv.Atoi(r.FormValue("laneID"))
+	if err != nil {
+		http.Error(w, "Invalid lane ID", http.StatusNotFound)
+		return
+	}
+
+	cellWithCards, err := h.CardService.GetCell(columnID, laneID)
+	if err != nil {
+		http.Error(w, "Cell not found", http.StatusNotFound)
+		return
+	}
+
+	filter := h.FilterService.GetActive(session.ID, cellWithCards.Column.Column.BoardID)
+	h.RenderTemplate(r.Context(), w, h.RenderComponent(cellWithCards, filter, false))
+}
+
+// RenderComponent renders the cell with only the cards matching the filter, which may be nil
+func (h *Handler) RenderComponent(cellWithCards *services.CellWithCards, filter *services.Filter, oob bool) templ.Component {
+	cell := services.Cell{ColumnID: cellWithCards.Column.Column.ID, LaneID: cellWithCards.Lane.ID}
+	cards := h.FilterService.Apply(filter, cellWithCards.Column.Column.BoardID, cellWithCards.Cards)
+
+	var cardComponents []templ.Component
+	for _, card := range cards {
+		cardComponents = append(cardComponents, h.CardHandler.RenderComponent(&card))
+	}
+	cardComponents = append(cardComponents, h.CardHandler.RenderComponentForNew(cell))
+
+	return Cell(CellProps{
+		Cell:  &cell,
+		Cards: cardComponents,
+		OOB:   oob,
+	})
+}
+
+// RenderReadOnlyComponent renders the cell from a replay of the board's history, with nothing that changes it
+func (h *Handler) RenderReadOnlyComponent(asOf *services.CardService, cellWithCards *services.CellWithCards) templ.Component {
+	cell := services.Cell{ColumnID: cellWithCards.Column.Column.ID, LaneID: cellWithCards.Lane.ID}
+
+	var cardComponents []templ.Component
+	for _, card := range cellWithCards.Cards {
+		cardComponents = append(cardComponents, h.CardHandler.RenderReadOnlyComponent(&card, asOf.GetComments(card.ID)))
+	}
+
+	return Cell(CellProps{
+		Cell:     &cell,
+		Cards:    cardComponents,
+		ReadOnly: true,
+	})
+}
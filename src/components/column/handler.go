@@ -3,7 +3,6 @@ package column
 import (
 	"log/slog"
 	"mesh/src/components/base"
-	"mesh/src/components/card"
 	"mesh/src/services"
 	"net/http"
 	"strconv"
@@ -13,7 +12,6 @@ import (
 
 type Handler struct {
 	*base.BaseHandler
-	CardHandler *card.Handler
 	*services.CardService
 	SSEService *services.SSEService
 }
@@ -22,29 +20,26 @@ func New(
 	log *slog.Logger,
 	cardService *services.CardService,
 	eventService *services.EventService,
-	cardHandler *card.Handler,
+	sessionService *services.SessionService,
 	sseService *services.SSEService,
 ) *Handler {
 	h := &Handler{
-		BaseHandler: base.NewBaseHandler(log, "column", eventService),
-		CardHandler: cardHandler,
+		BaseHandler: base.NewBaseHandler(log, "column", eventService, sessionService),
 		CardService: cardService,
 		SSEService:  sseService,
 	}
-	eventService.SubscribeCardDeleted(h.OnCardDeleted)
-	eventService.SubscribeCardChanged(h.OnCardChanged)
-	eventService.SubscribeCardMoved(h.OnCardMoved)
+	// Cards are rendered by their cells; the header only needs updating when its count or limit changes
+	eventService.SubscribeCardDeleted("column", h.OnCardDeleted)
+	eventService.SubscribeCardChanged("column", h.OnCardChanged)
+	eventService.SubscribeCardMoved("column", h.OnCardMoved)
+	eventService.SubscribeCardArchived("column", h.OnCardArchived)
+	eventService.SubscribeCardRestored("column", h.OnCardRestored)
+	eventService.SubscribeColumnChanged("column", h.OnColumnChanged)
 	return h
 }
 
 func (h *Handler) OnCardDeleted(event *services.CardDeletedEvent) {
-	column, err := h.CardService.GetColumn(event.ColumnID)
-	if err == nil {
-		component := h.RenderComponent(column, true)
-		h.SSEService.BroadcastOOBUpdate(component)
-	} else {
-		h.Log.Error("Failed to get to-column for SSE broadcast", "columnID", event.ColumnID, "error", err)
-	}
+	h.broadcastColumn(event.ColumnID)
 }
 
 func (h *Handler) OnCardChanged(event *services.CardChangedEvent) {
@@ -53,31 +48,36 @@ func (h *Handler) OnCardChanged(event *services.CardChangedEvent) {
 		h.Log.Error("Failed to get card for card changed event", "cardID", event.CardID, "error", err)
 		return
 	}
-
-	column, err := h.CardService.GetColumn(card.ColumnID)
-	if err == nil {
-		component := h.RenderComponent(column, true)
-		h.SSEService.BroadcastOOBUpdate(component)
-	} else {
-		h.Log.Error("Failed to get to-column for SSE broadcast", "columnID", card.ColumnID, "error", err)
-	}
+	h.broadcastColumn(card.ColumnID)
 }
 
 func (h *Handler) OnCardMoved(event *services.CardMovedEvent) {
-	column, err := h.CardService.GetColumn(event.ToColumnID)
-	if err == nil {
-		component := h.RenderComponent(column, true)
-		h.SSEService.BroadcastOOBUpdate(component)
-	} else {
-		h.Log.Error("Failed to get to-column for SSE broadcast", "columnID", event.ToColumnID, "error", err)
+	if event.FromColumnID == event.ToColumnID {
+		return
 	}
+	h.broadcastColumn(event.ToColumnID)
+	h.broadcastColumn(event.FromColumnID)
+}
 
-	column, err = h.CardService.GetColumn(event.FromColumnID)
-	if err == nil {
-		h.SSEService.BroadcastOOBUpdate(h.RenderComponent(column, true))
-	} else {
-		h.Log.Error("Failed to get from-column for SSE broadcast", "columnID", event.FromColumnID, "error", err)
+func (h *Handler) OnCardArchived(event *services.CardArchivedEvent) {
+	h.broadcastColumn(event.ColumnID)
+}
+
+func (h *Handler) OnCardRestored(event *services.CardRestoredEvent) {
+	h.broadcastColumn(event.ColumnID)
+}
+
+func (h *Handler) OnColumnChanged(event *services.ColumnChangedEvent) {
+	h.broadcastColumn(event.ColumnID)
+}
+
+func (h *Handler) broadcastColumn(columnID int) {
+	column, err := h.CardService.GetColumn(columnID)
+	if err != nil {
+		h.Log.Error("Failed to get column for SSE broadcast", "columnID", columnID, "error", err)
+		return
 	}
+	h.SSEService.BroadcastOOBUpdate(h.RenderComponent(column, true))
 }
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -109,17 +109,26 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) RenderComponent(column *services.ColumnWithCards, oob bool) templ.Component {
-	var cardComponents []templ.Component
-	for _, card := range column.Cards {
-		columnComponent := h.CardHandler.RenderComponent(&card)
-		cardComponents = append(cardComponents, columnComponent)
+	props := ColumnProps{
+		Column:    &column.Column,
+		Count:     len(column.Cards),
+		Full:      column.IsFull(),
+		OverLimit: column.IsOverLimit(),
+		Refuses:   column.RefusesCards(),
+		OOB:       oob,
 	}
-	newCard := h.CardHandler.RenderComponentForNew(column.Column.ID)
-	cardComponents = append(cardComponents, newCard)
+
+	return Column(props)
+}
+
+// RenderReadOnlyComponent renders the column header without the ID live updates look for, so they leave it be
+func (h *Handler) RenderReadOnlyComponent(column *services.ColumnWithCards) templ.Component {
 	props := ColumnProps{
-		Column: &column.Column,
-		Cards:  cardComponents,
-		OOB:    oob,
+		Column:    &column.Column,
+		Count:     len(column.Cards),
+		Full:      column.IsFull(),
+		OverLimit: column.IsOverLimit(),
+		ReadOnly:  true,
 	}
 
 	return Column(props)
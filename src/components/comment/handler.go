@@ -0,0 +1,74 @@
+package comment
+
+import (
+	"log/slog"
+	"mesh/src/components/base"
+	"mesh/src/components/card"
+	"mesh/src/services"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type Handler struct {
+	*base.BaseHandler
+	CardService *services.CardService
+	CardHandler *card.Handler
+}
+
+func New(
+	log *slog.Logger,
+	eventService *services.EventService,
+	sessionService *services.SessionService,
+	cardService *services.CardService,
+	cardHandler *card.Handler,
+) *Handler {
+	return &Handler{
+		BaseHandler: base.NewBaseHandler(log, "comment", eventService, sessionService),
+		CardService: cardService,
+		CardHandler: cardHandler,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.BaseHandler.ServeHTTP(w, r, map[string]http.HandlerFunc{
+		http.MethodPost: h.Post,
+	})
+}
+
+func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
+	actor := h.Actor(w, r)
+
+	cardID, err := strconv.Atoi(r.FormValue("cardID"))
+	if err != nil {
+		http.Error(w, "Invalid card ID", http.StatusNotFound)
+		return
+	}
+
+	card, err := h.CardService.GetCard(cardID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	body := strings.TrimSpace(r.FormValue("body"))
+	if body == "" {
+		h.RenderTemplate(r.Context(), w, h.CardHandler.RenderComponentWithCommentError(card, "Comment is required"))
+		return
+	}
+	if len(body) > 1000 {
+		h.RenderTemplate(r.Context(), w, h.CardHandler.RenderComponentWithCommentError(card, "Comment must be less than 1000 characters"))
+		return
+	}
+
+	comment, err := h.CardService.AddComment(card.ID, actor, body)
+	if err != nil {
+		h.Log.Info("Rejected comment", "cardID", card.ID, "error", err)
+		h.RenderTemplate(r.Context(), w, h.CardHandler.RenderComponentWithCommentError(card, "Let's keep it light shall we"))
+		return
+	}
+
+	h.RenderTemplate(r.Context(), w, h.CardHandler.RenderComponent(card))
+
+	h.EventService.PublishCardCommented(actor, card.ID, comment.ID)
+}
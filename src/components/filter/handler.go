@@ -0,0 +1,170 @@
+package filter
+
+import (
+	"log/slog"
+	"mesh/src/components/base"
+	"mesh/src/components/card"
+	"mesh/src/services"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/a-h/templ"
+)
+
+type Handler struct {
+	*base.BaseHandler
+	FilterService *services.FilterService
+	CardService   *services.CardService
+}
+
+func New(
+	log *slog.Logger,
+	eventService *services.EventService,
+	sessionService *services.SessionService,
+	filterService *services.FilterService,
+	cardService *services.CardService,
+) *Handler {
+	return &Handler{
+		BaseHandler:   base.NewBaseHandler(log, "filter", eventService, sessionService),
+		FilterService: filterService,
+		CardService:   cardService,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.BaseHandler.ServeHTTP(w, r, map[string]http.HandlerFunc{
+		http.MethodGet:    h.Get,
+		http.MethodPost:   h.Post,
+		http.MethodPut:    h.Put,
+		http.MethodDelete: h.Delete,
+	})
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	session := h.SessionService.Session(w, r)
+	boardID := h.BoardID(r)
+
+	if r.FormValue("open") != "1" {
+		h.RenderTemplate(r.Context(), w, h.RenderComponent(session.ID, boardID))
+		return
+	}
+	h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(session.ID, boardID, nil, ""))
+}
+
+// Post applies the filter in the form to the session's view, saving it too if it was given a name
+func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
+	session := h.SessionService.Session(w, r)
+	boardID := h.BoardID(r)
+
+	labels, _ := card.ParseLabels(r.FormValue("labels"))
+	filter := services.Filter{
+		Name:     strings.TrimSpace(r.FormValue("name")),
+		Text:     strings.TrimSpace(r.FormValue("q")),
+		Labels:   labels,
+		Assignee: strings.TrimSpace(r.FormValue("assignee")),
+		Due:      services.DueWindow(r.FormValue("due")),
+	}
+
+	if !slices.Contains(services.DueWindows, filter.Due) {
+		h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(session.ID, boardID, &filter, "Choose a due date from the list"))
+		return
+	}
+	if len(filter.Name) > 50 {
+		h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(session.ID, boardID, &filter, "Name must be less than 50 characters"))
+		return
+	}
+
+	if filter.Name != "" {
+		saved, err := h.FilterService.SaveFilter(session.ID, boardID, filter)
+		if err != nil {
+			h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(session.ID, boardID, &filter, "A saved filter needs something to filter by"))
+			return
+		}
+		filter = *saved
+	}
+
+	h.FilterService.SetActive(session.ID, boardID, filter)
+
+	h.RenderTemplate(r.Context(), w, h.RenderComponent(session.ID, boardID))
+	h.EventService.PublishFilterChanged(session.Name, session.ID, boardID)
+}
+
+// Put applies one of the session's saved filters
+func (h *Handler) Put(w http.ResponseWriter, r *http.Request) {
+	session := h.SessionService.Session(w, r)
+	boardID := h.BoardID(r)
+
+	filterID, err := strconv.Atoi(r.FormValue("filterID"))
+	if err != nil {
+		http.Error(w, "Invalid filter ID", http.StatusBadRequest)
+		return
+	}
+
+	filter, err := h.FilterService.GetSavedFilter(session.ID, boardID, filterID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	h.FilterService.SetActive(session.ID, boardID, *filter)
+
+	h.RenderTemplate(r.Context(), w, h.RenderComponent(session.ID, boardID))
+	h.EventService.PublishFilterChanged(session.Name, session.ID, boardID)
+}
+
+// Delete removes a saved filter when one is given, otherwise it clears the active filter
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	session := h.SessionService.Session(w, r)
+	boardID := h.BoardID(r)
+
+	if r.FormValue("filterID") == "" {
+		h.FilterService.ClearActive(session.ID, boardID)
+		h.RenderTemplate(r.Context(), w, h.RenderComponent(session.ID, boardID))
+		h.EventService.PublishFilterChanged(session.Name, session.ID, boardID)
+		return
+	}
+
+	filterID, err := strconv.Atoi(r.FormValue("filterID"))
+	if err != nil {
+		http.Error(w, "Invalid filter ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.FilterService.DeleteSavedFilter(session.ID, boardID, filterID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(session.ID, boardID, nil, ""))
+}
+
+// RenderComponent renders the collapsed filter bar, which shows the session's active filter
+func (h *Handler) RenderComponent(sessionID string, boardID int) templ.Component {
+	return Filter(FilterProps{
+		BoardID: boardID,
+		Active:  h.FilterService.GetActive(sessionID, boardID),
+	})
+}
+
+// RenderOpenComponent renders the filter form, filled in from draft or else the active filter
+func (h *Handler) RenderOpenComponent(sessionID string, boardID int, draft *services.Filter, errorMessage string) templ.Component {
+	active := h.FilterService.GetActive(sessionID, boardID)
+	if draft == nil {
+		draft = active
+	}
+	if draft == nil {
+		draft = &services.Filter{}
+	}
+
+	return Filter(FilterProps{
+		BoardID:   boardID,
+		Open:      true,
+		Active:    active,
+		Draft:     *draft,
+		Saved:     h.FilterService.GetSavedFilters(sessionID, boardID),
+		Assignees: h.CardService.GetAssignees(boardID),
+		Error:     errorMessage,
+	})
+}
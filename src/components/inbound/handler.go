@@ -0,0 +1,283 @@
+package inbound
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mesh/src/components/base"
+	"mesh/src/components/card"
+	"mesh/src/services"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/a-h/templ"
+)
+
+// maxPayloadSize is generous for alert and CI payloads, which are mostly small
+const maxPayloadSize = 256 << 10
+
+// Handler serves the panel for managing a board's inbound hooks on /inbound, and the endpoint that creates cards
+// from their payloads on /inbound/cards
+type Handler struct {
+	*base.BaseHandler
+	InboundService *services.InboundService
+	CardService    *services.CardService
+	WordService    *services.WordService
+	CardHandler    *card.Handler
+}
+
+func New(
+	log *slog.Logger,
+	eventService *services.EventService,
+	sessionService *services.SessionService,
+	inboundService *services.InboundService,
+	cardService *services.CardService,
+	wordService *services.WordService,
+	cardHandler *card.Handler,
+) *Handler {
+	return &Handler{
+		BaseHandler:    base.NewBaseHandler(log, "inbound", eventService, sessionService),
+		InboundService: inboundService,
+		CardService:    cardService,
+		WordService:    wordService,
+		CardHandler:    cardHandler,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/cards") {
+		h.BaseHandler.ServeHTTP(w, r, map[string]http.HandlerFunc{
+			http.MethodPost: h.PostCard,
+		})
+		return
+	}
+
+	h.BaseHandler.ServeHTTP(w, r, map[string]http.HandlerFunc{
+		http.MethodGet:    h.Get,
+		http.MethodPost:   h.Post,
+		http.MethodDelete: h.Delete,
+	})
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	session := h.SessionService.Session(w, r)
+	boardID := h.BoardID(r)
+	if r.FormValue("open") != "1" {
+		h.RenderTemplate(r.Context(), w, h.RenderComponent(boardID))
+		return
+	}
+	h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(r, session, boardID, ""))
+}
+
+// Post adds a hook, showing its token this once
+func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
+	session := h.SessionService.Session(w, r)
+	boardID := h.BoardID(r)
+
+	if !session.IsAdmin {
+		http.Error(w, "Admin access required", http.StatusForbidden)
+		return
+	}
+
+	columnID, _ := strconv.Atoi(r.FormValue("columnID"))
+	laneID, _ := strconv.Atoi(r.FormValue("laneID"))
+	labels, labelsError := card.ParseLabels(r.FormValue("labels"))
+
+	hook := services.InboundHook{
+		BoardID: boardID,
+		Name:    strings.TrimSpace(r.FormValue("name")),
+		Mapping: services.InboundMapping{
+			Title:      strings.TrimSpace(r.FormValue("title")),
+			Content:    strings.TrimSpace(r.FormValue("content")),
+			LabelsPath: strings.TrimSpace(r.FormValue("labelsPath")),
+			Labels:     labels,
+			ColumnPath: strings.TrimSpace(r.FormValue("columnPath")),
+		},
+		ColumnID: columnID,
+		LaneID:   laneID,
+	}
+
+	message := ""
+	var created *services.InboundHook
+	switch {
+	case len(hook.Name) > 50:
+		message = "Name must be less than 50 characters"
+	case len(hook.Mapping.Title) > 200 || len(hook.Mapping.Content) > 1000:
+		message = "Mappings must be less than 200 characters for the title and 1000 for the content"
+	case labelsError != "":
+		message = labelsError
+	case h.WordService.Filter(hook.Name+" "+strings.Join(labels, " ")) != "":
+		message = "Let's keep it light shall we"
+	default:
+		var err error
+		if created, err = h.InboundService.AddHook(hook); err != nil {
+			message = err.Error()
+		}
+	}
+
+	props := h.getProps(r, session, boardID, message)
+	props.Created = created
+	h.RenderTemplate(r.Context(), w, Inbound(props))
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	session := h.SessionService.Session(w, r)
+	boardID := h.BoardID(r)
+
+	if !session.IsAdmin {
+		http.Error(w, "Admin access required", http.StatusForbidden)
+		return
+	}
+
+	hookID, err := strconv.Atoi(r.FormValue("hookID"))
+	if err != nil {
+		http.Error(w, "Invalid hook ID", http.StatusBadRequest)
+		return
+	}
+	hook, err := h.InboundService.GetHook(hookID)
+	if err != nil || hook.BoardID != boardID {
+		http.Error(w, fmt.Sprintf("hook with ID %d not found", hookID), http.StatusNotFound)
+		return
+	}
+
+	if err := h.InboundService.DeleteHook(hook.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(r, session, boardID, ""))
+}
+
+// token reads the hook token from the Authorization header. It isn't read from the query string, where it would
+// end up in access logs.
+func token(r *http.Request) string {
+	if bearer, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); found {
+		return strings.TrimSpace(bearer)
+	}
+	return ""
+}
+
+// PostCard creates a card from a JSON payload, mapped by the hook the token belongs to
+func (h *Handler) PostCard(w http.ResponseWriter, r *http.Request) {
+	hook, ok := h.InboundService.Authenticate(token(r))
+	if !ok {
+		h.writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "invalid or missing token"})
+		return
+	}
+
+	var payload any
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPayloadSize))
+	if err := decoder.Decode(&payload); err != nil {
+		h.writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+
+	mapped, err := h.InboundService.Map(hook, payload)
+	if err != nil {
+		h.writeJSON(w, http.StatusUnprocessableEntity, map[string]any{"error": err.Error()})
+		return
+	}
+
+	// Inbound cards get exactly the checks the card form does
+	data, cardErrors := h.CardHandler.Validate(url.Values{
+		"title":    {mapped.Title},
+		"content":  {mapped.Content},
+		"labels":   {strings.Join(mapped.Labels, ",")},
+		"columnID": {strconv.Itoa(mapped.ColumnID)},
+		"laneID":   {strconv.Itoa(mapped.LaneID)},
+	})
+	if cardErrors.Any() {
+		h.writeJSON(w, http.StatusUnprocessableEntity, map[string]any{"errors": fieldErrors(cardErrors)})
+		return
+	}
+
+	created, err := h.CardService.AddCard(data.CardDetails(), data.ColumnID, data.LaneID)
+	var limitErr *services.WIPLimitError
+	if errors.As(err, &limitErr) {
+		h.writeJSON(w, http.StatusConflict, map[string]any{"error": limitErr.Error()})
+		return
+	}
+	var moderationErr *services.ModerationError
+	if errors.As(err, &moderationErr) && moderationErr.Held {
+		h.CardHandler.Hold("inbound:"+hook.Name, moderationErr, 0, data.ColumnID, data.LaneID, data.CardDetails())
+		h.InboundService.RecordUse(hook.ID)
+		h.writeJSON(w, http.StatusAccepted, map[string]any{"held": true, "reason": moderationErr.Error()})
+		return
+	}
+	if err != nil {
+		h.writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+	h.InboundService.RecordUse(hook.ID)
+	h.Log.Info("Created card from inbound hook", "hookID", hook.ID, "cardID", created.ID)
+
+	h.writeJSON(w, http.StatusCreated, map[string]any{
+		"id":       created.ID,
+		"title":    created.Title,
+		"columnID": created.ColumnID,
+		"laneID":   created.LaneID,
+		"labels":   append([]string{}, created.Labels...),
+	})
+	h.EventService.PublishCardChanged("inbound:"+hook.Name, *created)
+}
+
+func fieldErrors(errors card.Errors) map[string]string {
+	fields := map[string]string{}
+	for name, message := range map[string]string{
+		"title":    errors.Title,
+		"content":  errors.Content,
+		"labels":   errors.Labels,
+		"columnID": errors.ColumnID,
+	} {
+		if message != "" {
+			fields[name] = message
+		}
+	}
+	return fields
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		h.Log.Error("Failed to write inbound response", "error", err)
+	}
+}
+
+// RenderComponent renders the collapsed panel
+func (h *Handler) RenderComponent(boardID int) templ.Component {
+	return Inbound(InboundProps{BoardID: boardID})
+}
+
+// RenderOpenComponent renders the board's hooks, which only admins can see
+func (h *Handler) RenderOpenComponent(r *http.Request, session *services.Session, boardID int, errorMessage string) templ.Component {
+	return Inbound(h.getProps(r, session, boardID, errorMessage))
+}
+
+// getProps builds the panel, with the URL to post to as seen from the request
+func (h *Handler) getProps(r *http.Request, session *services.Session, boardID int, errorMessage string) InboundProps {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	props := InboundProps{
+		BoardID:  boardID,
+		Open:     true,
+		IsAdmin:  session.IsAdmin,
+		Endpoint: scheme + "://" + r.Host + "/inbound/cards",
+		Error:    errorMessage,
+	}
+	if !session.IsAdmin {
+		return props
+	}
+
+	props.Hooks = h.InboundService.GetHooks(boardID)
+	props.Lanes = h.CardService.GetLanes(boardID)
+	for _, column := range h.CardService.GetColumns(boardID) {
+		props.Columns = append(props.Columns, column.Column)
+	}
+	return props
+}
@@ -0,0 +1,5 @@
+import {MeshElement} from "../base/mesh-element.ts";
+
+export class Inbound extends MeshElement {
+}
+window.customElements.define('mesh-inbound', Inbound);
@@ -0,0 +1,88 @@
+package lane
+
+import (
+	"log/slog"
+	"mesh/src/components/base"
+	"mesh/src/components/board"
+	"mesh/src/services"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Handler adds and removes lanes; it has no template of its own and responds with the whole board
+type Handler struct {
+	*base.BaseHandler
+	*services.CardService
+	BoardHandler *board.Handler
+}
+
+func New(
+	log *slog.Logger,
+	eventService *services.EventService,
+	sessionService *services.SessionService,
+	cardService *services.CardService,
+	boardHandler *board.Handler,
+) *Handler {
+	return &Handler{
+		BaseHandler:  base.NewBaseHandler(log, "lane", eventService, sessionService),
+		CardService:  cardService,
+		BoardHandler: boardHandler,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.BaseHandler.ServeHTTP(w, r, map[string]http.HandlerFunc{
+		http.MethodPost:   h.Post,
+		http.MethodDelete: h.Delete,
+	})
+}
+
+func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
+	session := h.SessionService.Session(w, r)
+	boardID := h.BoardID(r)
+
+	title := strings.TrimSpace(r.FormValue("title"))
+	if title == "" {
+		h.RenderTemplate(r.Context(), w, h.BoardHandler.RenderComponentWithLaneError(boardID, session.ID, "Lane title is required"))
+		return
+	}
+	if len(title) > 100 {
+		h.RenderTemplate(r.Context(), w, h.BoardHandler.RenderComponentWithLaneError(boardID, session.ID, "Lane title must be less than 100 characters"))
+		return
+	}
+
+	if _, err := h.CardService.AddLane(boardID, title); err != nil {
+		h.Log.Error("Failed to add lane", "boardID", boardID, "error", err)
+		h.RenderTemplate(r.Context(), w, h.BoardHandler.RenderComponentWithLaneError(boardID, session.ID, "Could not add lane"))
+		return
+	}
+
+	h.RenderTemplate(r.Context(), w, h.BoardHandler.RenderComponentForBoard(boardID, session.ID))
+	h.EventService.PublishLaneChanged(session.Name, boardID)
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	session := h.SessionService.Session(w, r)
+
+	laneID, err := strconv.Atoi(r.FormValue("laneID"))
+	if err != nil {
+		http.Error(w, "Invalid lane ID", http.StatusBadRequest)
+		return
+	}
+
+	lane, err := h.CardService.GetLane(laneID)
+	if err != nil {
+		http.Error(w, "Lane not found", http.StatusNotFound)
+		return
+	}
+	boardID := lane.BoardID
+
+	if err := h.CardService.DeleteLane(laneID); err != nil {
+		h.RenderTemplate(r.Context(), w, h.BoardHandler.RenderComponentWithLaneError(boardID, session.ID, err.Error()))
+		return
+	}
+
+	h.RenderTemplate(r.Context(), w, h.BoardHandler.RenderComponentForBoard(boardID, session.ID))
+	h.EventService.PublishLaneChanged(session.Name, boardID)
+}
@@ -0,0 +1,181 @@
+package moderation
+
+import (
+	"log/slog"
+	"mesh/src/components/base"
+	"mesh/src/services"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/a-h/templ"
+)
+
+const (
+	ActionApprove = "approve"
+	ActionReject  = "reject"
+)
+
+// Handler serves a board's moderation panel on /moderation, where admins set how the board treats blacklisted
+// words and approve or reject the cards held for review
+type Handler struct {
+	*base.BaseHandler
+	*services.CardService
+	ModerationService *services.ModerationService
+}
+
+func New(
+	log *slog.Logger,
+	eventService *services.EventService,
+	sessionService *services.SessionService,
+	moderationService *services.ModerationService,
+	cardService *services.CardService,
+) *Handler {
+	return &Handler{
+		BaseHandler:       base.NewBaseHandler(log, "moderation", eventService, sessionService),
+		CardService:       cardService,
+		ModerationService: moderationService,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.BaseHandler.ServeHTTP(w, r, map[string]http.HandlerFunc{
+		http.MethodGet:   h.Get,
+		http.MethodPost:  h.Post,
+		http.MethodPatch: h.Patch,
+	})
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	session := h.SessionService.Session(w, r)
+	boardID := h.BoardID(r)
+
+	if r.FormValue("open") != "1" {
+		h.RenderTemplate(r.Context(), w, h.RenderComponent(boardID))
+		return
+	}
+	h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(session, boardID, ""))
+}
+
+// Patch changes the board's policy and its allowed words, given one per line
+func (h *Handler) Patch(w http.ResponseWriter, r *http.Request) {
+	session := h.SessionService.Session(w, r)
+	boardID := h.BoardID(r)
+
+	if !session.IsAdmin {
+		http.Error(w, "Admin access required", http.StatusForbidden)
+		return
+	}
+
+	policy := services.ModerationPolicy{
+		Mode:    services.ModerationMode(r.FormValue("mode")),
+		Allowed: strings.Split(r.FormValue("allowed"), "\n"),
+	}
+	if err := h.ModerationService.SetPolicy(boardID, policy); err != nil {
+		h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(session, boardID, err.Error()))
+		return
+	}
+
+	props := h.getProps(session, boardID, "")
+	props.Message = "Saved the moderation policy"
+	h.RenderTemplate(r.Context(), w, Moderation(props))
+}
+
+// Post approves a held card, adding it to the board or making its changes, or rejects it
+func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
+	session := h.SessionService.Session(w, r)
+	boardID := h.BoardID(r)
+
+	if !session.IsAdmin {
+		http.Error(w, "Admin access required", http.StatusForbidden)
+		return
+	}
+
+	heldID, err := strconv.Atoi(r.FormValue("heldID"))
+	if err != nil {
+		http.Error(w, "Invalid held card ID", http.StatusBadRequest)
+		return
+	}
+	action := r.FormValue("action")
+	if action != ActionApprove && action != ActionReject {
+		http.Error(w, "Invalid action", http.StatusBadRequest)
+		return
+	}
+	// The board is checked before taking the card, so a card held on another board stays in its queue
+	held, err := h.ModerationService.GetHeldCard(heldID)
+	if err == nil && held.BoardID == boardID {
+		held, err = h.ModerationService.Take(heldID)
+	}
+	if err != nil || held.BoardID != boardID {
+		h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(session, boardID, "That card has already been reviewed"))
+		return
+	}
+
+	switch action {
+	case ActionApprove:
+		card, err := h.approve(held)
+		if err != nil {
+			h.ModerationService.Return(held)
+			h.Log.Error("Failed to approve held card", "heldID", heldID, "error", err)
+			h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(session, boardID, "Could not approve the card: "+err.Error()))
+			return
+		}
+		props := h.getProps(session, boardID, "")
+		props.Message = "Approved “" + held.Details.Title + "”"
+		h.RenderTemplate(r.Context(), w, Moderation(props))
+		h.EventService.PublishCardChanged(session.Name, *card)
+	case ActionReject:
+		h.Log.Info("Rejected held card", "heldID", heldID, "boardID", boardID, "author", held.Author)
+		props := h.getProps(session, boardID, "")
+		props.Message = "Rejected “" + held.Details.Title + "”"
+		h.RenderTemplate(r.Context(), w, Moderation(props))
+	}
+}
+
+// approve adds the held card to the board, or makes the held changes to its card, returning the card as it is then
+func (h *Handler) approve(held *services.HeldCard) (*services.Card, error) {
+	if !held.IsNew() {
+		if err := h.CardService.UpdateApprovedCard(held.CardID, held.Details); err != nil {
+			return nil, err
+		}
+		return h.CardService.GetCard(held.CardID)
+	}
+	return h.CardService.AddApprovedCard(held.Details, held.ColumnID, held.LaneID)
+}
+
+// RenderComponent renders the collapsed panel, which loads the policy and the queue when opened
+func (h *Handler) RenderComponent(boardID int) templ.Component {
+	return Moderation(ModerationProps{BoardID: boardID})
+}
+
+func (h *Handler) RenderOpenComponent(session *services.Session, boardID int, errorMessage string) templ.Component {
+	return Moderation(h.getProps(session, boardID, errorMessage))
+}
+
+func (h *Handler) getProps(session *services.Session, boardID int, errorMessage string) ModerationProps {
+	props := ModerationProps{
+		BoardID: boardID,
+		Open:    true,
+		IsAdmin: session.IsAdmin,
+		Error:   errorMessage,
+	}
+	if !session.IsAdmin {
+		return props
+	}
+
+	props.Policy = h.ModerationService.GetPolicy(boardID)
+	for _, held := range h.ModerationService.GetQueue(boardID) {
+		queued := QueuedCard{HeldCard: held}
+		if column, err := h.CardService.GetColumn(held.ColumnID); err == nil {
+			queued.Column = column.Column.Title
+		}
+		if lane, err := h.CardService.GetLane(held.LaneID); err == nil {
+			queued.Lane = lane.Title
+		}
+		if !held.IsNew() {
+			queued.Card, _ = h.CardService.GetCard(held.CardID)
+		}
+		props.Queue = append(props.Queue, queued)
+	}
+	return props
+}
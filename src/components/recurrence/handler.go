@@ -0,0 +1,125 @@
+package recurrence
+
+import (
+	"log/slog"
+	"mesh/src/components/base"
+	"mesh/src/components/templates"
+	"mesh/src/services"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	FrequencyDaily  = "daily"
+	FrequencyWeekly = "weekly"
+	FrequencyCron   = "cron"
+)
+
+type Handler struct {
+	*base.BaseHandler
+	TemplateService  *services.TemplateService
+	TemplatesHandler *templates.Handler
+}
+
+func New(
+	log *slog.Logger,
+	eventService *services.EventService,
+	sessionService *services.SessionService,
+	templateService *services.TemplateService,
+	templatesHandler *templates.Handler,
+) *Handler {
+	return &Handler{
+		BaseHandler:      base.NewBaseHandler(log, "recurrence", eventService, sessionService),
+		TemplateService:  templateService,
+		TemplatesHandler: templatesHandler,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.BaseHandler.ServeHTTP(w, r, map[string]http.HandlerFunc{
+		http.MethodPost:   h.Post,
+		http.MethodDelete: h.Delete,
+	})
+}
+
+// parseSchedule builds the schedule from the form, which picks daily, weekly or a cron expression
+func parseSchedule(r *http.Request) (*services.Schedule, string) {
+	frequency := r.FormValue("frequency")
+	if frequency == FrequencyCron {
+		schedule, err := services.ParseSchedule(r.FormValue("cron"))
+		if err != nil {
+			return nil, "Invalid cron expression: " + err.Error()
+		}
+		return schedule, ""
+	}
+
+	at, err := time.Parse("15:04", r.FormValue("time"))
+	if err != nil {
+		return nil, "Time must be like 09:00"
+	}
+
+	switch frequency {
+	case FrequencyDaily:
+		return services.DailySchedule(at.Hour(), at.Minute()), ""
+	case FrequencyWeekly:
+		weekday, err := strconv.Atoi(r.FormValue("weekday"))
+		if err != nil || weekday < 0 || weekday > 6 {
+			return nil, "Choose a day of the week"
+		}
+		return services.WeeklySchedule(time.Weekday(weekday), at.Hour(), at.Minute()), ""
+	default:
+		return nil, "Choose how often to repeat"
+	}
+}
+
+func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
+	templateID, err := strconv.Atoi(r.FormValue("templateID"))
+	if err != nil {
+		http.Error(w, "Invalid template ID", http.StatusBadRequest)
+		return
+	}
+	template, err := h.TemplateService.GetTemplate(templateID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	schedule, message := parseSchedule(r)
+	if message != "" {
+		h.RenderTemplate(r.Context(), w, h.TemplatesHandler.RenderOpenComponent(template.BoardID, services.CardTemplate{}, message))
+		return
+	}
+
+	columnID, columnErr := strconv.Atoi(r.FormValue("columnID"))
+	laneID, laneErr := strconv.Atoi(r.FormValue("laneID"))
+	if columnErr != nil || laneErr != nil {
+		http.Error(w, "Invalid column or lane ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.TemplateService.AddRecurrence(template.ID, columnID, laneID, schedule, time.Now()); err != nil {
+		h.Log.Info("Rejected recurrence", "templateID", template.ID, "error", err)
+		h.RenderTemplate(r.Context(), w, h.TemplatesHandler.RenderOpenComponent(template.BoardID, services.CardTemplate{}, "Could not repeat template: "+err.Error()))
+		return
+	}
+
+	h.RenderTemplate(r.Context(), w, h.TemplatesHandler.RenderOpenComponent(template.BoardID, services.CardTemplate{}, ""))
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	boardID := h.BoardID(r)
+
+	recurrenceID, err := strconv.Atoi(r.FormValue("recurrenceID"))
+	if err != nil {
+		http.Error(w, "Invalid recurrence ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.TemplateService.DeleteRecurrence(recurrenceID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	h.RenderTemplate(r.Context(), w, h.TemplatesHandler.RenderOpenComponent(boardID, services.CardTemplate{}, ""))
+}
@@ -4,51 +4,193 @@ package components
 import (
 	"log/slog"
 
+	"mesh/src/components/activity"
+	"mesh/src/components/admin"
+	"mesh/src/components/analytics"
 	"mesh/src/components/app"
+	"mesh/src/components/archive"
+	"mesh/src/components/attachment"
+	"mesh/src/components/automations"
 	"mesh/src/components/board"
 	"mesh/src/components/card"
+	"mesh/src/components/cell"
 	"mesh/src/components/column"
+	"mesh/src/components/comment"
+	"mesh/src/components/filter"
+	"mesh/src/components/inbound"
+	"mesh/src/components/lane"
+	"mesh/src/components/moderation"
+	"mesh/src/components/recurrence"
+	"mesh/src/components/search"
+	"mesh/src/components/templates"
+	"mesh/src/components/trash"
+	"mesh/src/components/undo"
+	"mesh/src/components/webhooks"
 	"mesh/src/services"
 )
 
 // Registry holds references to all component handlers
 type Registry struct {
-	AppHandler    *app.Handler
-	BoardHandler  *board.Handler
-	ColumnHandler *column.Handler
-	CardHandler   *card.Handler
-	CardService   *services.CardService
-	EventService  *services.EventService
-	SSEService    *services.SSEService
-	WordService   *services.WordService
+	AppHandler         *app.Handler
+	BoardHandler       *board.Handler
+	ColumnHandler      *column.Handler
+	CellHandler        *cell.Handler
+	LaneHandler        *lane.Handler
+	CardHandler        *card.Handler
+	AttachmentHandler  *attachment.Handler
+	CommentHandler     *comment.Handler
+	ActivityHandler    *activity.Handler
+	UndoHandler        *undo.Handler
+	TrashHandler       *trash.Handler
+	ArchiveHandler     *archive.Handler
+	AdminHandler       *admin.Handler
+	SearchHandler      *search.Handler
+	FilterHandler      *filter.Handler
+	TemplatesHandler   *templates.Handler
+	RecurrenceHandler  *recurrence.Handler
+	AutomationsHandler *automations.Handler
+	WebhooksHandler    *webhooks.Handler
+	InboundHandler     *inbound.Handler
+	AnalyticsHandler   *analytics.Handler
+	ModerationHandler  *moderation.Handler
+	CardService        *services.CardService
+	EventService       *services.EventService
+	SessionService     *services.SessionService
+	SSEService         *services.SSEService
+	WordService        *services.WordService
+	AttachmentService  *services.AttachmentService
+	MarkdownService    *services.MarkdownService
+	ActivityService    *services.ActivityService
+	UndoService        *services.UndoService
+	RetentionService   *services.RetentionService
+	FilterService      *services.FilterService
+	TemplateService    *services.TemplateService
+	SchedulerService   *services.SchedulerService
+	AutomationService  *services.AutomationService
+	WebhookService     *services.WebhookService
+	InboundService     *services.InboundService
+	AnalyticsService   *services.AnalyticsService
+	FlowService        *services.FlowService
 }
 
 // NewRegistry creates a new registry with all handlers properly initialized
-func NewRegistry(logger *slog.Logger) *Registry {
+func NewRegistry(logger *slog.Logger, config *services.Config) *Registry {
 	// Create services
 	eventService := services.NewEventService(logger)
-	sseService := services.NewSSEService(logger)
-	wordService, err := services.NewWordService(logger, "blacklist.txt")
+	sessionService := services.NewSessionService(logger, config)
+	sseService := services.NewSSEService(logger, sessionService)
+	wordService, err := services.NewWordService(logger, config)
 	if err != nil {
-		panic("Failed to create WordService: missing blacklist.txt")
-		return nil
+		panic("Failed to create WordService: missing " + config.BlacklistPath)
 	}
-	cardService := services.NewCardService(logger, eventService, wordService)
+	attachmentService, err := services.NewAttachmentService(logger, eventService, config)
+	if err != nil {
+		panic("Failed to create AttachmentService: " + err.Error())
+	}
+	markdownService := services.NewMarkdownService(logger, services.NewHTMLSanitiser())
+	moderationService := services.NewModerationService(logger, wordService)
+	cardService, err := services.NewCardService(logger, eventService, wordService, moderationService, config)
+	if err != nil {
+		panic("Failed to create CardService: " + err.Error())
+	}
+	activityService := services.NewActivityService(logger, eventService, cardService)
+	undoService := services.NewUndoService(logger, cardService, eventService)
+	retentionService := services.NewRetentionService(logger, cardService, eventService, config)
+	filterService := services.NewFilterService(logger, cardService)
+	templateService := services.NewTemplateService(logger, cardService)
+	schedulerService := services.NewSchedulerService(logger, templateService, eventService)
+	automationService := services.NewAutomationService(logger, cardService, eventService)
+	webhookService := services.NewWebhookService(logger, cardService, eventService, config)
+	inboundService := services.NewInboundService(logger, cardService)
+	analyticsService := services.NewAnalyticsService(logger, cardService)
+	flowService := services.NewFlowService(logger, cardService)
 
 	// Create handlers with proper dependencies
-	cardHandler := card.New(logger, eventService, cardService, wordService)
-	columnHandler := column.New(logger, cardService, eventService, cardHandler, sseService)
-	boardHandler := board.New(logger, eventService, cardService, columnHandler)
-	appHandler := app.New(logger, eventService, boardHandler)
+	undoHandler := undo.New(logger, eventService, sessionService, undoService)
+	cardHandler := card.New(
+		logger,
+		eventService,
+		sessionService,
+		cardService,
+		wordService,
+		moderationService,
+		attachmentService,
+		markdownService,
+		templateService,
+		undoService,
+		undoHandler,
+	)
+	attachmentHandler := attachment.New(logger, eventService, sessionService, attachmentService, cardService, cardHandler)
+	commentHandler := comment.New(logger, eventService, sessionService, cardService, cardHandler)
+	columnHandler := column.New(logger, cardService, eventService, sessionService, sseService)
+	cellHandler := cell.New(logger, cardService, eventService, sessionService, cardHandler, filterService, sseService)
+	filterHandler := filter.New(logger, eventService, sessionService, filterService, cardService)
+	boardHandler := board.New(
+		logger,
+		eventService,
+		sessionService,
+		cardService,
+		columnHandler,
+		cellHandler,
+		filterHandler,
+		filterService,
+		sseService,
+	)
+	laneHandler := lane.New(logger, eventService, sessionService, cardService, boardHandler)
+	appHandler := app.New(logger, eventService, sessionService, boardHandler)
+	activityHandler := activity.New(logger, eventService, sessionService, activityService)
+	trashHandler := trash.New(logger, eventService, sessionService, cardService)
+	archiveHandler := archive.New(logger, eventService, sessionService, cardService)
+	adminHandler := admin.New(logger, eventService, sessionService, cardService, wordService)
+	searchHandler := search.New(logger, eventService, sessionService, cardService)
+	templatesHandler := templates.New(logger, eventService, sessionService, templateService, cardService, wordService)
+	recurrenceHandler := recurrence.New(logger, eventService, sessionService, templateService, templatesHandler)
+	automationsHandler := automations.New(logger, eventService, sessionService, automationService, cardService, wordService)
+	webhooksHandler := webhooks.New(logger, eventService, sessionService, webhookService)
+	inboundHandler := inbound.New(logger, eventService, sessionService, inboundService, cardService, wordService, cardHandler)
+	moderationHandler := moderation.New(logger, eventService, sessionService, moderationService, cardService)
+	analyticsHandler := analytics.New(logger, eventService, sessionService, analyticsService, flowService, cardService)
 
 	return &Registry{
-		AppHandler:    appHandler,
-		BoardHandler:  boardHandler,
-		ColumnHandler: columnHandler,
-		CardHandler:   cardHandler,
-		CardService:   cardService,
-		EventService:  eventService,
-		SSEService:    sseService,
-		WordService:   wordService,
+		AppHandler:         appHandler,
+		BoardHandler:       boardHandler,
+		ColumnHandler:      columnHandler,
+		CellHandler:        cellHandler,
+		LaneHandler:        laneHandler,
+		CardHandler:        cardHandler,
+		AttachmentHandler:  attachmentHandler,
+		CommentHandler:     commentHandler,
+		ActivityHandler:    activityHandler,
+		UndoHandler:        undoHandler,
+		TrashHandler:       trashHandler,
+		ArchiveHandler:     archiveHandler,
+		AdminHandler:       adminHandler,
+		SearchHandler:      searchHandler,
+		FilterHandler:      filterHandler,
+		TemplatesHandler:   templatesHandler,
+		RecurrenceHandler:  recurrenceHandler,
+		AutomationsHandler: automationsHandler,
+		WebhooksHandler:    webhooksHandler,
+		InboundHandler:     inboundHandler,
+		AnalyticsHandler:   analyticsHandler,
+		ModerationHandler:  moderationHandler,
+		CardService:        cardService,
+		EventService:       eventService,
+		SessionService:     sessionService,
+		SSEService:         sseService,
+		WordService:        wordService,
+		AttachmentService:  attachmentService,
+		MarkdownService:    markdownService,
+		ActivityService:    activityService,
+		UndoService:        undoService,
+		RetentionService:   retentionService,
+		FilterService:      filterService,
+		TemplateService:    templateService,
+		SchedulerService:   schedulerService,
+		AutomationService:  automationService,
+		WebhookService:     webhookService,
+		InboundService:     inboundService,
+		AnalyticsService:   analyticsService,
+		FlowService:        flowService,
 	}
 }
@@ -0,0 +1,112 @@
+package search
+
+import (
+	"encoding/json"
+	"log/slog"
+	"mesh/src/components/base"
+	"mesh/src/services"
+	"net/http"
+	"strings"
+
+	"github.com/a-h/templ"
+)
+
+// Highlight splits the matched fields into segments so clients can mark up the matches
+type Highlight struct {
+	Title   []services.SearchSegment `json:"title"`
+	Snippet []services.SearchSegment `json:"snippet"`
+}
+
+// Result is the JSON form of a search result
+type Result struct {
+	ID        int       `json:"id"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	Labels    []string  `json:"labels"`
+	Column    string    `json:"column"`
+	Lane      string    `json:"lane"`
+	Archived  bool      `json:"archived"`
+	Highlight Highlight `json:"highlight"`
+}
+
+type Response struct {
+	Query   string   `json:"query"`
+	Results []Result `json:"results"`
+}
+
+type Handler struct {
+	*base.BaseHandler
+	*services.CardService
+}
+
+func New(
+	log *slog.Logger,
+	eventService *services.EventService,
+	sessionService *services.SessionService,
+	cardService *services.CardService,
+) *Handler {
+	return &Handler{
+		BaseHandler: base.NewBaseHandler(log, "search", eventService, sessionService),
+		CardService: cardService,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.BaseHandler.ServeHTTP(w, r, map[string]http.HandlerFunc{
+		http.MethodGet: h.Get,
+	})
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	boardID := h.BoardID(r)
+	query := strings.TrimSpace(r.FormValue("q"))
+
+	if r.FormValue("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		h.writeJSON(w, boardID, query)
+		return
+	}
+
+	h.RenderTemplate(r.Context(), w, h.RenderComponentForQuery(boardID, query))
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, boardID int, query string) {
+	_, results := h.CardService.Search(boardID, query)
+
+	response := Response{Query: query, Results: make([]Result, 0, len(results))}
+	for _, result := range results {
+		response.Results = append(response.Results, Result{
+			ID:        result.Card.ID,
+			Title:     result.Card.Title,
+			Content:   result.Card.Content,
+			Labels:    append([]string{}, result.Card.Labels...),
+			Column:    result.ColumnTitle,
+			Lane:      result.LaneTitle,
+			Archived:  result.Card.IsArchived(),
+			Highlight: Highlight{Title: result.Title, Snippet: result.Snippet},
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.Log.Error("Failed to write search results", "error", err)
+	}
+}
+
+// RenderComponent renders an empty search box for the board
+func (h *Handler) RenderComponent(boardID int) templ.Component {
+	return Search(SearchProps{BoardID: boardID})
+}
+
+func (h *Handler) RenderComponentForQuery(boardID int, query string) templ.Component {
+	if query == "" {
+		return h.RenderComponent(boardID)
+	}
+
+	_, results := h.CardService.Search(boardID, query)
+	return Search(SearchProps{
+		BoardID:  boardID,
+		Query:    query,
+		Searched: true,
+		Results:  results,
+	})
+}
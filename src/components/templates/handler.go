@@ -0,0 +1,194 @@
+package templates
+
+import (
+	"log/slog"
+	"mesh/src/components/base"
+	"mesh/src/components/card"
+	"mesh/src/services"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/a-h/templ"
+)
+
+type Handler struct {
+	*base.BaseHandler
+	TemplateService *services.TemplateService
+	CardService     *services.CardService
+	WordService     *services.WordService
+}
+
+func New(
+	log *slog.Logger,
+	eventService *services.EventService,
+	sessionService *services.SessionService,
+	templateService *services.TemplateService,
+	cardService *services.CardService,
+	wordService *services.WordService,
+) *Handler {
+	return &Handler{
+		BaseHandler:     base.NewBaseHandler(log, "templates", eventService, sessionService),
+		TemplateService: templateService,
+		CardService:     cardService,
+		WordService:     wordService,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.BaseHandler.ServeHTTP(w, r, map[string]http.HandlerFunc{
+		http.MethodGet:    h.Get,
+		http.MethodPost:   h.Post,
+		http.MethodPatch:  h.Patch,
+		http.MethodDelete: h.Delete,
+	})
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	boardID := h.BoardID(r)
+	if r.FormValue("open") != "1" {
+		h.RenderTemplate(r.Context(), w, h.RenderComponent(boardID))
+		return
+	}
+	h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(boardID, services.CardTemplate{}, ""))
+}
+
+// validate reads a template from the form, returning a message explaining the first problem with it
+func (h *Handler) validate(r *http.Request) (services.CardTemplate, string) {
+	template := services.CardTemplate{
+		BoardID: h.BoardID(r),
+		Name:    strings.TrimSpace(r.FormValue("name")),
+		Title:   strings.TrimSpace(r.FormValue("title")),
+		Content: strings.TrimSpace(r.FormValue("content")),
+	}
+
+	labels, labelsError := card.ParseLabels(r.FormValue("labels"))
+	template.Labels = labels
+
+	for _, item := range strings.Split(r.FormValue("checklist"), "\n") {
+		if item = strings.TrimSpace(item); item != "" {
+			template.Checklist = append(template.Checklist, item)
+		}
+	}
+
+	switch {
+	case template.Name == "":
+		return template, "Name is required"
+	case len(template.Name) > 50:
+		return template, "Name must be less than 50 characters"
+	case template.Title == "":
+		return template, "Title is required"
+	case len(template.Title) > 100:
+		return template, "Title must be less than 100 characters"
+	case len(template.Content) > 1000:
+		return template, "Content must be less than 1000 characters"
+	case labelsError != "":
+		return template, labelsError
+	case len(template.Checklist) > 20:
+		return template, "Checklists can have at most 20 items"
+	}
+
+	text := strings.Join(append([]string{template.Name, template.Title, template.Content}, template.Checklist...), " ")
+	if blacklistedWord := h.WordService.Filter(text + " " + strings.Join(labels, " ")); blacklistedWord != "" {
+		return template, "Let's keep it light shall we"
+	}
+	return template, ""
+}
+
+func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
+	actor := h.Actor(w, r)
+
+	template, message := h.validate(r)
+	if message != "" {
+		h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(template.BoardID, template, message))
+		return
+	}
+
+	if _, err := h.TemplateService.AddTemplate(template); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(template.BoardID, services.CardTemplate{}, ""))
+	h.EventService.PublishTemplateChanged(actor, template.BoardID)
+}
+
+func (h *Handler) Patch(w http.ResponseWriter, r *http.Request) {
+	actor := h.Actor(w, r)
+
+	templateID, err := strconv.Atoi(r.FormValue("templateID"))
+	if err != nil {
+		http.Error(w, "Invalid template ID", http.StatusBadRequest)
+		return
+	}
+	existing, err := h.TemplateService.GetTemplate(templateID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	template, message := h.validate(r)
+	if message != "" {
+		h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(existing.BoardID, services.CardTemplate{}, message))
+		return
+	}
+
+	template.ID = existing.ID
+	if err := h.TemplateService.UpdateTemplate(template); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(existing.BoardID, services.CardTemplate{}, ""))
+	h.EventService.PublishTemplateChanged(actor, existing.BoardID)
+}
+
+// Delete removes a template, which stops its recurrences too
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	actor := h.Actor(w, r)
+
+	templateID, err := strconv.Atoi(r.FormValue("templateID"))
+	if err != nil {
+		http.Error(w, "Invalid template ID", http.StatusBadRequest)
+		return
+	}
+	template, err := h.TemplateService.GetTemplate(templateID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if err := h.TemplateService.DeleteTemplate(template.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(template.BoardID, services.CardTemplate{}, ""))
+	h.EventService.PublishTemplateChanged(actor, template.BoardID)
+}
+
+// RenderComponent renders the collapsed panel
+func (h *Handler) RenderComponent(boardID int) templ.Component {
+	return Templates(TemplatesProps{BoardID: boardID})
+}
+
+// RenderOpenComponent renders the board's templates and their recurrences, with draft filling in the new template form
+func (h *Handler) RenderOpenComponent(boardID int, draft services.CardTemplate, errorMessage string) templ.Component {
+	props := TemplatesProps{
+		BoardID: boardID,
+		Open:    true,
+		Lanes:   h.CardService.GetLanes(boardID),
+		Draft:   draft,
+		Error:   errorMessage,
+	}
+	for _, column := range h.CardService.GetColumns(boardID) {
+		props.Columns = append(props.Columns, column.Column)
+	}
+	for _, template := range h.TemplateService.GetTemplates(boardID) {
+		props.Templates = append(props.Templates, TemplateRow{
+			Template:    template,
+			Recurrences: h.TemplateService.GetRecurrences(template.ID),
+		})
+	}
+	return Templates(props)
+}
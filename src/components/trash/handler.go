@@ -0,0 +1,133 @@
+package trash
+
+import (
+	"errors"
+	"log/slog"
+	"mesh/src/components/base"
+	"mesh/src/services"
+	"net/http"
+	"strconv"
+
+	"github.com/a-h/templ"
+)
+
+const (
+	ActionRestore = "restore"
+	ActionPurge   = "purge"
+)
+
+type Handler struct {
+	*base.BaseHandler
+	*services.CardService
+}
+
+func New(
+	log *slog.Logger,
+	eventService *services.EventService,
+	sessionService *services.SessionService,
+	cardService *services.CardService,
+) *Handler {
+	return &Handler{
+		BaseHandler: base.NewBaseHandler(log, "trash", eventService, sessionService),
+		CardService: cardService,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.BaseHandler.ServeHTTP(w, r, map[string]http.HandlerFunc{
+		http.MethodGet:    h.Get,
+		http.MethodPost:   h.Post,
+		http.MethodDelete: h.Delete,
+	})
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	boardID := h.BoardID(r)
+	if r.FormValue("open") != "1" {
+		h.RenderTemplate(r.Context(), w, h.RenderComponent(boardID))
+		return
+	}
+	h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(boardID, ""))
+}
+
+// Post restores or permanently deletes a single card
+func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
+	actor := h.Actor(w, r)
+	boardID := h.BoardID(r)
+
+	cardID, err := strconv.Atoi(r.FormValue("cardID"))
+	if err != nil {
+		http.Error(w, "Invalid card ID", http.StatusBadRequest)
+		return
+	}
+	if !h.inTrash(boardID, cardID) {
+		http.Error(w, "Card not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.FormValue("action") {
+	case ActionRestore:
+		card, err := h.CardService.RestoreCard(cardID)
+		var limitErr *services.WIPLimitError
+		if errors.As(err, &limitErr) {
+			h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(boardID, "Could not restore card: "+limitErr.Error()))
+			return
+		}
+		if err != nil {
+			h.Log.Error("Failed to restore card", "cardID", cardID, "error", err)
+			h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(boardID, "Could not restore card"))
+			return
+		}
+		h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(boardID, ""))
+		h.EventService.PublishCardRestored(actor, card.ID, card.Cell())
+	case ActionPurge:
+		if err := h.CardService.PurgeCard(cardID); err != nil {
+			h.Log.Error("Failed to purge card", "cardID", cardID, "error", err)
+			h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(boardID, "Could not delete card"))
+			return
+		}
+		h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(boardID, ""))
+		h.EventService.PublishCardPurged(actor, cardID)
+	default:
+		http.Error(w, "Invalid action", http.StatusBadRequest)
+	}
+}
+
+// inTrash says whether the card is in the board's trash, going by the column it was deleted from
+func (h *Handler) inTrash(boardID, cardID int) bool {
+	trashed, err := h.CardService.GetTrashedCard(cardID)
+	if err != nil {
+		return false
+	}
+	column, err := h.CardService.GetColumn(trashed.Card.ColumnID)
+	return err == nil && column.Column.BoardID == boardID
+}
+
+// Delete empties the trash for a board
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	actor := h.Actor(w, r)
+	boardID := h.BoardID(r)
+
+	for _, trashed := range h.CardService.GetTrash(boardID) {
+		if err := h.CardService.PurgeCard(trashed.Card.ID); err != nil {
+			h.Log.Error("Failed to purge card", "cardID", trashed.Card.ID, "error", err)
+			continue
+		}
+		h.EventService.PublishCardPurged(actor, trashed.Card.ID)
+	}
+	h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(boardID, ""))
+}
+
+// RenderComponent renders the collapsed panel, which loads its cards when opened
+func (h *Handler) RenderComponent(boardID int) templ.Component {
+	return Trash(TrashProps{BoardID: boardID})
+}
+
+func (h *Handler) RenderOpenComponent(boardID int, errorMessage string) templ.Component {
+	return Trash(TrashProps{
+		BoardID: boardID,
+		Open:    true,
+		Cards:   h.CardService.GetTrash(boardID),
+		Error:   errorMessage,
+	})
+}
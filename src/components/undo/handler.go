@@ -0,0 +1,67 @@
+package undo
+
+import (
+	"log/slog"
+	"mesh/src/components/base"
+	"mesh/src/services"
+	"net/http"
+	"strings"
+
+	"github.com/a-h/templ"
+)
+
+type Handler struct {
+	*base.BaseHandler
+	*services.UndoService
+}
+
+func New(
+	log *slog.Logger,
+	eventService *services.EventService,
+	sessionService *services.SessionService,
+	undoService *services.UndoService,
+) *Handler {
+	return &Handler{
+		BaseHandler: base.NewBaseHandler(log, "undo", eventService, sessionService),
+		UndoService: undoService,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.BaseHandler.ServeHTTP(w, r, map[string]http.HandlerFunc{
+		http.MethodPost: h.Post,
+	})
+}
+
+// Post undoes the session's last operation, or redoes it when mounted at /redo
+func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
+	session := h.SessionService.Session(w, r)
+
+	var operation services.Operation
+	var err error
+	var verb string
+	if strings.HasSuffix(r.URL.Path, "/redo") {
+		operation, err = h.UndoService.Redo(session.ID, session.Name)
+		verb = "Redid"
+	} else {
+		operation, err = h.UndoService.Undo(session.ID, session.Name)
+		verb = "Undid"
+	}
+
+	message := ""
+	if err != nil {
+		message = strings.ToUpper(err.Error()[:1]) + err.Error()[1:]
+	} else {
+		message = verb + " " + operation.Describe()
+	}
+
+	h.RenderTemplate(r.Context(), w, h.RenderComponent(session, message))
+}
+
+func (h *Handler) RenderComponent(session *services.Session, message string) templ.Component {
+	return Undo(UndoProps{
+		Message: message,
+		CanUndo: h.UndoService.CanUndo(session.ID),
+		CanRedo: h.UndoService.CanRedo(session.ID),
+	})
+}
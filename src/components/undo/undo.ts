@@ -0,0 +1,35 @@
+import {MeshElement} from "../base/mesh-element.ts";
+
+import {X} from 'lucide';
+
+export class Undo extends MeshElement {
+    protected icons = {
+        X,
+    };
+
+    private timeout: number | null = null;
+
+    connectedCallback() {
+        // The toast outlives whichever component rendered it, so it moves itself to the body
+        if (this.parentNode !== document.body) {
+            document.querySelectorAll('mesh-undo').forEach(toast => toast.remove());
+            document.body.appendChild(this);
+            return;
+        }
+
+        super.connectedCallback();
+        this.timeout = window.setTimeout(() => this.dismiss(), 8000);
+    }
+
+    disconnectedCallback() {
+        if (this.timeout) {
+            window.clearTimeout(this.timeout);
+            this.timeout = null;
+        }
+    }
+
+    dismiss() {
+        this.remove();
+    }
+}
+window.customElements.define('mesh-undo', Undo);
@@ -0,0 +1,168 @@
+package webhooks
+
+import (
+	"fmt"
+	"log/slog"
+	"mesh/src/components/base"
+	"mesh/src/services"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/a-h/templ"
+)
+
+// deliveryLimit is how many deliveries the log shows
+const deliveryLimit = 30
+
+type Handler struct {
+	*base.BaseHandler
+	WebhookService *services.WebhookService
+}
+
+func New(
+	log *slog.Logger,
+	eventService *services.EventService,
+	sessionService *services.SessionService,
+	webhookService *services.WebhookService,
+) *Handler {
+	return &Handler{
+		BaseHandler:    base.NewBaseHandler(log, "webhooks", eventService, sessionService),
+		WebhookService: webhookService,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.BaseHandler.ServeHTTP(w, r, map[string]http.HandlerFunc{
+		http.MethodGet:    h.Get,
+		http.MethodPost:   h.Post,
+		http.MethodPut:    h.Put,
+		http.MethodDelete: h.Delete,
+	})
+}
+
+// Get renders the panel, with the delivery log narrowed down to one status if one is given
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	session := h.SessionService.Session(w, r)
+	boardID := h.BoardID(r)
+	if r.FormValue("open") != "1" {
+		h.RenderTemplate(r.Context(), w, h.RenderComponent(boardID))
+		return
+	}
+	status := services.DeliveryStatus(r.FormValue("status"))
+	h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(session, boardID, status, ""))
+}
+
+// Post adds a webhook, showing its secret this once
+func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
+	session := h.SessionService.Session(w, r)
+	boardID := h.BoardID(r)
+
+	if !session.IsAdmin {
+		http.Error(w, "Admin access required", http.StatusForbidden)
+		return
+	}
+
+	url := strings.TrimSpace(r.FormValue("url"))
+	secret := strings.TrimSpace(r.FormValue("secret"))
+
+	message := ""
+	var created *services.Webhook
+	switch {
+	case url == "":
+		message = "URL is required"
+	case len(url) > 500:
+		message = "URL must be less than 500 characters"
+	case len(secret) > 100:
+		message = "Secret must be less than 100 characters"
+	default:
+		var err error
+		if created, err = h.WebhookService.AddWebhook(boardID, url, secret, r.Form["events"]); err != nil {
+			message = err.Error()
+		}
+	}
+
+	props := h.getProps(session, boardID, "", message)
+	props.Created = created
+	h.RenderTemplate(r.Context(), w, Webhooks(props))
+}
+
+// Put redelivers a dead letter
+func (h *Handler) Put(w http.ResponseWriter, r *http.Request) {
+	session := h.SessionService.Session(w, r)
+	boardID := h.BoardID(r)
+
+	if !session.IsAdmin {
+		http.Error(w, "Admin access required", http.StatusForbidden)
+		return
+	}
+
+	deliveryID, err := strconv.Atoi(r.FormValue("deliveryID"))
+	if err != nil {
+		http.Error(w, "Invalid delivery ID", http.StatusBadRequest)
+		return
+	}
+	delivery, err := h.WebhookService.GetDelivery(deliveryID)
+	if err != nil || delivery.BoardID != boardID {
+		http.Error(w, fmt.Sprintf("delivery with ID %d not found", deliveryID), http.StatusNotFound)
+		return
+	}
+
+	message := ""
+	if err := h.WebhookService.Redeliver(delivery.ID); err != nil {
+		message = err.Error()
+	}
+	h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(session, boardID, "", message))
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	session := h.SessionService.Session(w, r)
+	boardID := h.BoardID(r)
+
+	if !session.IsAdmin {
+		http.Error(w, "Admin access required", http.StatusForbidden)
+		return
+	}
+
+	webhookID, err := strconv.Atoi(r.FormValue("webhookID"))
+	if err != nil {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+	webhook, err := h.WebhookService.GetWebhook(webhookID)
+	if err != nil || webhook.BoardID != boardID {
+		http.Error(w, fmt.Sprintf("webhook with ID %d not found", webhookID), http.StatusNotFound)
+		return
+	}
+
+	if err := h.WebhookService.DeleteWebhook(webhook.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	h.RenderTemplate(r.Context(), w, h.RenderOpenComponent(session, boardID, "", ""))
+}
+
+// RenderComponent renders the collapsed panel
+func (h *Handler) RenderComponent(boardID int) templ.Component {
+	return Webhooks(WebhooksProps{BoardID: boardID})
+}
+
+// RenderOpenComponent renders the board's webhooks and the log of their deliveries, which only admins can see
+func (h *Handler) RenderOpenComponent(session *services.Session, boardID int, status services.DeliveryStatus, errorMessage string) templ.Component {
+	return Webhooks(h.getProps(session, boardID, status, errorMessage))
+}
+
+func (h *Handler) getProps(session *services.Session, boardID int, status services.DeliveryStatus, errorMessage string) WebhooksProps {
+	props := WebhooksProps{
+		BoardID: boardID,
+		Open:    true,
+		IsAdmin: session.IsAdmin,
+		Status:  status,
+		Error:   errorMessage,
+	}
+	if session.IsAdmin {
+		props.Webhooks = h.WebhookService.GetWebhooks(boardID)
+		props.Deliveries = h.WebhookService.GetDeliveries(boardID, status, deliveryLimit)
+	}
+	return props
+}
@@ -0,0 +1,5 @@
+import {MeshElement} from "../base/mesh-element.ts";
+
+export class Webhooks extends MeshElement {
+}
+window.customElements.define('mesh-webhooks', Webhooks);
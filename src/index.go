@@ -6,8 +6,10 @@ import (
 	"html/template"
 	"log"
 	"mesh/src/components"
+	"mesh/src/services"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 )
 
@@ -64,6 +66,9 @@ func getAssetsFromManifest(manifest ViteManifest) (string, string) {
 
 func IndexHandler(registry *components.Registry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		// Start a session up front so the cookie is set before any component requests
+		session := registry.SessionService.Session(w, r)
+
 		manifest, err := loadViteManifest()
 		if err != nil {
 			log.Printf("Error loading Vite manifest: %v", err)
@@ -88,7 +93,7 @@ func IndexHandler(registry *components.Registry) http.HandlerFunc {
 		}
 
 		buf := new(bytes.Buffer)
-		appComponent := registry.AppHandler.RenderComponent()
+		appComponent := registry.AppHandler.RenderComponent(indexBoardID(registry, r), session.ID)
 		err = appComponent.Render(r.Context(), buf)
 		if err != nil {
 			log.Printf("Error rendering app template: %v", err)
@@ -111,3 +116,15 @@ func IndexHandler(registry *components.Registry) http.HandlerFunc {
 		}
 	}
 }
+
+// indexBoardID is the board asked for with ?boardID=, such as one just imported, or the default board
+func indexBoardID(registry *components.Registry, r *http.Request) int {
+	boardID, err := strconv.Atoi(r.URL.Query().Get("boardID"))
+	if err != nil {
+		return services.DefaultBoardID
+	}
+	if _, err := registry.CardService.GetBoard(boardID); err != nil {
+		return services.DefaultBoardID
+	}
+	return boardID
+}
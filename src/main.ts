@@ -3,6 +3,20 @@ import './main.scss';
 import './components/app/app';
 import './components/board/board';
 import './components/column/column';
+import './components/cell/cell';
 import './components/card/card';
+import './components/activity/activity';
+import './components/undo/undo';
+import './components/trash/trash';
+import './components/archive/archive';
+import './components/moderation/moderation';
+import './components/admin/admin';
+import './components/search/search';
+import './components/filter/filter';
+import './components/templates/templates';
+import './components/automations/automations';
+import './components/webhooks/webhooks';
+import './components/inbound/inbound';
+import './components/analytics/analytics';
 
 import './sse.ts';
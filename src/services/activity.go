@@ -0,0 +1,336 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type ActivityKind string
+
+const (
+	ActivityCreated  ActivityKind = "created"
+	ActivityChanged  ActivityKind = "changed"
+	ActivityMoved    ActivityKind = "moved"
+	ActivityDeleted  ActivityKind = "deleted"
+	ActivityArchived ActivityKind = "archived"
+	ActivityRestored ActivityKind = "restored"
+	ActivityPurged   ActivityKind = "purged"
+	ActivityComment  ActivityKind = "commented"
+)
+
+type FieldChange struct {
+	Field  string
+	Before string
+	After  string
+}
+
+type Activity struct {
+	ID         int
+	Kind       ActivityKind
+	BoardID    int
+	CardID     int
+	CardTitle  string
+	Actor      string
+	Time       time.Time
+	FromColumn string
+	ToColumn   string
+	Changes    []FieldChange
+}
+
+type ActivityPage struct {
+	Entries  []Activity
+	Page     int
+	PageSize int
+	Total    int
+}
+
+func (p *ActivityPage) HasPrevious() bool {
+	return p.Page > 1
+}
+
+func (p *ActivityPage) HasNext() bool {
+	return p.Page*p.PageSize < p.Total
+}
+
+// ActivityService keeps an append-only audit log of every card event
+type ActivityService struct {
+	mu        sync.RWMutex
+	entries   []Activity   // oldest first, never modified once added
+	snapshots map[int]Card // cardID -> last known state, for computing diffs
+
+	log         *slog.Logger
+	cardService *CardService
+}
+
+func NewActivityService(log *slog.Logger, eventService *EventService, cardService *CardService) *ActivityService {
+	service := &ActivityService{
+		snapshots:   make(map[int]Card),
+		log:         log,
+		cardService: cardService,
+	}
+
+	for _, card := range cardService.GetCards() {
+		service.snapshots[card.ID] = card
+	}
+
+	eventService.SubscribeCardChanged("activity", service.OnCardChanged)
+	eventService.SubscribeCardMoved("activity", service.OnCardMoved)
+	eventService.SubscribeCardDeleted("activity", service.OnCardDeleted)
+	eventService.SubscribeCardArchived("activity", service.OnCardArchived)
+	eventService.SubscribeCardRestored("activity", service.OnCardRestored)
+	eventService.SubscribeCardPurged("activity", service.OnCardPurged)
+	eventService.SubscribeCardCommented("activity", service.OnCardCommented)
+	return service
+}
+
+// OnCardChanged compares the card as the event has it with how the card was at the previous event, rather than
+// with the card now, which could already include later changes by someone else
+func (a *ActivityService) OnCardChanged(event *CardChangedEvent) {
+	card := &event.Card
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	before, known := a.snapshots[card.ID]
+	kind := ActivityChanged
+	if !known {
+		kind = ActivityCreated
+	}
+
+	a.append(Activity{
+		Kind:      kind,
+		BoardID:   a.boardOf(card.ColumnID),
+		CardID:    card.ID,
+		CardTitle: card.Title,
+		Actor:     event.Actor,
+		Time:      event.Time,
+		Changes:   diffCards(before, *card),
+	})
+	a.snapshots[card.ID] = *card
+}
+
+func (a *ActivityService) OnCardMoved(event *CardMovedEvent) {
+	fromColumn := a.columnTitle(event.FromColumnID)
+	toColumn := a.columnTitle(event.ToColumnID)
+
+	var changes []FieldChange
+	if event.FromColumnID != event.ToColumnID {
+		changes = append(changes, FieldChange{Field: "Column", Before: fromColumn, After: toColumn})
+	}
+	if event.FromLaneID != event.ToLaneID {
+		fromLane := a.laneTitle(event.FromLaneID)
+		toLane := a.laneTitle(event.ToLaneID)
+		changes = append(changes, FieldChange{Field: "Lane", Before: fromLane, After: toLane})
+
+		// Name the lanes too, otherwise a move between lanes reads as a move to the same place
+		fromColumn = fmt.Sprintf("%s (%s)", fromColumn, fromLane)
+		toColumn = fmt.Sprintf("%s (%s)", toColumn, toLane)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	card := a.snapshots[event.CardID]
+	a.append(Activity{
+		Kind:       ActivityMoved,
+		BoardID:    a.boardOf(event.ToColumnID),
+		CardID:     event.CardID,
+		CardTitle:  card.Title,
+		Actor:      event.Actor,
+		Time:       event.Time,
+		FromColumn: fromColumn,
+		ToColumn:   toColumn,
+		Changes:    changes,
+	})
+	card.ID, card.ColumnID, card.LaneID = event.CardID, event.ToColumnID, event.ToLaneID
+	a.snapshots[event.CardID] = card
+}
+
+func (a *ActivityService) OnCardDeleted(event *CardDeletedEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// The snapshot is kept while the card is in the trash, in case it is restored
+	before := a.snapshots[event.CardID]
+	a.append(Activity{
+		Kind:      ActivityDeleted,
+		BoardID:   a.boardOf(event.ColumnID),
+		CardID:    event.CardID,
+		CardTitle: before.Title,
+		Actor:     event.Actor,
+		Time:      event.Time,
+	})
+}
+
+func (a *ActivityService) OnCardArchived(event *CardArchivedEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.append(Activity{
+		Kind:      ActivityArchived,
+		BoardID:   a.boardOf(event.ColumnID),
+		CardID:    event.CardID,
+		CardTitle: a.snapshots[event.CardID].Title,
+		Actor:     event.Actor,
+		Time:      event.Time,
+	})
+}
+
+func (a *ActivityService) OnCardRestored(event *CardRestoredEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.append(Activity{
+		Kind:      ActivityRestored,
+		BoardID:   a.boardOf(event.ColumnID),
+		CardID:    event.CardID,
+		CardTitle: a.snapshots[event.CardID].Title,
+		Actor:     event.Actor,
+		Time:      event.Time,
+	})
+}
+
+func (a *ActivityService) OnCardPurged(event *CardPurgedEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.append(Activity{
+		Kind:      ActivityPurged,
+		BoardID:   a.boardOf(a.snapshots[event.CardID].ColumnID),
+		CardID:    event.CardID,
+		CardTitle: a.snapshots[event.CardID].Title,
+		Actor:     event.Actor,
+		Time:      event.Time,
+	})
+	delete(a.snapshots, event.CardID)
+}
+
+func (a *ActivityService) OnCardCommented(event *CardCommentedEvent) {
+	var body string
+	for _, comment := range a.cardService.GetComments(event.CardID) {
+		if comment.ID == event.CommentID {
+			body = comment.Body
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.append(Activity{
+		Kind:      ActivityComment,
+		BoardID:   a.boardOf(a.snapshots[event.CardID].ColumnID),
+		CardID:    event.CardID,
+		CardTitle: a.snapshots[event.CardID].Title,
+		Actor:     event.Actor,
+		Time:      event.Time,
+		Changes:   []FieldChange{{Field: "Comment", After: body}},
+	})
+}
+
+// append adds the activity in order of when its event was published. Events about different cards are delivered
+// concurrently, so one can arrive after a later one and is slotted in behind it.
+func (a *ActivityService) append(activity Activity) {
+	activity.ID = len(a.entries) + 1
+	at := len(a.entries)
+	for at > 0 && a.entries[at-1].Time.After(activity.Time) {
+		at--
+	}
+	a.entries = slices.Insert(a.entries, at, activity)
+}
+
+// boardOf is the board the column is on, or 0 if it can't be found
+func (a *ActivityService) boardOf(columnID int) int {
+	column, err := a.cardService.GetColumn(columnID)
+	if err != nil {
+		return 0
+	}
+	return column.Column.BoardID
+}
+
+func (a *ActivityService) columnTitle(columnID int) string {
+	column, err := a.cardService.GetColumn(columnID)
+	if err != nil {
+		return ""
+	}
+	return column.Column.Title
+}
+
+func (a *ActivityService) laneTitle(laneID int) string {
+	lane, err := a.cardService.GetLane(laneID)
+	if err != nil {
+		return ""
+	}
+	return lane.Title
+}
+
+// GetFeed returns a page of activity across the whole board, newest first
+func (a *ActivityService) GetFeed(boardID, page, pageSize int) ActivityPage {
+	return a.getPage(page, pageSize, func(activity *Activity) bool {
+		return activity.BoardID == boardID
+	})
+}
+
+// GetCardHistory returns a page of activity for a single card, newest first
+func (a *ActivityService) GetCardHistory(cardID, page, pageSize int) ActivityPage {
+	return a.getPage(page, pageSize, func(activity *Activity) bool {
+		return activity.CardID == cardID
+	})
+}
+
+func (a *ActivityService) getPage(page, pageSize int, match func(activity *Activity) bool) ActivityPage {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	page = max(page, 1)
+	result := ActivityPage{Page: page, PageSize: pageSize}
+	skip := (page - 1) * pageSize
+
+	for i := len(a.entries) - 1; i >= 0; i-- {
+		if !match(&a.entries[i]) {
+			continue
+		}
+		result.Total++
+		if result.Total > skip && len(result.Entries) < pageSize {
+			entry := a.entries[i]
+			entry.Changes = slices.Clone(entry.Changes)
+			result.Entries = append(result.Entries, entry)
+		}
+	}
+
+	return result
+}
+
+func diffCards(before, after Card) []FieldChange {
+	var changes []FieldChange
+	if before.Title != after.Title {
+		changes = append(changes, FieldChange{Field: "Title", Before: before.Title, After: after.Title})
+	}
+	if before.Content != after.Content {
+		changes = append(changes, FieldChange{Field: "Content", Before: before.Content, After: after.Content})
+	}
+	if !slices.Equal(before.Labels, after.Labels) {
+		changes = append(changes, FieldChange{
+			Field:  "Labels",
+			Before: strings.Join(before.Labels, ", "),
+			After:  strings.Join(after.Labels, ", "),
+		})
+	}
+	if before.Assignee != after.Assignee {
+		changes = append(changes, FieldChange{Field: "Assignee", Before: before.Assignee, After: after.Assignee})
+	}
+	if !before.DueAt.Equal(after.DueAt) {
+		changes = append(changes, FieldChange{Field: "Due", Before: formatDueDate(before), After: formatDueDate(after)})
+	}
+	return changes
+}
+
+func formatDueDate(card Card) string {
+	if !card.HasDueDate() {
+		return ""
+	}
+	return card.DueAt.Format("2 Jan 2006")
+}
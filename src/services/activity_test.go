@@ -0,0 +1,51 @@
+package services
+
+import "testing"
+
+func TestActivityRecordsEachEditAsItWasMade(t *testing.T) {
+	events := NewEventService(newTestLogger())
+	cards := newTestCardServiceWith(t, events)
+	activity := NewActivityService(newTestLogger(), events, cards)
+
+	edit := func(actor, title string) {
+		card, err := cards.GetCard(1)
+		if err != nil {
+			t.Fatalf("GetCard: %v", err)
+		}
+		details := card.Details()
+		details.Title = title
+		if err := cards.UpdateCard(1, details); err != nil {
+			t.Fatalf("UpdateCard: %v", err)
+		}
+		if card, err = cards.GetCard(1); err != nil {
+			t.Fatalf("GetCard: %v", err)
+		}
+		events.PublishCardChanged(actor, *card)
+	}
+
+	// Holding the log back makes sure both edits are made before it hears of the first
+	activity.mu.Lock()
+	edit("alice", "Write more tests")
+	edit("bob", "Write all the tests")
+	activity.mu.Unlock()
+	events.Wait()
+
+	history := activity.GetCardHistory(1, 1, 10)
+	if len(history.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2: %+v", len(history.Entries), history.Entries)
+	}
+	want := []struct {
+		actor         string
+		before, after string
+	}{
+		{actor: "bob", before: "Write more tests", after: "Write all the tests"},
+		{actor: "alice", before: "Write tests", after: "Write more tests"},
+	}
+	for i, entry := range history.Entries {
+		if entry.Actor != want[i].actor || len(entry.Changes) != 1 ||
+			entry.Changes[0].Before != want[i].before || entry.Changes[0].After != want[i].after {
+			t.Errorf("entry %d = %s changing %+v, want %s changing the title from %q to %q",
+				i, entry.Actor, entry.Changes, want[i].actor, want[i].before, want[i].after)
+		}
+	}
+}
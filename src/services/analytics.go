@@ -0,0 +1,296 @@
+package services
+
+import (
+	"log/slog"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ColumnVisit is a stretch of time a card spent in a column; LeftAt is zero while it's still there
+type ColumnVisit struct {
+	ColumnID  int
+	EnteredAt time.Time
+	LeftAt    time.Time
+}
+
+// CardFlow is a card's way across its board, one visit for each time it entered a column
+type CardFlow struct {
+	CardID  int
+	BoardID int
+	Title   string
+	Visits  []ColumnVisit
+}
+
+// CardTimes is what a card's flow comes to. A card is started once it first leaves the board's first column and
+// done while its latest visit is to the last one; lead time runs from creation to done, cycle time from start.
+type CardTimes struct {
+	CardID    int
+	Title     string
+	CreatedAt time.Time
+	StartedAt time.Time
+	DoneAt    time.Time
+	LeadTime  time.Duration
+	CycleTime time.Duration
+	InColumn  map[int]time.Duration // columnID -> time spent there
+}
+
+func (t *CardTimes) IsStarted() bool {
+	return !t.StartedAt.IsZero()
+}
+
+func (t *CardTimes) IsDone() bool {
+	return !t.DoneAt.IsZero()
+}
+
+// Percentiles summarises a set of durations, using the nearest rank
+type Percentiles struct {
+	Count int
+	P50   time.Duration
+	P85   time.Duration
+	P95   time.Duration
+}
+
+// FlowReport is a board's lead and cycle times for the cards done between From and To
+type FlowReport struct {
+	BoardID         int
+	From            time.Time
+	To              time.Time
+	Columns         []Column
+	Done            []CardTimes // done within the range, earliest first
+	InProgress      []CardTimes // started and not done, oldest first
+	LeadTime        Percentiles
+	CycleTime       Percentiles
+	AverageInColumn map[int]time.Duration // columnID -> mean time the cards done spent there
+}
+
+// AnalyticsService follows each card's way across its board. The flows are built from the card history, so every
+// visit starts and ends when the event that caused it was recorded, however late its notification arrives.
+type AnalyticsService struct {
+	mu        sync.Mutex
+	flows     map[int]*CardFlow // cardID -> CardFlow
+	replay    *CardService      // the history applied so far, for the boards columns belonged to at the time
+	builtFrom int               // how many events the flows were built from
+
+	log         *slog.Logger
+	cardService *CardService
+}
+
+func NewAnalyticsService(log *slog.Logger, cardService *CardService) *AnalyticsService {
+	a := &AnalyticsService{
+		flows:       make(map[int]*CardFlow),
+		replay:      newCardService(log, nil, cardService.wordService),
+		log:         log,
+		cardService: cardService,
+	}
+	a.refresh()
+	return a
+}
+
+// refresh catches the flows up with whatever has been recorded since they were last built. The history is only
+// ever appended to, so the events already applied are left as they are.
+func (a *AnalyticsService) refresh() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.builtFrom == a.cardService.historyLength() {
+		return
+	}
+	history := a.cardService.History()
+	for _, recorded := range history[a.builtFrom:] {
+		a.apply(recorded)
+	}
+	a.builtFrom = len(history)
+}
+
+// apply opens and closes the visits the event caused; the caller holds the lock
+func (a *AnalyticsService) apply(recorded RecordedEvent) {
+	recorded.Event.apply(a.replay)
+
+	switch event := recorded.Event.(type) {
+	case *CardCreated:
+		a.enter(event.Card.ID, event.Card.Title, event.Card.ColumnID, recorded.Time)
+	case *CardUpdated:
+		if flow, exists := a.flows[event.CardID]; exists {
+			flow.Title = event.After.Title
+		}
+	case *CardMoved:
+		if event.From.ColumnID != event.To.ColumnID {
+			a.leave(event.CardID, recorded.Time)
+			a.enter(event.CardID, "", event.To.ColumnID, recorded.Time)
+		}
+	case *CardDeleted:
+		a.leave(event.Card.ID, recorded.Time)
+	case *CardArchived:
+		a.leave(event.CardID, recorded.Time)
+	case *CardRestored:
+		a.enter(event.CardID, "", event.Cell.ColumnID, recorded.Time)
+	case *CardUnarchived:
+		if flow, exists := a.flows[event.CardID]; exists && len(flow.Visits) > 0 {
+			a.enter(event.CardID, "", flow.Visits[len(flow.Visits)-1].ColumnID, recorded.Time)
+		}
+	case *CardPurged:
+		delete(a.flows, event.CardID)
+	}
+}
+
+// enter opens a visit to the column, unless the card is already there; the caller holds the lock
+func (a *AnalyticsService) enter(cardID int, title string, columnID int, at time.Time) {
+	flow, exists := a.flows[cardID]
+	if !exists {
+		column, err := a.replay.GetColumn(columnID)
+		if err != nil {
+			a.log.Error("Failed to get column for card flow", "cardID", cardID, "columnID", columnID, "error", err)
+			return
+		}
+		flow = &CardFlow{CardID: cardID, BoardID: column.Column.BoardID, Title: title}
+		a.flows[cardID] = flow
+	}
+
+	if n := len(flow.Visits); n > 0 && flow.Visits[n-1].LeftAt.IsZero() {
+		if flow.Visits[n-1].ColumnID == columnID {
+			return
+		}
+		flow.Visits[n-1].LeftAt = at
+	}
+	flow.Visits = append(flow.Visits, ColumnVisit{ColumnID: columnID, EnteredAt: at})
+}
+
+// leave closes the card's open visit; the caller holds the lock
+func (a *AnalyticsService) leave(cardID int, at time.Time) {
+	flow, exists := a.flows[cardID]
+	if !exists || len(flow.Visits) == 0 {
+		return
+	}
+	if last := &flow.Visits[len(flow.Visits)-1]; last.LeftAt.IsZero() {
+		last.LeftAt = at
+	}
+}
+
+// GetFlow returns the columns the card has been through so far
+func (a *AnalyticsService) GetFlow(cardID int) (*CardFlow, bool) {
+	a.refresh()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	flow, exists := a.flows[cardID]
+	if !exists {
+		return nil, false
+	}
+	copied := *flow
+	copied.Visits = slices.Clone(flow.Visits)
+	return &copied, true
+}
+
+// GetReport measures the board's cards against its current columns, with open visits lasting until now
+func (a *AnalyticsService) GetReport(boardID int, from, to time.Time) FlowReport {
+	report := FlowReport{
+		BoardID:         boardID,
+		From:            from,
+		To:              to,
+		AverageInColumn: make(map[int]time.Duration),
+	}
+	for _, column := range a.cardService.GetColumns(boardID) {
+		report.Columns = append(report.Columns, column.Column)
+	}
+	if len(report.Columns) == 0 {
+		return report
+	}
+	first, last := report.Columns[0].ID, report.Columns[len(report.Columns)-1].ID
+
+	a.refresh()
+	a.mu.Lock()
+	now := time.Now()
+	for _, flow := range a.flows {
+		if flow.BoardID != boardID {
+			continue
+		}
+		times := flow.times(first, last, now)
+		switch {
+		case times.IsDone() && !times.DoneAt.Before(from) && times.DoneAt.Before(to):
+			report.Done = append(report.Done, times)
+		case times.IsStarted() && !times.IsDone() && flow.isOnBoard():
+			report.InProgress = append(report.InProgress, times)
+		}
+	}
+	a.mu.Unlock()
+
+	slices.SortFunc(report.Done, func(a, b CardTimes) int {
+		return a.DoneAt.Compare(b.DoneAt)
+	})
+	slices.SortFunc(report.InProgress, func(a, b CardTimes) int {
+		return a.StartedAt.Compare(b.StartedAt)
+	})
+
+	var leadTimes, cycleTimes []time.Duration
+	totals := make(map[int]time.Duration)
+	for _, times := range report.Done {
+		leadTimes = append(leadTimes, times.LeadTime)
+		cycleTimes = append(cycleTimes, times.CycleTime)
+		for columnID, spent := range times.InColumn {
+			totals[columnID] += spent
+		}
+	}
+	report.LeadTime = percentiles(leadTimes)
+	report.CycleTime = percentiles(cycleTimes)
+	for columnID, total := range totals {
+		report.AverageInColumn[columnID] = total / time.Duration(len(report.Done))
+	}
+	return report
+}
+
+// isOnBoard says whether the card is in a column now, rather than archived or deleted
+func (f *CardFlow) isOnBoard() bool {
+	return len(f.Visits) > 0 && f.Visits[len(f.Visits)-1].LeftAt.IsZero()
+}
+
+// times works out the card's times on a board whose first and last columns are given
+func (f *CardFlow) times(first, last int, now time.Time) CardTimes {
+	times := CardTimes{
+		CardID:   f.CardID,
+		Title:    f.Title,
+		InColumn: make(map[int]time.Duration),
+	}
+	if len(f.Visits) == 0 {
+		return times
+	}
+	times.CreatedAt = f.Visits[0].EnteredAt
+
+	for _, visit := range f.Visits {
+		if times.StartedAt.IsZero() && visit.ColumnID != first {
+			times.StartedAt = visit.EnteredAt
+		}
+		left := visit.LeftAt
+		if left.IsZero() {
+			left = now
+		}
+		times.InColumn[visit.ColumnID] += left.Sub(visit.EnteredAt)
+	}
+
+	// Done cards that are archived or deleted stay done; cards moved back out of the last column aren't
+	if latest := f.Visits[len(f.Visits)-1]; latest.ColumnID == last {
+		times.DoneAt = latest.EnteredAt
+		times.LeadTime = times.DoneAt.Sub(times.CreatedAt)
+		times.CycleTime = times.DoneAt.Sub(times.StartedAt)
+	}
+	return times
+}
+
+func percentiles(durations []time.Duration) Percentiles {
+	if len(durations) == 0 {
+		return Percentiles{}
+	}
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+
+	rank := func(p float64) time.Duration {
+		return sorted[int(math.Ceil(p/100*float64(len(sorted))))-1]
+	}
+	return Percentiles{
+		Count: len(sorted),
+		P50:   rank(50),
+		P85:   rank(85),
+		P95:   rank(95),
+	}
+}
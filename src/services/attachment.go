@@ -0,0 +1,226 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrAttachmentTooLarge = errors.New("attachment is too large")
+	ErrAttachmentType     = errors.New("attachment type is not allowed")
+)
+
+type Attachment struct {
+	ID        int
+	CardID    int
+	Name      string
+	MimeType  string
+	Size      int64
+	Hash      string
+	CreatedAt time.Time
+}
+
+// IsImage reports whether the attachment can be shown as a thumbnail
+func (a *Attachment) IsImage() bool {
+	return strings.HasPrefix(a.MimeType, "image/")
+}
+
+type AttachmentService struct {
+	mu          sync.RWMutex
+	attachments map[int]*Attachment // attachmentID -> Attachment
+	cardIndex   map[int][]int       // cardID -> []attachmentID (ordered)
+
+	nextAttachmentID int
+
+	log       *slog.Logger
+	dir       string
+	maxSize   int64
+	mimeTypes []string
+}
+
+func NewAttachmentService(log *slog.Logger, eventService *EventService, config *Config) (*AttachmentService, error) {
+	if err := os.MkdirAll(config.AttachmentDir, 0o755); err != nil {
+		return nil, fmt.Errorf("could not create attachment directory: %w", err)
+	}
+
+	service := &AttachmentService{
+		attachments:      make(map[int]*Attachment),
+		cardIndex:        make(map[int][]int),
+		nextAttachmentID: 1,
+		log:              log,
+		dir:              config.AttachmentDir,
+		maxSize:          config.AttachmentMaxSize,
+		mimeTypes:        config.AttachmentMimeTypes,
+	}
+
+	// Attachments stay around while a card is in the trash, so they're only collected on purge
+	eventService.SubscribeCardPurged("attachment", func(event *CardPurgedEvent) {
+		service.DeleteAttachments(event.CardID)
+	})
+	return service, nil
+}
+
+// MaxSize returns the largest accepted attachment in bytes
+func (a *AttachmentService) MaxSize() int64 {
+	return a.maxSize
+}
+
+// AddAttachment stores the contents of reader under its content hash and attaches it to the card
+func (a *AttachmentService) AddAttachment(cardID int, name string, reader io.Reader) (*Attachment, error) {
+	temp, err := os.CreateTemp(a.dir, ".upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("could not create temporary file: %w", err)
+	}
+	defer os.Remove(temp.Name())
+	defer temp.Close()
+
+	// Read one byte past the limit so oversized uploads can be detected
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(temp, hash), io.LimitReader(reader, a.maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("could not store attachment: %w", err)
+	}
+	if size > a.maxSize {
+		return nil, ErrAttachmentTooLarge
+	}
+
+	mimeType, err := a.sniffMimeType(temp)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(a.mimeTypes, mimeType) {
+		return nil, ErrAttachmentType
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if err := temp.Close(); err != nil {
+		return nil, fmt.Errorf("could not store attachment: %w", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// Identical content is stored once and shared between attachments
+	if _, err := os.Stat(a.path(sum)); errors.Is(err, os.ErrNotExist) {
+		if err := os.Rename(temp.Name(), a.path(sum)); err != nil {
+			return nil, fmt.Errorf("could not store attachment: %w", err)
+		}
+	}
+
+	attachment := &Attachment{
+		ID:        a.nextAttachmentID,
+		CardID:    cardID,
+		Name:      filepath.Base(name),
+		MimeType:  mimeType,
+		Size:      size,
+		Hash:      sum,
+		CreatedAt: time.Now(),
+	}
+
+	a.attachments[attachment.ID] = attachment
+	a.cardIndex[cardID] = append(a.cardIndex[cardID], attachment.ID)
+	a.nextAttachmentID++
+
+	a.log.Info("Stored attachment", "attachmentID", attachment.ID, "cardID", cardID, "hash", sum, "size", size)
+	return attachment, nil
+}
+
+// sniffMimeType detects the content type from the start of the file rather than trusting the client
+func (a *AttachmentService) sniffMimeType(file *os.File) (string, error) {
+	buf := make([]byte, 512)
+	n, err := file.ReadAt(buf, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("could not read attachment: %w", err)
+	}
+
+	mimeType, _, err := mime.ParseMediaType(http.DetectContentType(buf[:n]))
+	if err != nil {
+		return "", ErrAttachmentType
+	}
+	return mimeType, nil
+}
+
+func (a *AttachmentService) GetAttachment(attachmentID int) (*Attachment, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if attachment, exists := a.attachments[attachmentID]; exists {
+		return attachment, nil
+	}
+	return nil, fmt.Errorf("attachment with ID %d not found", attachmentID)
+}
+
+func (a *AttachmentService) GetAttachments(cardID int) []*Attachment {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	attachments := make([]*Attachment, 0, len(a.cardIndex[cardID]))
+	for _, attachmentID := range a.cardIndex[cardID] {
+		attachments = append(attachments, a.attachments[attachmentID])
+	}
+	return attachments
+}
+
+// Open returns the stored file for an attachment; the caller must close it
+func (a *AttachmentService) Open(attachment *Attachment) (*os.File, error) {
+	return os.Open(a.path(attachment.Hash))
+}
+
+func (a *AttachmentService) DeleteAttachment(attachmentID int) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	attachment, exists := a.attachments[attachmentID]
+	if !exists {
+		return fmt.Errorf("attachment with ID %d not found", attachmentID)
+	}
+
+	a.cardIndex[attachment.CardID] = removeFromSlice(a.cardIndex[attachment.CardID], attachmentID)
+	delete(a.attachments, attachmentID)
+	a.collectGarbage(attachment.Hash)
+	return nil
+}
+
+// DeleteAttachments removes every attachment belonging to a card
+func (a *AttachmentService) DeleteAttachments(cardID int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, attachmentID := range a.cardIndex[cardID] {
+		attachment := a.attachments[attachmentID]
+		delete(a.attachments, attachmentID)
+		a.collectGarbage(attachment.Hash)
+	}
+	delete(a.cardIndex, cardID)
+}
+
+// collectGarbage removes the stored file once no attachment refers to its hash
+func (a *AttachmentService) collectGarbage(hash string) {
+	for _, attachment := range a.attachments {
+		if attachment.Hash == hash {
+			return
+		}
+	}
+
+	if err := os.Remove(a.path(hash)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		a.log.Error("Could not remove attachment file", "hash", hash, "error", err)
+		return
+	}
+	a.log.Info("Removed attachment file", "hash", hash)
+}
+
+func (a *AttachmentService) path(hash string) string {
+	return filepath.Join(a.dir, hash)
+}
@@ -0,0 +1,503 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	automationActor = "automation"
+
+	// automationChainLimit is how many automated actions can follow on from each other for a card before the
+	// chain is assumed to be a loop and cut off
+	automationChainLimit = 5
+
+	automationRunLimit = 200
+)
+
+type TriggerKind string
+
+const (
+	TriggerMovedTo    TriggerKind = "moved-to"
+	TriggerLabelAdded TriggerKind = "label-added"
+	TriggerDuePassed  TriggerKind = "due-passed"
+)
+
+type ActionKind string
+
+const (
+	ActionMove     ActionKind = "move"
+	ActionAssign   ActionKind = "assign"
+	ActionAddLabel ActionKind = "add-label"
+	ActionSetDue   ActionKind = "set-due"
+	ActionComment  ActionKind = "comment"
+)
+
+// Trigger is the event that sets a rule off: a card moving into ColumnID, gaining Label, or passing its due date
+type Trigger struct {
+	Kind     TriggerKind
+	ColumnID int
+	Label    string // empty matches any label
+}
+
+// Condition narrows down the cards a rule applies to; empty fields match every card
+type Condition struct {
+	Label    string
+	Assignee string
+	LaneID   int
+}
+
+func (c *Condition) Matches(card *Card) bool {
+	if c.Label != "" && !slices.ContainsFunc(card.Labels, func(label string) bool {
+		return strings.EqualFold(label, c.Label)
+	}) {
+		return false
+	}
+	if c.Assignee != "" && !strings.EqualFold(card.Assignee, c.Assignee) {
+		return false
+	}
+	return c.LaneID == 0 || card.LaneID == c.LaneID
+}
+
+// Action is what a rule does to the card; only the fields for its kind are used
+type Action struct {
+	Kind      ActionKind
+	ColumnID  int
+	Assignee  string
+	Label     string
+	DueInDays int
+	Comment   string
+}
+
+type AutomationRule struct {
+	ID        int
+	BoardID   int
+	Name      string
+	Trigger   Trigger
+	Condition Condition
+	Action    Action
+	Enabled   bool
+}
+
+// AutomationRun records a rule firing, whether or not its action succeeded
+type AutomationRun struct {
+	ID       int
+	RuleID   int
+	RuleName string
+	BoardID  int
+	CardID   int
+	Time     time.Time
+	Outcome  string
+	Failed   bool
+}
+
+// AutomationService runs each board's rules as the events that trigger them are published
+type AutomationService struct {
+	mu     sync.Mutex
+	rules  map[int]*AutomationRule // ruleID -> AutomationRule
+	runs   []AutomationRun         // oldest first, capped at automationRunLimit
+	labels map[int][]string        // cardID -> labels when last seen, for spotting added labels
+	chains map[int]int             // cardID -> automated actions in a row
+	dueRun map[int]time.Time       // cardID -> due date its due rules last fired for
+
+	nextRuleID int
+	nextRunID  int
+
+	log          *slog.Logger
+	cardService  *CardService
+	eventService *EventService
+	interval     time.Duration
+}
+
+func NewAutomationService(log *slog.Logger, cardService *CardService, eventService *EventService) *AutomationService {
+	service := &AutomationService{
+		rules:        make(map[int]*AutomationRule),
+		labels:       make(map[int][]string),
+		chains:       make(map[int]int),
+		dueRun:       make(map[int]time.Time),
+		nextRuleID:   1,
+		nextRunID:    1,
+		log:          log,
+		cardService:  cardService,
+		eventService: eventService,
+		interval:     time.Minute,
+	}
+
+	for _, card := range cardService.GetCards() {
+		service.labels[card.ID] = slices.Clone(card.Labels)
+	}
+
+	eventService.SubscribeCardMoved("automation", service.OnCardMoved)
+	eventService.SubscribeCardChanged("automation", service.OnCardChanged)
+	eventService.SubscribeCardPurged("automation", service.OnCardPurged)
+	return service
+}
+
+// Start checks for cards passing their due dates in the background
+func (a *AutomationService) Start() {
+	go func() {
+		ticker := time.NewTicker(a.interval)
+		defer ticker.Stop()
+
+		for now := range ticker.C {
+			a.CheckDueDates(now)
+		}
+	}()
+}
+
+func (a *AutomationService) AddRule(rule AutomationRule) (*AutomationRule, error) {
+	if err := a.validate(&rule); err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	rule.ID = a.nextRuleID
+	a.rules[rule.ID] = &rule
+	a.nextRuleID++
+
+	a.log.Info("Added automation rule", "ruleID", rule.ID, "boardID", rule.BoardID, "name", rule.Name)
+	return &rule, nil
+}
+
+// validate checks the columns and lanes a rule refers to belong to its board
+func (a *AutomationService) validate(rule *AutomationRule) error {
+	columnOnBoard := func(columnID int) error {
+		column, err := a.cardService.GetColumn(columnID)
+		if err != nil || column.Column.BoardID != rule.BoardID {
+			return fmt.Errorf("column with ID %d not found", columnID)
+		}
+		return nil
+	}
+
+	switch rule.Trigger.Kind {
+	case TriggerMovedTo:
+		if err := columnOnBoard(rule.Trigger.ColumnID); err != nil {
+			return err
+		}
+	case TriggerLabelAdded, TriggerDuePassed:
+	default:
+		return fmt.Errorf("unknown trigger %q", rule.Trigger.Kind)
+	}
+
+	if rule.Condition.LaneID != 0 {
+		lane, err := a.cardService.GetLane(rule.Condition.LaneID)
+		if err != nil || lane.BoardID != rule.BoardID {
+			return fmt.Errorf("lane with ID %d not found", rule.Condition.LaneID)
+		}
+	}
+
+	switch rule.Action.Kind {
+	case ActionMove:
+		return columnOnBoard(rule.Action.ColumnID)
+	case ActionAssign, ActionSetDue:
+		return nil
+	case ActionAddLabel:
+		if rule.Action.Label == "" {
+			return fmt.Errorf("label is required")
+		}
+		return nil
+	case ActionComment:
+		if rule.Action.Comment == "" {
+			return fmt.Errorf("comment is required")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown action %q", rule.Action.Kind)
+	}
+}
+
+// SetRuleEnabled switches a rule on or off without losing it
+func (a *AutomationService) SetRuleEnabled(ruleID int, enabled bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	rule, exists := a.rules[ruleID]
+	if !exists {
+		return fmt.Errorf("rule with ID %d not found", ruleID)
+	}
+	rule.Enabled = enabled
+	return nil
+}
+
+func (a *AutomationService) DeleteRule(ruleID int) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, exists := a.rules[ruleID]; !exists {
+		return fmt.Errorf("rule with ID %d not found", ruleID)
+	}
+	delete(a.rules, ruleID)
+	return nil
+}
+
+func (a *AutomationService) GetRule(ruleID int) (*AutomationRule, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	rule, exists := a.rules[ruleID]
+	if !exists {
+		return nil, fmt.Errorf("rule with ID %d not found", ruleID)
+	}
+	copied := *rule
+	return &copied, nil
+}
+
+// GetRules returns the board's rules in the order they were added, which is the order they run in
+func (a *AutomationService) GetRules(boardID int) []AutomationRule {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var rules []AutomationRule
+	for _, rule := range a.rules {
+		if rule.BoardID == boardID {
+			rules = append(rules, *rule)
+		}
+	}
+	slices.SortFunc(rules, func(x, y AutomationRule) int {
+		return x.ID - y.ID
+	})
+	return rules
+}
+
+// GetRuns returns the board's most recent rule executions, newest first
+func (a *AutomationService) GetRuns(boardID, limit int) []AutomationRun {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var runs []AutomationRun
+	for i := len(a.runs) - 1; i >= 0 && len(runs) < limit; i-- {
+		if a.runs[i].BoardID == boardID {
+			runs = append(runs, a.runs[i])
+		}
+	}
+	return runs
+}
+
+func (a *AutomationService) OnCardMoved(event *CardMovedEvent) {
+	if event.FromColumnID == event.ToColumnID {
+		return
+	}
+
+	a.fire(event.Actor, event.CardID, func(rule *AutomationRule, card *Card) bool {
+		return rule.Trigger.Kind == TriggerMovedTo && rule.Trigger.ColumnID == event.ToColumnID
+	})
+}
+
+func (a *AutomationService) OnCardChanged(event *CardChangedEvent) {
+	card := &event.Card
+
+	a.mu.Lock()
+	previous := a.labels[card.ID]
+	a.labels[card.ID] = slices.Clone(card.Labels)
+	a.mu.Unlock()
+
+	var added []string
+	for _, label := range card.Labels {
+		if !slices.ContainsFunc(previous, func(before string) bool { return strings.EqualFold(before, label) }) {
+			added = append(added, label)
+		}
+	}
+	if len(added) == 0 {
+		a.endChain(event.Actor, card.ID)
+		return
+	}
+
+	a.fire(event.Actor, card.ID, func(rule *AutomationRule, card *Card) bool {
+		if rule.Trigger.Kind != TriggerLabelAdded {
+			return false
+		}
+		return rule.Trigger.Label == "" || slices.ContainsFunc(added, func(label string) bool {
+			return strings.EqualFold(label, rule.Trigger.Label)
+		})
+	})
+}
+
+func (a *AutomationService) OnCardPurged(event *CardPurgedEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.labels, event.CardID)
+	delete(a.chains, event.CardID)
+	delete(a.dueRun, event.CardID)
+}
+
+// CheckDueDates fires due date rules for cards that have become overdue, once per due date
+func (a *AutomationService) CheckDueDates(now time.Time) {
+	for _, card := range a.cardService.GetCards() {
+		if card.IsArchived() || !card.IsOverdue(now) {
+			continue
+		}
+
+		a.mu.Lock()
+		alreadyRun := a.dueRun[card.ID].Equal(card.DueAt)
+		a.dueRun[card.ID] = card.DueAt
+		a.mu.Unlock()
+		if alreadyRun {
+			continue
+		}
+
+		a.fire(automationActor+":due", card.ID, func(rule *AutomationRule, card *Card) bool {
+			return rule.Trigger.Kind == TriggerDuePassed
+		})
+	}
+}
+
+// endChain resets the loop guard once someone other than an automation touches the card
+func (a *AutomationService) endChain(actor string, cardID int) {
+	if actor == automationActor {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.chains, cardID)
+}
+
+// fire runs the enabled rules on the card's board whose trigger matches and whose condition holds
+func (a *AutomationService) fire(actor string, cardID int, triggered func(rule *AutomationRule, card *Card) bool) {
+	a.endChain(actor, cardID)
+
+	card, err := a.cardService.GetCard(cardID)
+	if err != nil || card.IsArchived() {
+		return
+	}
+	column, err := a.cardService.GetColumn(card.ColumnID)
+	if err != nil {
+		return
+	}
+
+	for _, rule := range a.GetRules(column.Column.BoardID) {
+		if !rule.Enabled || !triggered(&rule, card) || !rule.Condition.Matches(card) {
+			continue
+		}
+
+		a.mu.Lock()
+		chain := a.chains[card.ID]
+		if chain >= automationChainLimit {
+			a.mu.Unlock()
+			a.record(rule, card.ID, "Stopped: rules kept triggering each other", true)
+			continue
+		}
+		a.chains[card.ID] = chain + 1
+		a.mu.Unlock()
+
+		outcome, err := a.run(rule, card)
+		if err != nil {
+			a.record(rule, card.ID, err.Error(), true)
+			continue
+		}
+		a.record(rule, card.ID, outcome, false)
+
+		// Later rules see the card as this one left it
+		if card, err = a.cardService.GetCard(cardID); err != nil {
+			return
+		}
+	}
+}
+
+// publishChanged announces an action's change to the card, as the card is straight after it
+func (a *AutomationService) publishChanged(cardID int) {
+	if card, err := a.cardService.GetCard(cardID); err == nil {
+		a.eventService.PublishCardChanged(automationActor, *card)
+	}
+}
+
+func (a *AutomationService) run(rule AutomationRule, card *Card) (string, error) {
+	action := rule.Action
+	details := card.Details()
+
+	switch action.Kind {
+	case ActionMove:
+		from, to, err := a.cardService.MoveCard(card.ID, action.ColumnID, card.LaneID, -1)
+		if err != nil {
+			return "", err
+		}
+		if from == to {
+			return "Already there", nil
+		}
+		a.eventService.PublishCardMoved(automationActor, card.ID, from, to)
+		return "Moved to " + a.columnTitle(action.ColumnID), nil
+
+	case ActionAssign:
+		details.Assignee = action.Assignee
+		if err := a.cardService.UpdateCard(card.ID, details); err != nil {
+			return "", err
+		}
+		a.publishChanged(card.ID)
+		if action.Assignee == "" {
+			return "Unassigned", nil
+		}
+		return "Assigned to " + action.Assignee, nil
+
+	case ActionAddLabel:
+		if slices.ContainsFunc(details.Labels, func(label string) bool { return strings.EqualFold(label, action.Label) }) {
+			return "Already labelled " + action.Label, nil
+		}
+		details.Labels = append(slices.Clone(details.Labels), action.Label)
+		if err := a.cardService.UpdateCard(card.ID, details); err != nil {
+			return "", err
+		}
+		a.publishChanged(card.ID)
+		return "Labelled " + action.Label, nil
+
+	case ActionSetDue:
+		now := time.Now()
+		details.DueAt = time.Date(now.Year(), now.Month(), now.Day()+action.DueInDays, 0, 0, 0, 0, now.Location())
+		if err := a.cardService.UpdateCard(card.ID, details); err != nil {
+			return "", err
+		}
+		a.publishChanged(card.ID)
+		return "Due " + details.DueAt.Format("2 Jan"), nil
+
+	case ActionComment:
+		comment, err := a.cardService.AddComment(card.ID, automationActor, action.Comment)
+		if err != nil {
+			return "", err
+		}
+		a.eventService.PublishCardCommented(automationActor, card.ID, comment.ID)
+		return "Commented", nil
+
+	default:
+		return "", fmt.Errorf("unknown action %q", action.Kind)
+	}
+}
+
+func (a *AutomationService) record(rule AutomationRule, cardID int, outcome string, failed bool) {
+	if failed {
+		a.log.Warn("Automation rule failed", "ruleID", rule.ID, "cardID", cardID, "outcome", outcome)
+	} else {
+		a.log.Info("Automation rule ran", "ruleID", rule.ID, "cardID", cardID, "outcome", outcome)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.runs = append(a.runs, AutomationRun{
+		ID:       a.nextRunID,
+		RuleID:   rule.ID,
+		RuleName: rule.Name,
+		BoardID:  rule.BoardID,
+		CardID:   cardID,
+		Time:     time.Now(),
+		Outcome:  outcome,
+		Failed:   failed,
+	})
+	a.nextRunID++
+	if len(a.runs) > automationRunLimit {
+		a.runs = a.runs[len(a.runs)-automationRunLimit:]
+	}
+}
+
+func (a *AutomationService) columnTitle(columnID int) string {
+	column, err := a.cardService.GetColumn(columnID)
+	if err != nil {
+		return ""
+	}
+	return column.Column.Title
+}
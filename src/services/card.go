@@ -3,68 +3,202 @@ package services
 import (
 	"fmt"
 	"log/slog"
+	"os"
+	"slices"
 	"sort"
+	"strings"
 	"sync"
+	"time"
 )
 
+const DefaultBoardID = 1
+
 type Card struct {
-	ID       int
+	ID         int
+	Title      string
+	Content    string
+	ColumnID   int
+	LaneID     int
+	Labels     []string
+	Assignee   string
+	DueAt      time.Time
+	ArchivedAt time.Time
+}
+
+// DueDateLayout is how due dates are written; they're days rather than moments
+const DueDateLayout = "2006-01-02"
+
+// CardDetails are the fields of a card that can be edited
+type CardDetails struct {
 	Title    string
 	Content  string
-	ColumnID int
+	Labels   []string
+	Assignee string
+	DueAt    time.Time
+}
+
+type Comment struct {
+	ID        int
+	CardID    int
+	Author    string
+	Body      string
+	CreatedAt time.Time
+}
+
+func (c *Card) Cell() Cell {
+	return Cell{ColumnID: c.ColumnID, LaneID: c.LaneID}
+}
+
+func (c *Card) Details() CardDetails {
+	return CardDetails{
+		Title:    c.Title,
+		Content:  c.Content,
+		Labels:   c.Labels,
+		Assignee: c.Assignee,
+		DueAt:    c.DueAt,
+	}
+}
+
+func (c *Card) HasDueDate() bool {
+	return !c.DueAt.IsZero()
+}
+
+// IsOverdue reports whether the whole of the card's due date has passed
+func (c *Card) IsOverdue(now time.Time) bool {
+	return c.HasDueDate() && c.DueAt.AddDate(0, 0, 1).Before(now)
 }
 
+// IsArchived reports whether the card has been archived, which hides it from its column
+func (c *Card) IsArchived() bool {
+	return !c.ArchivedAt.IsZero()
+}
+
+// WIPMode decides what happens when a column reaches its work-in-progress limit
+type WIPMode string
+
+const (
+	WIPModeSoft WIPMode = "soft" // cards can still be added, but the column is flagged
+	WIPModeHard WIPMode = "hard" // cards are refused
+)
+
 type Column struct {
+	ID       int
+	BoardID  int
+	Title    string
+	Order    int
+	WIPLimit int // zero means no limit
+	WIPMode  WIPMode
+}
+
+// WIPLimitError is returned when a card would take a column past a hard WIP limit
+type WIPLimitError struct {
+	ColumnID    int
+	ColumnTitle string
+	Limit       int
+}
+
+func (e *WIPLimitError) Error() string {
+	return fmt.Sprintf("%s has reached its WIP limit of %d", e.ColumnTitle, e.Limit)
+}
+
+// Lane is a horizontal swimlane, so each card sits in the cell where its column and lane cross
+type Lane struct {
+	ID      int
+	BoardID int
+	Title   string
+	Order   int
+}
+
+type Board struct {
 	ID    int
 	Title string
-	Order int
 }
 
+// Cell identifies where a column and a lane cross
+type Cell struct {
+	ColumnID int
+	LaneID   int
+}
+
+// TrashedCard is a deleted card, kept so that it can be restored until it is purged
+type TrashedCard struct {
+	Card      Card
+	Position  int
+	DeletedAt time.Time
+}
+
+// CardService owns boards and everything on them; its state is a projection of the domain events in its history
 type CardService struct {
-	mu          sync.RWMutex
-	cards       map[int]*Card   // cardID -> Card
-	columns     map[int]*Column // columnID -> Column
-	columnCards map[int][]int   // columnID -> []cardID (ordered)
+	mu        sync.RWMutex
+	boards    map[int]*Board       // boardID -> Board
+	cards     map[int]*Card        // cardID -> Card
+	columns   map[int]*Column      // columnID -> Column
+	lanes     map[int]*Lane        // laneID -> Lane
+	cellCards map[Cell][]int       // Cell -> []cardID (ordered)
+	trash     map[int]*TrashedCard // cardID -> TrashedCard
+	comments  map[int][]Comment    // cardID -> []Comment (oldest first)
+	index     *SearchIndex         // kept up to date by every mutation below
+	history   []RecordedEvent      // every change above, as the domain events that made it
+	replays   replayCache          // the boards as they were, for looking back at them
 
-	nextCardID   int
-	nextColumnID int
+	nextBoardID   int
+	nextCardID    int
+	nextColumnID  int
+	nextLaneID    int
+	nextCommentID int
 
-	log          *slog.Logger
-	eventService *EventService
-	wordService  *WordService
+	log               *slog.Logger
+	eventService      *EventService
+	wordService       *WordService
+	moderationService *ModerationService // applies each board's policy to cards and comments, when set
 }
 
-func NewCardService(log *slog.Logger, eventService *EventService, wordService *WordService) *CardService {
-	service := &CardService{
-		mu:           sync.RWMutex{},
-		cards:        make(map[int]*Card),
-		columns:      make(map[int]*Column),
-		columnCards:  make(map[int][]int),
-		log:          log,
-		eventService: eventService,
-		wordService:  wordService,
+// NewCardService creates a card service with the board in the seed document at config.SeedPath
+func NewCardService(
+	log *slog.Logger,
+	eventService *EventService,
+	wordService *WordService,
+	moderationService *ModerationService,
+	config *Config,
+) (*CardService, error) {
+	service := newCardService(log, eventService, wordService)
+	service.moderationService = moderationService
+
+	data, err := os.ReadFile(config.SeedPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read seed file: %w", err)
+	}
+	document, err := ParseBoardDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid seed file %s: %w", config.SeedPath, err)
 	}
-	service.seedData()
-	return service
+	if _, err := service.ImportBoard(document); err != nil {
+		return nil, fmt.Errorf("invalid seed file %s: %w", config.SeedPath, err)
+	}
+	return service, nil
 }
 
-func (c *CardService) seedData() {
-	// Create columns
-	c.columns[1] = &Column{ID: 1, Title: "To Do", Order: 0}
-	c.columns[2] = &Column{ID: 2, Title: "In Progress", Order: 1}
-	c.columns[3] = &Column{ID: 3, Title: "Done", Order: 2}
-	c.nextColumnID = 4
-
-	// Create cards
-	c.cards[1] = &Card{ID: 1, Title: "Blog post", Content: "Once the app is working and looking good, write it up", ColumnID: 1}
-	c.cards[2] = &Card{ID: 2, Title: "Post to HN", Content: "", ColumnID: 1}
-	c.cards[3] = &Card{ID: 3, Title: "Build app", Content: "Implement minimal Kanban Board with columns and draggable/editable cards", ColumnID: 2}
-	c.nextCardID = 4
-
-	// Set up column ordering
-	c.columnCards[1] = []int{1, 2}
-	c.columnCards[2] = []int{3}
-	c.columnCards[3] = []int{}
+// newCardService creates a card service without any boards, ready for events to be applied to it
+func newCardService(log *slog.Logger, eventService *EventService, wordService *WordService) *CardService {
+	return &CardService{
+		mu:            sync.RWMutex{},
+		boards:        make(map[int]*Board),
+		cards:         make(map[int]*Card),
+		columns:       make(map[int]*Column),
+		lanes:         make(map[int]*Lane),
+		cellCards:     make(map[Cell][]int),
+		trash:         make(map[int]*TrashedCard),
+		comments:      make(map[int][]Comment),
+		index:         NewSearchIndex(),
+		nextBoardID:   1,
+		nextCardID:    1,
+		nextColumnID:  1,
+		nextLaneID:    1,
+		nextCommentID: 1,
+		log:           log,
+		eventService:  eventService,
+		wordService:   wordService,
+	}
 }
 
 func removeFromSlice(slice []int, element int) []int {
@@ -76,19 +210,34 @@ func removeFromSlice(slice []int, element int) []int {
 	return slice
 }
 
-func (c *CardService) getColumnByOrder(order int) *Column {
+func (c *CardService) getColumnByOrder(boardID, order int) *Column {
 	for _, column := range c.columns {
-		if column.Order == order {
+		if column.BoardID == boardID && column.Order == order {
 			return column
 		}
 	}
 	return nil
 }
 
-func (c *CardService) getSortedColumns() []*Column {
+func (c *CardService) getSortedLanes(boardID int) []*Lane {
+	lanes := make([]*Lane, 0, len(c.lanes))
+	for _, lane := range c.lanes {
+		if lane.BoardID == boardID {
+			lanes = append(lanes, lane)
+		}
+	}
+	sort.Slice(lanes, func(i, j int) bool {
+		return lanes[i].Order < lanes[j].Order
+	})
+	return lanes
+}
+
+func (c *CardService) getSortedColumns(boardID int) []*Column {
 	columns := make([]*Column, 0, len(c.columns))
 	for _, column := range c.columns {
-		columns = append(columns, column)
+		if column.BoardID == boardID {
+			columns = append(columns, column)
+		}
 	}
 	sort.Slice(columns, func(i, j int) bool {
 		return columns[i].Order < columns[j].Order
@@ -110,7 +259,7 @@ func (c *CardService) CanPromote(cardID int) bool {
 		return false
 	}
 
-	nextColumn := c.getColumnByOrder(currentColumn.Order + 1)
+	nextColumn := c.getColumnByOrder(currentColumn.BoardID, currentColumn.Order+1)
 	return nextColumn != nil
 }
 
@@ -131,42 +280,40 @@ func (c *CardService) CanDemote(cardID int) bool {
 	return currentColumn.Order > 0
 }
 
-func (c *CardService) Promote(cardID int) (*Column, *Column, error) {
-	card, exists := c.cards[cardID]
-	if !exists {
-		return nil, nil, fmt.Errorf("card with ID %d not found", cardID)
-	}
-
-	currentColumn := c.columns[card.ColumnID]
-	if currentColumn == nil {
-		return nil, nil, fmt.Errorf("current column not found for card %d", cardID)
-	}
-
-	targetColumn := c.getColumnByOrder(currentColumn.Order + 1)
-	if targetColumn == nil {
-		return nil, nil, fmt.Errorf("card %d cannot be promoted further", cardID)
-	}
+// Promote moves the card to the next column, staying in the same lane
+func (c *CardService) Promote(cardID int) (Cell, Cell, error) {
+	return c.moveByColumns(cardID, 1, "promoted")
+}
 
-	return c.MoveCard(cardID, targetColumn.ID, -1)
+// Demote moves the card to the previous column, staying in the same lane
+func (c *CardService) Demote(cardID int) (Cell, Cell, error) {
+	return c.moveByColumns(cardID, -1, "demoted")
 }
 
-func (c *CardService) Demote(cardID int) (*Column, *Column, error) {
+// moveByColumns moves the card the given number of columns along, staying in the same lane. The target is
+// looked up under the read lock, which is released before MoveCard takes the write lock.
+func (c *CardService) moveByColumns(cardID, offset int, verb string) (Cell, Cell, error) {
+	c.mu.RLock()
 	card, exists := c.cards[cardID]
 	if !exists {
-		return nil, nil, fmt.Errorf("card with ID %d not found", cardID)
+		c.mu.RUnlock()
+		return Cell{}, Cell{}, fmt.Errorf("card with ID %d not found", cardID)
 	}
 
 	currentColumn := c.columns[card.ColumnID]
 	if currentColumn == nil {
-		return nil, nil, fmt.Errorf("current column not found for card %d", cardID)
+		c.mu.RUnlock()
+		return Cell{}, Cell{}, fmt.Errorf("current column not found for card %d", cardID)
 	}
 
-	targetColumn := c.getColumnByOrder(currentColumn.Order - 1)
+	targetColumn := c.getColumnByOrder(currentColumn.BoardID, currentColumn.Order+offset)
+	laneID := card.LaneID
+	c.mu.RUnlock()
+
 	if targetColumn == nil {
-		return nil, nil, fmt.Errorf("card %d cannot be demoted further", cardID)
+		return Cell{}, Cell{}, fmt.Errorf("card %d cannot be %s further", cardID, verb)
 	}
-
-	return c.MoveCard(cardID, targetColumn.ID, -1)
+	return c.MoveCard(cardID, targetColumn.ID, laneID, -1)
 }
 
 func (c *CardService) GetColumn(id int) (*ColumnWithCards, error) {
@@ -185,36 +332,206 @@ func (c *CardService) GetColumn(id int) (*ColumnWithCards, error) {
 	}, nil
 }
 
-func (c *CardService) GetColumns() []ColumnWithCards {
+// GetCell returns the cards where a column and a lane cross, along with the column they count towards
+func (c *CardService) GetCell(columnID, laneID int) (*CellWithCards, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	var result []ColumnWithCards
+	column, exists := c.columns[columnID]
+	if !exists {
+		return nil, fmt.Errorf("column with id %d not found", columnID)
+	}
+
+	lane, exists := c.lanes[laneID]
+	if !exists {
+		return nil, fmt.Errorf("lane with id %d not found", laneID)
+	}
+
+	return &CellWithCards{
+		Column: ColumnWithCards{
+			Column: *column,
+			Cards:  c.getCardsForColumn(columnID),
+		},
+		Lane:  *lane,
+		Cards: c.getCardsForCell(Cell{ColumnID: columnID, LaneID: laneID}),
+	}, nil
+}
+
+func (c *CardService) GetLane(laneID int) (*Lane, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if lane, exists := c.lanes[laneID]; exists {
+		return lane, nil
+	}
+	return nil, fmt.Errorf("lane with ID %d not found", laneID)
+}
+
+// GetLanes returns the board's lanes from top to bottom
+func (c *CardService) GetLanes(boardID int) []Lane {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var result []Lane
+	for _, lane := range c.getSortedLanes(boardID) {
+		result = append(result, *lane)
+	}
+	return result
+}
+
+// AddLane appends a lane to the bottom of the board
+func (c *CardService) AddLane(boardID int, title string) (*Lane, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	// Sort columns by order
-	for i := 0; i < len(c.columns); i++ {
-		for _, column := range c.columns {
-			if column.Order == i {
-				cards := c.getCardsForColumn(column.ID)
-				result = append(result, ColumnWithCards{
-					Column: *column,
-					Cards:  cards,
-				})
-				break
-			}
+	if _, exists := c.boards[boardID]; !exists {
+		return nil, fmt.Errorf("board with ID %d not found", boardID)
+	}
+
+	if blacklistedWord := c.wordService.Filter(title); blacklistedWord != "" {
+		return nil, fmt.Errorf("title contains prohibited word: %s", blacklistedWord)
+	}
+
+	lane := Lane{
+		ID:      c.nextLaneID,
+		BoardID: boardID,
+		Title:   title,
+		Order:   len(c.getSortedLanes(boardID)),
+	}
+	c.record(&LaneCreated{Lane: lane}, time.Now())
+	return &lane, nil
+}
+
+// DeleteLane removes a lane that no longer holds any cards, archived ones included
+func (c *CardService) DeleteLane(laneID int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	lane, exists := c.lanes[laneID]
+	if !exists {
+		return fmt.Errorf("lane with ID %d not found", laneID)
+	}
+
+	lanes := c.getSortedLanes(lane.BoardID)
+	if len(lanes) == 1 {
+		return fmt.Errorf("a board needs at least one lane")
+	}
+
+	for _, card := range c.cards {
+		if card.LaneID == laneID {
+			return fmt.Errorf("%q still has cards", lane.Title)
 		}
 	}
 
+	c.record(&LaneDeleted{Lane: *lane}, time.Now())
+	return nil
+}
+
+func (c *CardService) GetBoard(boardID int) (*Board, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if board, exists := c.boards[boardID]; exists {
+		return board, nil
+	}
+	return nil, fmt.Errorf("board with ID %d not found", boardID)
+}
+
+func (c *CardService) GetColumns(boardID int) []ColumnWithCards {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var result []ColumnWithCards
+	for _, column := range c.getSortedColumns(boardID) {
+		result = append(result, ColumnWithCards{
+			Column: *column,
+			Cards:  c.getCardsForColumn(column.ID),
+		})
+	}
+
 	return result
 }
 
+// GetCards returns every card that hasn't been deleted, including archived cards, ordered by ID
+func (c *CardService) GetCards() []Card {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	cards := make([]Card, 0, len(c.cards))
+	for _, card := range c.cards {
+		cards = append(cards, *card)
+	}
+	sort.Slice(cards, func(i, j int) bool {
+		return cards[i].ID < cards[j].ID
+	})
+	return cards
+}
+
+// GetAssignees returns everyone assigned to a card on the board, sorted by name
+func (c *CardService) GetAssignees(boardID int) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var assignees []string
+	for _, card := range c.cards {
+		column, exists := c.columns[card.ColumnID]
+		if !exists || card.Assignee == "" || column.BoardID != boardID {
+			continue
+		}
+		if !slices.Contains(assignees, card.Assignee) {
+			assignees = append(assignees, card.Assignee)
+		}
+	}
+	slices.Sort(assignees)
+	return assignees
+}
+
 type ColumnWithCards struct {
 	Column Column
+	Cards  []Card // across every lane
+}
+
+func (c *ColumnWithCards) HasWIPLimit() bool {
+	return c.Column.WIPLimit > 0
+}
+
+// IsFull reports whether the column has reached its WIP limit
+func (c *ColumnWithCards) IsFull() bool {
+	return c.HasWIPLimit() && len(c.Cards) >= c.Column.WIPLimit
+}
+
+// IsOverLimit reports whether the column holds more cards than its WIP limit, which soft limits allow
+func (c *ColumnWithCards) IsOverLimit() bool {
+	return c.HasWIPLimit() && len(c.Cards) > c.Column.WIPLimit
+}
+
+// RefusesCards reports whether a hard WIP limit stops any more cards being added
+func (c *ColumnWithCards) RefusesCards() bool {
+	return c.IsFull() && c.Column.WIPMode == WIPModeHard
+}
+
+type CellWithCards struct {
+	Column ColumnWithCards // WIP limits apply to the whole column
+	Lane   Lane
 	Cards  []Card
 }
 
+// getCardsForColumn returns the column's cards lane by lane
 func (c *CardService) getCardsForColumn(columnID int) []Card {
-	cardIDs := c.columnCards[columnID]
+	column, exists := c.columns[columnID]
+	if !exists {
+		return nil
+	}
+
+	var cards []Card
+	for _, lane := range c.getSortedLanes(column.BoardID) {
+		cards = append(cards, c.getCardsForCell(Cell{ColumnID: columnID, LaneID: lane.ID})...)
+	}
+	return cards
+}
+
+func (c *CardService) getCardsForCell(cell Cell) []Card {
+	cardIDs := c.cellCards[cell]
 	cards := make([]Card, 0, len(cardIDs))
 
 	for _, cardID := range cardIDs {
@@ -226,46 +543,75 @@ func (c *CardService) getCardsForColumn(columnID int) []Card {
 	return cards
 }
 
+// GetCard returns a copy of the card, which event subscribers can read while the card carries on changing
 func (c *CardService) GetCard(cardID int) (*Card, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
 	if card, exists := c.cards[cardID]; exists {
-		return card, nil
+		copied := *card
+		copied.Labels = slices.Clone(card.Labels)
+		return &copied, nil
 	}
 	return nil, fmt.Errorf("card with ID %d not found", cardID)
 }
 
-func (c *CardService) AddCard(title, content string, columnID int) (*Card, error) {
+func (c *CardService) AddCard(details CardDetails, columnID, laneID int) (*Card, error) {
+	return c.addCard(details, columnID, laneID, true)
+}
+
+// AddApprovedCard adds a card an admin has approved from the moderation queue, which the board's policy has
+// already had its say on
+func (c *CardService) AddApprovedCard(details CardDetails, columnID, laneID int) (*Card, error) {
+	return c.addCard(details, columnID, laneID, false)
+}
+
+func (c *CardService) addCard(details CardDetails, columnID, laneID int, moderate bool) (*Card, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if _, exists := c.columns[columnID]; !exists {
+	column, exists := c.columns[columnID]
+	if !exists {
 		return nil, fmt.Errorf("column with ID %d not found", columnID)
 	}
 
-	if blacklistedWord := c.wordService.Filter(title); blacklistedWord != "" {
-		return nil, fmt.Errorf("title contains prohibited word: %s", blacklistedWord)
+	lane, exists := c.lanes[laneID]
+	if !exists || lane.BoardID != column.BoardID {
+		return nil, fmt.Errorf("lane with ID %d not found", laneID)
 	}
-	if blacklistedWord := c.wordService.Filter(content); blacklistedWord != "" {
-		return nil, fmt.Errorf("content contains prohibited word: %s", blacklistedWord)
+
+	if err := c.checkWIPLimit(column); err != nil {
+		return nil, err
 	}
 
-	card := &Card{
+	if moderate {
+		var err error
+		if details, err = c.checkDetails(column.BoardID, details); err != nil {
+			return nil, err
+		}
+	}
+
+	card := Card{
 		ID:       c.nextCardID,
-		Title:    title,
-		Content:  content,
 		ColumnID: columnID,
+		LaneID:   laneID,
 	}
+	card.setDetails(details)
 
-	c.cards[c.nextCardID] = card
-	c.columnCards[columnID] = append(c.columnCards[columnID], c.nextCardID)
-	c.nextCardID++
+	c.record(&CardCreated{Card: card, Position: len(c.cellCards[card.Cell()])}, time.Now())
+	return &card, nil
+}
 
-	return card, nil
+func (c *CardService) UpdateCard(cardID int, details CardDetails) error {
+	return c.updateCard(cardID, details, true)
 }
 
-func (c *CardService) UpdateCard(cardID int, title, content string) error {
+// UpdateApprovedCard makes changes to a card that an admin has approved from the moderation queue
+func (c *CardService) UpdateApprovedCard(cardID int, details CardDetails) error {
+	return c.updateCard(cardID, details, false)
+}
+
+func (c *CardService) updateCard(cardID int, details CardDetails, moderate bool) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -274,42 +620,149 @@ func (c *CardService) UpdateCard(cardID int, title, content string) error {
 		return fmt.Errorf("card with ID %d not found", cardID)
 	}
 
-	// Check for blacklisted words if WordService is available
-	if c.wordService != nil {
-		if blacklistedWord := c.wordService.Filter(title); blacklistedWord != "" {
-			return fmt.Errorf("title contains prohibited word: %s", blacklistedWord)
-		}
-		if blacklistedWord := c.wordService.Filter(content); blacklistedWord != "" {
-			return fmt.Errorf("content contains prohibited word: %s", blacklistedWord)
+	if moderate {
+		var err error
+		if details, err = c.checkDetails(c.columns[card.ColumnID].BoardID, details); err != nil {
+			return err
 		}
 	}
 
-	card.Title = title
-	card.Content = content
+	c.record(&CardUpdated{CardID: cardID, Before: card.Details(), After: details}, time.Now())
 	return nil
 }
 
-func (c *CardService) MoveCard(cardID, newColumnID, newPosition int) (*Column, *Column, error) {
+func (c *Card) setDetails(details CardDetails) {
+	c.Title = details.Title
+	c.Content = details.Content
+	c.Labels = details.Labels
+	c.Assignee = details.Assignee
+	c.DueAt = details.DueAt
+}
+
+// checkDetails applies the board's moderation policy to card details, returning them as they should be saved.
+// Without a moderation service, details containing blacklisted words are rejected.
+func (c *CardService) checkDetails(boardID int, details CardDetails) (CardDetails, error) {
+	if c.moderationService != nil {
+		return c.moderationService.Check(boardID, details)
+	}
+	// Check for blacklisted words if WordService is available
+	if c.wordService == nil {
+		return details, nil
+	}
+	if blacklistedWord := c.wordService.Filter(details.Title); blacklistedWord != "" {
+		return details, fmt.Errorf("title contains prohibited word: %s", blacklistedWord)
+	}
+	if blacklistedWord := c.wordService.Filter(details.Content); blacklistedWord != "" {
+		return details, fmt.Errorf("content contains prohibited word: %s", blacklistedWord)
+	}
+	if blacklistedWord := c.wordService.Filter(strings.Join(details.Labels, " ")); blacklistedWord != "" {
+		return details, fmt.Errorf("labels contain prohibited word: %s", blacklistedWord)
+	}
+	if blacklistedWord := c.wordService.Filter(details.Assignee); blacklistedWord != "" {
+		return details, fmt.Errorf("assignee contains prohibited word: %s", blacklistedWord)
+	}
+	return details, nil
+}
+
+// MoveCard moves a card to a position within the cell where the column and lane cross
+func (c *CardService) MoveCard(cardID, newColumnID, newLaneID, newPosition int) (Cell, Cell, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	card, exists := c.cards[cardID]
 	if !exists {
-		return nil, nil, fmt.Errorf("card with ID %d not found", cardID)
+		return Cell{}, Cell{}, fmt.Errorf("card with ID %d not found", cardID)
+	}
+
+	if card.IsArchived() {
+		return Cell{}, Cell{}, fmt.Errorf("card %d is archived", cardID)
+	}
+
+	newColumn, exists := c.columns[newColumnID]
+	if !exists {
+		return Cell{}, Cell{}, fmt.Errorf("column with ID %d not found", newColumnID)
+	}
+
+	newLane, exists := c.lanes[newLaneID]
+	if !exists {
+		return Cell{}, Cell{}, fmt.Errorf("lane with ID %d not found", newLaneID)
+	}
+
+	boardID := c.columns[card.ColumnID].BoardID
+	if newColumn.BoardID != boardID || newLane.BoardID != boardID {
+		return Cell{}, Cell{}, fmt.Errorf("card %d cannot be moved to another board", cardID)
+	}
+
+	// Moving between lanes or within a column never changes how many cards it holds
+	if newColumnID != card.ColumnID {
+		if err := c.checkWIPLimit(newColumn); err != nil {
+			return Cell{}, Cell{}, err
+		}
+	}
+
+	oldCell := card.Cell()
+	newCell := Cell{ColumnID: newColumnID, LaneID: newLaneID}
+
+	// Positions are recorded as they end up, rather than as asked for
+	fromPosition := slices.Index(c.cellCards[oldCell], cardID)
+	available := len(c.cellCards[newCell])
+	if newCell == oldCell {
+		available--
+	}
+	toPosition := newPosition
+	if toPosition < 0 || toPosition > available {
+		toPosition = available
+	}
+
+	c.record(&CardMoved{
+		CardID:       cardID,
+		From:         oldCell,
+		FromPosition: fromPosition,
+		To:           newCell,
+		ToPosition:   toPosition,
+	}, time.Now())
+	return oldCell, newCell, nil
+}
+
+// SetWIPLimit changes a column's WIP limit; a limit of zero removes it
+func (c *CardService) SetWIPLimit(columnID, limit int, mode WIPMode) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	column, exists := c.columns[columnID]
+	if !exists {
+		return fmt.Errorf("column with ID %d not found", columnID)
 	}
 
-	if _, exists := c.columns[newColumnID]; !exists {
-		return nil, nil, fmt.Errorf("column with ID %d not found", newColumnID)
+	if limit < 0 {
+		return fmt.Errorf("WIP limit cannot be negative")
 	}
 
-	oldColumnID := card.ColumnID
-	c.removeCardFromColumn(cardID, oldColumnID)
-	c.insertCardInColumn(cardID, newColumnID, newPosition)
-	card.ColumnID = newColumnID
+	if mode != WIPModeSoft && mode != WIPModeHard {
+		return fmt.Errorf("unknown WIP mode %q", mode)
+	}
+
+	c.record(&WIPLimitSet{ColumnID: column.ID, Limit: limit, Mode: mode}, time.Now())
+	return nil
+}
+
+// checkWIPLimit refuses another card for a column that is full under a hard limit
+func (c *CardService) checkWIPLimit(column *Column) error {
+	if column.WIPLimit <= 0 || column.WIPMode != WIPModeHard {
+		return nil
+	}
 
-	return c.columns[oldColumnID], c.columns[newColumnID], nil
+	if len(c.getCardsForColumn(column.ID)) >= column.WIPLimit {
+		return &WIPLimitError{
+			ColumnID:    column.ID,
+			ColumnTitle: column.Title,
+			Limit:       column.WIPLimit,
+		}
+	}
+	return nil
 }
 
+// DeleteCard moves the card to its board's trash, from where it can be restored until purged
 func (c *CardService) DeleteCard(cardID int) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -319,23 +772,311 @@ func (c *CardService) DeleteCard(cardID int) error {
 		return fmt.Errorf("card with ID %d not found", cardID)
 	}
 
-	c.removeCardFromColumn(cardID, card.ColumnID)
-	delete(c.cards, cardID)
+	now := time.Now()
+	c.record(&CardDeleted{
+		Card:      *card,
+		Position:  slices.Index(c.cellCards[card.Cell()], cardID),
+		DeletedAt: now,
+	}, now)
+	return nil
+}
+
+// GetTrash returns the board's deleted cards, most recently deleted first
+func (c *CardService) GetTrash(boardID int) []TrashedCard {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var trashed []TrashedCard
+	for _, trashedCard := range c.trash {
+		if column, exists := c.columns[trashedCard.Card.ColumnID]; exists && column.BoardID == boardID {
+			trashed = append(trashed, *trashedCard)
+		}
+	}
+	sort.Slice(trashed, func(i, j int) bool {
+		return trashed[i].DeletedAt.After(trashed[j].DeletedAt)
+	})
+	return trashed
+}
+
+func (c *CardService) GetTrashedCard(cardID int) (*TrashedCard, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if trashedCard, exists := c.trash[cardID]; exists {
+		return trashedCard, nil
+	}
+	return nil, fmt.Errorf("card with ID %d is not in the trash", cardID)
+}
+
+// RestoreCard takes a card out of the trash and puts it back where it was deleted from, unless that would take
+// its column past a hard WIP limit
+func (c *CardService) RestoreCard(cardID int) (*Card, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	trashedCard, exists := c.trash[cardID]
+	if !exists {
+		return nil, fmt.Errorf("card with ID %d is not in the trash", cardID)
+	}
+
+	column, exists := c.columns[trashedCard.Card.ColumnID]
+	if !exists {
+		return nil, fmt.Errorf("column with ID %d not found", trashedCard.Card.ColumnID)
+	}
+	if err := c.checkWIPLimit(column); err != nil {
+		return nil, err
+	}
+
+	cell := trashedCard.Card.Cell()
+	if _, exists := c.lanes[cell.LaneID]; !exists {
+		// The lane was deleted while the card was in the trash
+		cell.LaneID = c.getSortedLanes(c.columns[cell.ColumnID].BoardID)[0].ID
+	}
+	position := trashedCard.Position
+	if cellLength := len(c.cellCards[cell]); position < 0 || position > cellLength {
+		position = cellLength
+	}
+
+	c.record(&CardRestored{CardID: cardID, Cell: cell, Position: position}, time.Now())
+	card := *c.cards[cardID]
+	return &card, nil
+}
+
+// PurgeCard permanently removes a card from the trash
+func (c *CardService) PurgeCard(cardID int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.trash[cardID]; !exists {
+		return fmt.Errorf("card with ID %d is not in the trash", cardID)
+	}
+
+	c.record(&CardPurged{CardID: cardID}, time.Now())
 	return nil
 }
 
-func (c *CardService) removeCardFromColumn(cardID, columnID int) {
-	c.columnCards[columnID] = removeFromSlice(c.columnCards[columnID], cardID)
+// GetExpiredTrash returns the IDs of trashed cards deleted before the cutoff
+func (c *CardService) GetExpiredTrash(cutoff time.Time) []int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var expired []int
+	for cardID, trashedCard := range c.trash {
+		if trashedCard.DeletedAt.Before(cutoff) {
+			expired = append(expired, cardID)
+		}
+	}
+	return expired
+}
+
+// ArchiveCard hides a card from its column while keeping it on the board
+func (c *CardService) ArchiveCard(cardID int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	card, exists := c.cards[cardID]
+	if !exists {
+		return fmt.Errorf("card with ID %d not found", cardID)
+	}
+
+	if card.IsArchived() {
+		return fmt.Errorf("card %d is already archived", cardID)
+	}
+
+	now := time.Now()
+	c.record(&CardArchived{
+		CardID:     cardID,
+		Cell:       card.Cell(),
+		Position:   slices.Index(c.cellCards[card.Cell()], cardID),
+		ArchivedAt: now,
+	}, now)
+	return nil
+}
+
+// UnarchiveCard returns an archived card to the bottom of its cell, unless that would take its column past a hard
+// WIP limit
+func (c *CardService) UnarchiveCard(cardID int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	card, exists := c.cards[cardID]
+	if !exists {
+		return fmt.Errorf("card with ID %d not found", cardID)
+	}
+
+	if !card.IsArchived() {
+		return fmt.Errorf("card %d is not archived", cardID)
+	}
+	if column, exists := c.columns[card.ColumnID]; exists {
+		if err := c.checkWIPLimit(column); err != nil {
+			return err
+		}
+	}
+
+	c.record(&CardUnarchived{CardID: cardID, Position: len(c.cellCards[card.Cell()])}, time.Now())
+	return nil
+}
+
+// GetArchive returns the board's archived cards matching the query, most recently archived first
+func (c *CardService) GetArchive(boardID int, query string) []Card {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	query = strings.ToLower(strings.TrimSpace(query))
+	var archived []Card
+	for _, card := range c.cards {
+		column, exists := c.columns[card.ColumnID]
+		if !exists || column.BoardID != boardID || !card.IsArchived() {
+			continue
+		}
+		if query != "" &&
+			!strings.Contains(strings.ToLower(card.Title), query) &&
+			!strings.Contains(strings.ToLower(card.Content), query) {
+			continue
+		}
+		archived = append(archived, *card)
+	}
+	sort.Slice(archived, func(i, j int) bool {
+		return archived[i].ArchivedAt.After(archived[j].ArchivedAt)
+	})
+	return archived
+}
+
+// AddComment appends a comment to a card, which makes it searchable too
+func (c *CardService) AddComment(cardID int, author, body string) (*Comment, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	card, exists := c.cards[cardID]
+	if !exists {
+		return nil, fmt.Errorf("card with ID %d not found", cardID)
+	}
+
+	if c.moderationService != nil {
+		var err error
+		if body, err = c.moderationService.CheckText(c.columns[card.ColumnID].BoardID, "comment", body); err != nil {
+			return nil, err
+		}
+	} else if blacklistedWord := c.wordService.Filter(body); blacklistedWord != "" {
+		return nil, fmt.Errorf("comment contains prohibited word: %s", blacklistedWord)
+	}
+
+	comment := Comment{
+		ID:        c.nextCommentID,
+		CardID:    cardID,
+		Author:    author,
+		Body:      body,
+		CreatedAt: time.Now(),
+	}
+	c.record(&CommentAdded{Comment: comment}, comment.CreatedAt)
+	return &comment, nil
+}
+
+func (c *CardService) GetComments(cardID int) []Comment {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return slices.Clone(c.comments[cardID])
+}
+
+// Search finds the board's cards, archived ones included, that match the query, in board order
+func (c *CardService) Search(boardID int, input string) (*SearchQuery, []SearchResult) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	query := c.index.ParseQuery(input)
+	if query.IsEmpty() {
+		return query, nil
+	}
+
+	terms := query.highlightTerms()
+	var results []SearchResult
+	for _, card := range c.searchCards(boardID, query) {
+		texts := []string{card.Content}
+		for _, comment := range c.comments[card.ID] {
+			texts = append(texts, comment.Body)
+		}
+
+		results = append(results, SearchResult{
+			Card:        *card,
+			ColumnTitle: c.columns[card.ColumnID].Title,
+			LaneTitle:   c.lanes[card.LaneID].Title,
+			Title:       c.index.Highlight(card.Title, terms),
+			Snippet:     c.index.Snippet(texts, terms),
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i].Card, results[j].Card
+		if a.IsArchived() != b.IsArchived() {
+			return !a.IsArchived()
+		}
+		if a.ColumnID != b.ColumnID {
+			return c.columns[a.ColumnID].Order < c.columns[b.ColumnID].Order
+		}
+		return c.lanes[a.LaneID].Order < c.lanes[b.LaneID].Order
+	})
+	return query, results
+}
+
+// MatchCards returns the IDs of the board's cards matching a search, using the same syntax as Search
+func (c *CardService) MatchCards(boardID int, input string) map[int]bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	matches := make(map[int]bool)
+	for _, card := range c.searchCards(boardID, c.index.ParseQuery(input)) {
+		matches[card.ID] = true
+	}
+	return matches
+}
+
+// searchCards returns the board's cards matching the query; the caller must hold the lock
+func (c *CardService) searchCards(boardID int, query *SearchQuery) []*Card {
+	if query.IsEmpty() {
+		return nil
+	}
+
+	var cards []*Card
+	for _, cardID := range c.index.Match(query) {
+		card := c.cards[cardID]
+		column := c.columns[card.ColumnID]
+		if column.BoardID != boardID {
+			continue
+		}
+		if len(query.Columns) > 0 && !slices.Contains(query.Columns, c.index.normalize(column.Title)) {
+			continue
+		}
+		cards = append(cards, card)
+	}
+	return cards
+}
+
+// GetCardPosition returns the index of the card within its cell
+func (c *CardService) GetCardPosition(cardID int) (int, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	card, exists := c.cards[cardID]
+	if !exists {
+		return 0, fmt.Errorf("card with ID %d not found", cardID)
+	}
+
+	return slices.Index(c.cellCards[card.Cell()], cardID), nil
+}
+
+func (c *CardService) removeCardFromCell(cardID int, cell Cell) {
+	c.cellCards[cell] = removeFromSlice(c.cellCards[cell], cardID)
 }
 
-func (c *CardService) insertCardInColumn(cardID, columnID, position int) {
-	cardList := c.columnCards[columnID]
+func (c *CardService) insertCardInCell(cardID int, cell Cell, position int) {
+	cardList := c.cellCards[cell]
 
 	if position == -1 || position >= len(cardList) {
-		c.columnCards[columnID] = append(cardList, cardID)
+		c.cellCards[cell] = append(cardList, cardID)
 	} else {
 		tail := append([]int{cardID}, cardList[position:]...)
 		cardList = append(cardList[:position], tail...)
-		c.columnCards[columnID] = cardList
+		c.cellCards[cell] = cardList
 	}
 }
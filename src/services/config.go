@@ -0,0 +1,89 @@
+package services
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	AdminToken            string
+	BlacklistPath         string
+	BlacklistPollInterval time.Duration
+	Leetspeak             []string
+	SeedPath              string
+
+	AttachmentDir       string
+	AttachmentMaxSize   int64
+	AttachmentMimeTypes []string
+
+	TrashRetentionDays int
+
+	WebhookTimeout     time.Duration
+	WebhookMaxAttempts int
+	WebhookBackoff     time.Duration
+}
+
+// LoadConfig reads the service configuration from the environment, falling back to defaults
+func LoadConfig() *Config {
+	return &Config{
+		AdminToken:            getEnv("MESH_ADMIN_TOKEN", ""),
+		BlacklistPath:         getEnv("MESH_BLACKLIST_PATH", "blacklist.txt"),
+		BlacklistPollInterval: getEnvDuration("MESH_BLACKLIST_POLL_INTERVAL", 10*time.Second),
+		Leetspeak:             getEnvList("MESH_LEETSPEAK", defaultLeetspeak),
+		SeedPath:              getEnv("MESH_SEED_PATH", "seed.json"),
+		AttachmentDir:         getEnv("MESH_ATTACHMENT_DIR", "attachments"),
+		AttachmentMaxSize:     getEnvInt64("MESH_ATTACHMENT_MAX_SIZE", 10<<20),
+		AttachmentMimeTypes: getEnvList("MESH_ATTACHMENT_MIME_TYPES", []string{
+			"image/png",
+			"image/jpeg",
+			"image/gif",
+			"image/webp",
+			"application/pdf",
+			"text/plain",
+		}),
+		TrashRetentionDays: int(getEnvInt64("MESH_TRASH_RETENTION_DAYS", 30)),
+		WebhookTimeout:     getEnvDuration("MESH_WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookMaxAttempts: int(getEnvInt64("MESH_WEBHOOK_MAX_ATTEMPTS", 6)),
+		WebhookBackoff:     getEnvDuration("MESH_WEBHOOK_BACKOFF", 30*time.Second),
+	}
+}
+
+func getEnv(key, fallback string) string {
+	if value, exists := os.LookupEnv(key); exists && value != "" {
+		return value
+	}
+	return fallback
+}
+
+func getEnvInt64(key string, fallback int64) int64 {
+	value, err := strconv.ParseInt(getEnv(key, ""), 10, 64)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(getEnv(key, ""))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+func getEnvList(key string, fallback []string) []string {
+	value := getEnv(key, "")
+	if value == "" {
+		return fallback
+	}
+
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,403 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BoardDocumentVersion is the version of the board document this build writes, and the only one it reads
+const BoardDocumentVersion = 1
+
+// BoardDocument is a whole board as versioned JSON, for backups, moving boards between instances and seeding.
+// IDs are the exporting instance's and only tie cards to their columns and lanes; importing gives everything new
+// ones. Columns and lanes are listed in board order, and cards in the order they sit in their cell.
+type BoardDocument struct {
+	Version    int           `json:"version"`
+	ExportedAt string        `json:"exportedAt,omitempty"`
+	Board      BoardSnapshot `json:"board"`
+}
+
+type BoardSnapshot struct {
+	ID      int              `json:"id"`
+	Title   string           `json:"title"`
+	Columns []ColumnSnapshot `json:"columns"`
+	Lanes   []LaneSnapshot   `json:"lanes"`
+	Cards   []CardSnapshot   `json:"cards"`
+}
+
+type ColumnSnapshot struct {
+	ID       int     `json:"id"`
+	Title    string  `json:"title"`
+	WIPLimit int     `json:"wipLimit,omitempty"`
+	WIPMode  WIPMode `json:"wipMode,omitempty"`
+}
+
+type LaneSnapshot struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+}
+
+type CardSnapshot struct {
+	ID         int               `json:"id"`
+	Title      string            `json:"title"`
+	Content    string            `json:"content,omitempty"`
+	ColumnID   int               `json:"columnID"`
+	LaneID     int               `json:"laneID"`
+	Labels     []string          `json:"labels,omitempty"`
+	Assignee   string            `json:"assignee,omitempty"`
+	DueAt      string            `json:"dueAt,omitempty"`      // a DueDateLayout date
+	ArchivedAt string            `json:"archivedAt,omitempty"` // RFC 3339
+	Comments   []CommentSnapshot `json:"comments,omitempty"`
+}
+
+type CommentSnapshot struct {
+	Author    string `json:"author"`
+	Body      string `json:"body"`
+	CreatedAt string `json:"createdAt"` // RFC 3339
+}
+
+// DocumentError is a problem with a board document, at the JSON path of the value at fault
+type DocumentError struct {
+	Path    string
+	Message string
+}
+
+func (e *DocumentError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// DocumentErrors is every problem found with a board document, in document order
+type DocumentErrors []*DocumentError
+
+func (e DocumentErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// ExportBoard writes the board, its archived cards and their comments to a document. Trashed cards aren't part
+// of the board any more, and attachments are kept on disk rather than in the card service, so neither is included.
+func (c *CardService) ExportBoard(boardID int, now time.Time) (*BoardDocument, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	board, exists := c.boards[boardID]
+	if !exists {
+		return nil, fmt.Errorf("board with ID %d not found", boardID)
+	}
+
+	snapshot := BoardSnapshot{
+		ID:      board.ID,
+		Title:   board.Title,
+		Columns: []ColumnSnapshot{},
+		Lanes:   []LaneSnapshot{},
+		Cards:   []CardSnapshot{},
+	}
+	columns := c.getSortedColumns(boardID)
+	for _, column := range columns {
+		snapshot.Columns = append(snapshot.Columns, ColumnSnapshot{
+			ID:       column.ID,
+			Title:    column.Title,
+			WIPLimit: column.WIPLimit,
+			WIPMode:  column.WIPMode,
+		})
+	}
+	lanes := c.getSortedLanes(boardID)
+	for _, lane := range lanes {
+		snapshot.Lanes = append(snapshot.Lanes, LaneSnapshot{ID: lane.ID, Title: lane.Title})
+	}
+
+	for _, column := range columns {
+		for _, lane := range lanes {
+			for _, cardID := range c.cellCards[Cell{ColumnID: column.ID, LaneID: lane.ID}] {
+				snapshot.Cards = append(snapshot.Cards, c.snapshotCard(c.cards[cardID]))
+			}
+		}
+	}
+
+	// Archived cards aren't in any cell, so they follow the rest in the order they were created
+	var archived []*Card
+	for _, card := range c.cards {
+		if column, exists := c.columns[card.ColumnID]; exists && column.BoardID == boardID && card.IsArchived() {
+			archived = append(archived, card)
+		}
+	}
+	slices.SortFunc(archived, func(a, b *Card) int {
+		return a.ID - b.ID
+	})
+	for _, card := range archived {
+		snapshot.Cards = append(snapshot.Cards, c.snapshotCard(card))
+	}
+
+	return &BoardDocument{
+		Version:    BoardDocumentVersion,
+		ExportedAt: now.UTC().Format(time.RFC3339),
+		Board:      snapshot,
+	}, nil
+}
+
+// snapshotCard copies the card and its comments into a document; the caller holds the lock
+func (c *CardService) snapshotCard(card *Card) CardSnapshot {
+	snapshot := CardSnapshot{
+		ID:       card.ID,
+		Title:    card.Title,
+		Content:  card.Content,
+		ColumnID: card.ColumnID,
+		LaneID:   card.LaneID,
+		Labels:   slices.Clone(card.Labels),
+		Assignee: card.Assignee,
+	}
+	if card.HasDueDate() {
+		snapshot.DueAt = card.DueAt.Format(DueDateLayout)
+	}
+	if card.IsArchived() {
+		snapshot.ArchivedAt = card.ArchivedAt.UTC().Format(time.RFC3339)
+	}
+	for _, comment := range c.comments[card.ID] {
+		snapshot.Comments = append(snapshot.Comments, CommentSnapshot{
+			Author:    comment.Author,
+			Body:      comment.Body,
+			CreatedAt: comment.CreatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+	return snapshot
+}
+
+// ParseBoardDocument decodes a board document, reporting where in it anything is malformed
+func ParseBoardDocument(data []byte) (*BoardDocument, error) {
+	var document BoardDocument
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if err := decoder.Decode(&document); err != nil {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &syntaxErr):
+			return nil, DocumentErrors{{Path: "$", Message: fmt.Sprintf("invalid JSON at byte %d: %s", syntaxErr.Offset, syntaxErr)}}
+		case errors.As(err, &typeErr):
+			return nil, DocumentErrors{{Path: jsonPath(typeErr.Field), Message: fmt.Sprintf("expected %s, got %s", typeErr.Type, typeErr.Value)}}
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			return nil, DocumentErrors{{Path: "$", Message: "invalid JSON: the document ends too soon"}}
+		default:
+			return nil, DocumentErrors{{Path: "$", Message: err.Error()}}
+		}
+	}
+	return &document, nil
+}
+
+// jsonPath turns the dotted field the JSON decoder reports, like board.cards.0.labels, into a path like
+// $.board.cards[0].labels
+func jsonPath(field string) string {
+	path := "$"
+	if field == "" {
+		return path
+	}
+	for _, segment := range strings.Split(field, ".") {
+		if _, err := strconv.Atoi(segment); err == nil {
+			path += "[" + segment + "]"
+		} else {
+			path += "." + segment
+		}
+	}
+	return path
+}
+
+// documentCheck collects the problems with a board document as it's walked
+type documentCheck struct {
+	errors      DocumentErrors
+	wordService *WordService
+}
+
+func (d *documentCheck) fail(path, format string, args ...any) {
+	d.errors = append(d.errors, &DocumentError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// text checks a required or optional string against a length limit and the blacklist
+func (d *documentCheck) text(path, value string, required bool, limit int) {
+	switch {
+	case required && strings.TrimSpace(value) == "":
+		d.fail(path, "is required")
+	case len(value) > limit:
+		d.fail(path, "must be less than %d characters", limit)
+	case d.wordService != nil && d.wordService.Filter(value) != "":
+		d.fail(path, "contains prohibited word: %s", d.wordService.Filter(value))
+	}
+}
+
+func (d *documentCheck) time(path, value, layout string) time.Time {
+	parsed, err := time.Parse(layout, value)
+	if err != nil {
+		d.fail(path, "expected a time like %s, got %q", layout, value)
+	}
+	return parsed
+}
+
+// validate checks the whole document the way the board's own forms would, so nothing is imported half way
+func (d *documentCheck) validate(document *BoardDocument) {
+	switch {
+	case document.Version == 0:
+		d.fail("$.version", "is required")
+	case document.Version != BoardDocumentVersion:
+		d.fail("$.version", "unsupported version %d, expected %d", document.Version, BoardDocumentVersion)
+	}
+
+	board := document.Board
+	d.text("$.board.title", board.Title, true, 100)
+
+	columnIDs := make(map[int]bool)
+	if len(board.Columns) == 0 {
+		d.fail("$.board.columns", "a board needs at least one column")
+	}
+	for i, column := range board.Columns {
+		path := fmt.Sprintf("$.board.columns[%d]", i)
+		if columnIDs[column.ID] {
+			d.fail(path+".id", "duplicate column ID %d", column.ID)
+		}
+		columnIDs[column.ID] = true
+		d.text(path+".title", column.Title, true, 100)
+		if column.WIPLimit < 0 {
+			d.fail(path+".wipLimit", "must not be negative")
+		}
+		if column.WIPMode != "" && column.WIPMode != WIPModeSoft && column.WIPMode != WIPModeHard {
+			d.fail(path+".wipMode", "expected %q or %q, got %q", WIPModeSoft, WIPModeHard, column.WIPMode)
+		}
+	}
+
+	laneIDs := make(map[int]bool)
+	if len(board.Lanes) == 0 {
+		d.fail("$.board.lanes", "a board needs at least one lane")
+	}
+	for i, lane := range board.Lanes {
+		path := fmt.Sprintf("$.board.lanes[%d]", i)
+		if laneIDs[lane.ID] {
+			d.fail(path+".id", "duplicate lane ID %d", lane.ID)
+		}
+		laneIDs[lane.ID] = true
+		d.text(path+".title", lane.Title, true, 100)
+	}
+
+	cardIDs := make(map[int]bool)
+	for i, card := range board.Cards {
+		path := fmt.Sprintf("$.board.cards[%d]", i)
+		if cardIDs[card.ID] {
+			d.fail(path+".id", "duplicate card ID %d", card.ID)
+		}
+		cardIDs[card.ID] = true
+		d.text(path+".title", card.Title, true, 100)
+		d.text(path+".content", card.Content, false, 1000)
+		d.text(path+".assignee", card.Assignee, false, 50)
+		if !columnIDs[card.ColumnID] {
+			d.fail(path+".columnID", "no column with ID %d", card.ColumnID)
+		}
+		if !laneIDs[card.LaneID] {
+			d.fail(path+".laneID", "no lane with ID %d", card.LaneID)
+		}
+		if len(card.Labels) > 10 {
+			d.fail(path+".labels", "cards can have at most 10 labels")
+		}
+		for j, label := range card.Labels {
+			d.text(fmt.Sprintf("%s.labels[%d]", path, j), label, true, 30)
+		}
+		if card.DueAt != "" {
+			d.time(path+".dueAt", card.DueAt, DueDateLayout)
+		}
+		if card.ArchivedAt != "" {
+			d.time(path+".archivedAt", card.ArchivedAt, time.RFC3339)
+		}
+		for j, comment := range card.Comments {
+			commentPath := fmt.Sprintf("%s.comments[%d]", path, j)
+			d.text(commentPath+".author", comment.Author, true, 50)
+			d.text(commentPath+".body", comment.Body, true, 500)
+			d.time(commentPath+".createdAt", comment.CreatedAt, time.RFC3339)
+		}
+	}
+}
+
+// ValidateBoardDocument returns every problem with the document, or nil if it can be imported
+func (c *CardService) ValidateBoardDocument(document *BoardDocument) error {
+	check := &documentCheck{wordService: c.wordService}
+	check.validate(document)
+	if len(check.errors) > 0 {
+		return check.errors
+	}
+	return nil
+}
+
+// ImportBoard creates a new board from the document, giving the board and everything on it new IDs. Cards
+// keep their order within each cell, and WIP limits are set but not enforced against the cards imported.
+func (c *CardService) ImportBoard(document *BoardDocument) (*Board, error) {
+	if err := c.ValidateBoardDocument(document); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	board := Board{ID: c.nextBoardID, Title: strings.TrimSpace(document.Board.Title)}
+	c.record(&BoardCreated{Board: board}, now)
+
+	columnIDs := make(map[int]int) // document ID -> new ID
+	for i, snapshot := range document.Board.Columns {
+		column := Column{
+			ID:       c.nextColumnID,
+			BoardID:  board.ID,
+			Title:    strings.TrimSpace(snapshot.Title),
+			Order:    i,
+			WIPLimit: snapshot.WIPLimit,
+			WIPMode:  snapshot.WIPMode,
+		}
+		columnIDs[snapshot.ID] = column.ID
+		c.record(&ColumnCreated{Column: column}, now)
+	}
+
+	laneIDs := make(map[int]int) // document ID -> new ID
+	for i, snapshot := range document.Board.Lanes {
+		lane := Lane{ID: c.nextLaneID, BoardID: board.ID, Title: strings.TrimSpace(snapshot.Title), Order: i}
+		laneIDs[snapshot.ID] = lane.ID
+		c.record(&LaneCreated{Lane: lane}, now)
+	}
+
+	for _, snapshot := range document.Board.Cards {
+		card := Card{
+			ID:       c.nextCardID,
+			Title:    strings.TrimSpace(snapshot.Title),
+			Content:  snapshot.Content,
+			ColumnID: columnIDs[snapshot.ColumnID],
+			LaneID:   laneIDs[snapshot.LaneID],
+			Labels:   slices.Clone(snapshot.Labels),
+			Assignee: snapshot.Assignee,
+		}
+		// Validation has already checked the times parse
+		if snapshot.DueAt != "" {
+			card.DueAt, _ = time.ParseInLocation(DueDateLayout, snapshot.DueAt, time.Local)
+		}
+		if snapshot.ArchivedAt != "" {
+			card.ArchivedAt, _ = time.Parse(time.RFC3339, snapshot.ArchivedAt)
+		}
+		c.record(&CardCreated{Card: card, Position: len(c.cellCards[card.Cell()])}, now)
+
+		for _, snapshot := range snapshot.Comments {
+			createdAt, _ := time.Parse(time.RFC3339, snapshot.CreatedAt)
+			comment := Comment{
+				ID:        c.nextCommentID,
+				CardID:    card.ID,
+				Author:    snapshot.Author,
+				Body:      snapshot.Body,
+				CreatedAt: createdAt,
+			}
+			c.record(&CommentAdded{Comment: comment}, now)
+		}
+	}
+
+	c.log.Info("Imported board", "boardID", board.ID, "title", board.Title, "cards", len(document.Board.Cards))
+	return &board, nil
+}
@@ -1,13 +1,26 @@
 package services
 
 import (
+	"fmt"
 	"log/slog"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"time"
 )
 
 const (
-	CardMovedEventKey   = "card-moved"
-	CardChangedEventKey = "card-changed"
-	CardDeletedEventKey = "card-deleted"
+	CardMovedEventKey       = "card-moved"
+	CardChangedEventKey     = "card-changed"
+	CardDeletedEventKey     = "card-deleted"
+	CardArchivedEventKey    = "card-archived"
+	CardRestoredEventKey    = "card-restored"
+	CardPurgedEventKey      = "card-purged"
+	CardCommentedEventKey   = "card-commented"
+	ColumnChangedEventKey   = "column-changed"
+	LaneChangedEventKey     = "lane-changed"
+	FilterChangedEventKey   = "filter-changed"
+	TemplateChangedEventKey = "template-changed"
 )
 
 type Event interface {
@@ -15,15 +28,111 @@ type Event interface {
 }
 
 type CardDeletedEvent struct {
+	Actor    string
+	CardID   int
 	ColumnID int
+	LaneID   int
+	Time     time.Time
 }
 
 func (e *CardDeletedEvent) Key() string {
 	return CardDeletedEventKey
 }
 
+type CardArchivedEvent struct {
+	Actor    string
+	CardID   int
+	ColumnID int
+	LaneID   int
+	Time     time.Time
+}
+
+func (e *CardArchivedEvent) Key() string {
+	return CardArchivedEventKey
+}
+
+// CardRestoredEvent is published when a card comes back from the trash or the archive
+type CardRestoredEvent struct {
+	Actor    string
+	CardID   int
+	ColumnID int
+	LaneID   int
+	Time     time.Time
+}
+
+func (e *CardRestoredEvent) Key() string {
+	return CardRestoredEventKey
+}
+
+type CardPurgedEvent struct {
+	Actor  string
+	CardID int
+	Time   time.Time
+}
+
+func (e *CardPurgedEvent) Key() string {
+	return CardPurgedEventKey
+}
+
+// LaneChangedEvent is published when a board's lanes are added or removed
+type LaneChangedEvent struct {
+	Actor   string
+	BoardID int
+}
+
+func (e *LaneChangedEvent) Key() string {
+	return LaneChangedEventKey
+}
+
+// TemplateChangedEvent is published when a board's card templates are added, edited or removed
+type TemplateChangedEvent struct {
+	Actor   string
+	BoardID int
+}
+
+func (e *TemplateChangedEvent) Key() string {
+	return TemplateChangedEventKey
+}
+
+// FilterChangedEvent is published when a session applies or clears a filter on its view of a board
+type FilterChangedEvent struct {
+	Actor     string
+	SessionID string
+	BoardID   int
+}
+
+func (e *FilterChangedEvent) Key() string {
+	return FilterChangedEventKey
+}
+
+// ColumnChangedEvent is published when a column's settings, such as its WIP limit, change
+type ColumnChangedEvent struct {
+	Actor    string
+	ColumnID int
+}
+
+func (e *ColumnChangedEvent) Key() string {
+	return ColumnChangedEventKey
+}
+
+type CardCommentedEvent struct {
+	Actor     string
+	CardID    int
+	CommentID int
+	Time      time.Time
+}
+
+func (e *CardCommentedEvent) Key() string {
+	return CardCommentedEventKey
+}
+
+// CardChangedEvent is published when a card is added or edited. Card is the card as it was then, since by the time
+// a subscriber gets the event the card may have changed again.
 type CardChangedEvent struct {
+	Actor  string
 	CardID int
+	Card   Card
+	Time   time.Time
 }
 
 func (e *CardChangedEvent) Key() string {
@@ -31,81 +140,367 @@ func (e *CardChangedEvent) Key() string {
 }
 
 type CardMovedEvent struct {
+	Actor        string
 	CardID       int
 	FromColumnID int
+	FromLaneID   int
 	ToColumnID   int
+	ToLaneID     int
+	Time         time.Time
 }
 
 func (e *CardMovedEvent) Key() string {
 	return CardMovedEventKey
 }
 
+// subscription is one subscriber's handler for one kind of event
+type subscription struct {
+	subscriber string
+	handler    func(event Event)
+}
+
+// queuedEvent is an event waiting for delivery to one subscription
+type queuedEvent struct {
+	event        Event
+	subscription *subscription
+}
+
+// EventService is the bus events are published on. Each subscriber, named when it subscribes, has a queue for
+// every subject, which is the card for card events and the kind of event otherwise. A queue is delivered on its own
+// goroutine in the order events were published, so a subscriber sees everything about one card in sequence,
+// whatever kinds of event they are, while a slow subscriber only holds up itself. A panicking subscriber is logged
+// and delivery carries on. A synchronous service calls subscribers on the publisher's goroutine instead.
 type EventService struct {
 	log         *slog.Logger
-	subscribers map[string][]func(event Event)
+	synchronous bool
+
+	mu            sync.RWMutex
+	subscriptions map[string][]*subscription // event key -> subscriptions
+
+	queueMu sync.Mutex
+	queues  map[string][]queuedEvent // subscriber and subject -> events waiting; present while a goroutine delivers them
+	pending int
+	idle    *sync.Cond
+}
+
+func NewEventService(log *slog.Logger) *EventService {
+	service := &EventService{
+		log:           log,
+		subscriptions: make(map[string][]*subscription),
+		queues:        make(map[string][]queuedEvent),
+	}
+	service.idle = sync.NewCond(&service.queueMu)
+	return service
+}
+
+// NewSynchronousEventService delivers each event to every subscriber before Publish returns, which makes the
+// effects of publishing easy to check in tests
+func NewSynchronousEventService(log *slog.Logger) *EventService {
+	service := NewEventService(log)
+	service.synchronous = true
+	return service
+}
+
+// subject is what an event is about, and so what it must stay in order with
+func subject(event Event) string {
+	switch event := event.(type) {
+	case *CardMovedEvent:
+		return fmt.Sprintf("card:%d", event.CardID)
+	case *CardChangedEvent:
+		return fmt.Sprintf("card:%d", event.CardID)
+	case *CardDeletedEvent:
+		return fmt.Sprintf("card:%d", event.CardID)
+	case *CardArchivedEvent:
+		return fmt.Sprintf("card:%d", event.CardID)
+	case *CardRestoredEvent:
+		return fmt.Sprintf("card:%d", event.CardID)
+	case *CardPurgedEvent:
+		return fmt.Sprintf("card:%d", event.CardID)
+	case *CardCommentedEvent:
+		return fmt.Sprintf("card:%d", event.CardID)
+	default:
+		return event.Key()
+	}
 }
 
+// Publish queues the event for each of its subscribers without waiting for them
 func (e *EventService) Publish(event Event) {
-	for _, subscriber := range e.subscribers[event.Key()] {
-		subscriber(event)
+	e.mu.RLock()
+	subscriptions := e.subscriptions[event.Key()]
+	e.mu.RUnlock()
+
+	if e.synchronous {
+		for _, subscription := range subscriptions {
+			e.deliver(subscription, event)
+		}
+		return
+	}
+
+	about := subject(event)
+	for _, subscription := range subscriptions {
+		queue := subscription.subscriber + " " + about
+		e.queueMu.Lock()
+		e.pending++
+		waiting, delivering := e.queues[queue]
+		e.queues[queue] = append(waiting, queuedEvent{event: event, subscription: subscription})
+		e.queueMu.Unlock()
+
+		if !delivering {
+			go e.run(queue)
+		}
 	}
 }
 
-func (e *EventService) Subscribe(key string, subscriber func(event Event)) {
-	e.subscribers[key] = append(e.subscribers[key], subscriber)
+// Subscribe has the subscriber's handler called with every event of the kind. Events about the same subject reach
+// a subscriber in order across all the kinds it subscribes to, so each subscriber needs a name of its own.
+func (e *EventService) Subscribe(subscriber, key string, handler func(event Event)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.subscriptions[key] = append(e.subscriptions[key], &subscription{
+		subscriber: subscriber,
+		handler:    handler,
+	})
 }
 
-func NewEventService(log *slog.Logger) *EventService {
-	return &EventService{
-		log:         log,
-		subscribers: make(map[string][]func(event Event)),
+// Wait blocks until every event published so far has been delivered, including any published along the way
+func (e *EventService) Wait() {
+	e.queueMu.Lock()
+	defer e.queueMu.Unlock()
+
+	for e.pending > 0 {
+		e.idle.Wait()
 	}
 }
 
+// run delivers the queue's events one at a time until there are none left
+func (e *EventService) run(queue string) {
+	for {
+		e.queueMu.Lock()
+		waiting := e.queues[queue]
+		if len(waiting) == 0 {
+			delete(e.queues, queue)
+			e.queueMu.Unlock()
+			return
+		}
+		next := waiting[0]
+		waiting[0] = queuedEvent{}
+		e.queues[queue] = waiting[1:]
+		e.queueMu.Unlock()
+
+		e.deliver(next.subscription, next.event)
+
+		e.queueMu.Lock()
+		e.pending--
+		if e.pending == 0 {
+			e.idle.Broadcast()
+		}
+		e.queueMu.Unlock()
+	}
+}
+
+func (e *EventService) deliver(subscription *subscription, event Event) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			e.log.Error("Event subscriber panicked",
+				"subscriber", subscription.subscriber,
+				"event", event.Key(),
+				"panic", recovered,
+				"stack", string(debug.Stack()),
+			)
+		}
+	}()
+
+	subscription.handler(event)
+}
+
 func (e *EventService) PublishCardMoved(
+	actor string,
 	cardID int,
-	fromColumnID int,
-	toColumnID int,
+	from Cell,
+	to Cell,
 ) *CardMovedEvent {
 	event := &CardMovedEvent{
+		Actor:        actor,
 		CardID:       cardID,
-		FromColumnID: fromColumnID,
-		ToColumnID:   toColumnID,
+		FromColumnID: from.ColumnID,
+		FromLaneID:   from.LaneID,
+		ToColumnID:   to.ColumnID,
+		ToLaneID:     to.LaneID,
+		Time:         time.Now(),
 	}
 	e.Publish(event)
 	return event
 }
 
-func (e *EventService) SubscribeCardMoved(subscriber func(event *CardMovedEvent)) {
-	e.Subscribe(CardMovedEventKey, func(event Event) {
-		subscriber(event.(*CardMovedEvent))
+func (e *EventService) SubscribeCardMoved(subscriber string, handler func(event *CardMovedEvent)) {
+	e.Subscribe(subscriber, CardMovedEventKey, func(event Event) {
+		handler(event.(*CardMovedEvent))
 	})
 }
 
-func (e *EventService) PublishCardChanged(cardID int) *CardChangedEvent {
+func (e *EventService) PublishCardChanged(actor string, card Card) *CardChangedEvent {
+	card.Labels = slices.Clone(card.Labels)
 	event := &CardChangedEvent{
-		CardID: cardID,
+		Actor:  actor,
+		CardID: card.ID,
+		Card:   card,
+		Time:   time.Now(),
 	}
 	e.Publish(event)
 	return event
 }
 
-func (e *EventService) SubscribeCardChanged(subscriber func(event *CardChangedEvent)) {
-	e.Subscribe(CardChangedEventKey, func(event Event) {
-		subscriber(event.(*CardChangedEvent))
+func (e *EventService) SubscribeCardChanged(subscriber string, handler func(event *CardChangedEvent)) {
+	e.Subscribe(subscriber, CardChangedEventKey, func(event Event) {
+		handler(event.(*CardChangedEvent))
 	})
 }
 
-func (e *EventService) PublishCardDeleted(columnID int) *CardDeletedEvent {
+func (e *EventService) PublishCardDeleted(actor string, cardID int, cell Cell) *CardDeletedEvent {
 	event := &CardDeletedEvent{
+		Actor:    actor,
+		CardID:   cardID,
+		ColumnID: cell.ColumnID,
+		LaneID:   cell.LaneID,
+		Time:     time.Now(),
+	}
+	e.Publish(event)
+	return event
+}
+
+func (e *EventService) SubscribeCardDeleted(subscriber string, handler func(event *CardDeletedEvent)) {
+	e.Subscribe(subscriber, CardDeletedEventKey, func(event Event) {
+		handler(event.(*CardDeletedEvent))
+	})
+}
+
+func (e *EventService) PublishCardArchived(actor string, cardID int, cell Cell) *CardArchivedEvent {
+	event := &CardArchivedEvent{
+		Actor:    actor,
+		CardID:   cardID,
+		ColumnID: cell.ColumnID,
+		LaneID:   cell.LaneID,
+		Time:     time.Now(),
+	}
+	e.Publish(event)
+	return event
+}
+
+func (e *EventService) SubscribeCardArchived(subscriber string, handler func(event *CardArchivedEvent)) {
+	e.Subscribe(subscriber, CardArchivedEventKey, func(event Event) {
+		handler(event.(*CardArchivedEvent))
+	})
+}
+
+func (e *EventService) PublishCardRestored(actor string, cardID int, cell Cell) *CardRestoredEvent {
+	event := &CardRestoredEvent{
+		Actor:    actor,
+		CardID:   cardID,
+		ColumnID: cell.ColumnID,
+		LaneID:   cell.LaneID,
+		Time:     time.Now(),
+	}
+	e.Publish(event)
+	return event
+}
+
+func (e *EventService) SubscribeCardRestored(subscriber string, handler func(event *CardRestoredEvent)) {
+	e.Subscribe(subscriber, CardRestoredEventKey, func(event Event) {
+		handler(event.(*CardRestoredEvent))
+	})
+}
+
+func (e *EventService) PublishCardPurged(actor string, cardID int) *CardPurgedEvent {
+	event := &CardPurgedEvent{
+		Actor:  actor,
+		CardID: cardID,
+		Time:   time.Now(),
+	}
+	e.Publish(event)
+	return event
+}
+
+func (e *EventService) SubscribeCardPurged(subscriber string, handler func(event *CardPurgedEvent)) {
+	e.Subscribe(subscriber, CardPurgedEventKey, func(event Event) {
+		handler(event.(*CardPurgedEvent))
+	})
+}
+
+func (e *EventService) PublishColumnChanged(actor string, columnID int) *ColumnChangedEvent {
+	event := &ColumnChangedEvent{
+		Actor:    actor,
 		ColumnID: columnID,
 	}
 	e.Publish(event)
 	return event
 }
 
-func (e *EventService) SubscribeCardDeleted(subscriber func(event *CardDeletedEvent)) {
-	e.Subscribe(CardDeletedEventKey, func(event Event) {
-		subscriber(event.(*CardDeletedEvent))
+func (e *EventService) SubscribeColumnChanged(subscriber string, handler func(event *ColumnChangedEvent)) {
+	e.Subscribe(subscriber, ColumnChangedEventKey, func(event Event) {
+		handler(event.(*ColumnChangedEvent))
+	})
+}
+
+func (e *EventService) PublishLaneChanged(actor string, boardID int) *LaneChangedEvent {
+	event := &LaneChangedEvent{
+		Actor:   actor,
+		BoardID: boardID,
+	}
+	e.Publish(event)
+	return event
+}
+
+func (e *EventService) SubscribeLaneChanged(subscriber string, handler func(event *LaneChangedEvent)) {
+	e.Subscribe(subscriber, LaneChangedEventKey, func(event Event) {
+		handler(event.(*LaneChangedEvent))
+	})
+}
+
+func (e *EventService) PublishCardCommented(actor string, cardID int, commentID int) *CardCommentedEvent {
+	event := &CardCommentedEvent{
+		Actor:     actor,
+		CardID:    cardID,
+		CommentID: commentID,
+		Time:      time.Now(),
+	}
+	e.Publish(event)
+	return event
+}
+
+func (e *EventService) SubscribeCardCommented(subscriber string, handler func(event *CardCommentedEvent)) {
+	e.Subscribe(subscriber, CardCommentedEventKey, func(event Event) {
+		handler(event.(*CardCommentedEvent))
+	})
+}
+
+func (e *EventService) PublishFilterChanged(actor, sessionID string, boardID int) *FilterChangedEvent {
+	event := &FilterChangedEvent{
+		Actor:     actor,
+		SessionID: sessionID,
+		BoardID:   boardID,
+	}
+	e.Publish(event)
+	return event
+}
+
+func (e *EventService) SubscribeFilterChanged(subscriber string, handler func(event *FilterChangedEvent)) {
+	e.Subscribe(subscriber, FilterChangedEventKey, func(event Event) {
+		handler(event.(*FilterChangedEvent))
+	})
+}
+
+func (e *EventService) PublishTemplateChanged(actor string, boardID int) *TemplateChangedEvent {
+	event := &TemplateChangedEvent{
+		Actor:   actor,
+		BoardID: boardID,
+	}
+	e.Publish(event)
+	return event
+}
+
+func (e *EventService) SubscribeTemplateChanged(subscriber string, handler func(event *TemplateChangedEvent)) {
+	e.Subscribe(subscriber, TemplateChangedEventKey, func(event Event) {
+		handler(event.(*TemplateChangedEvent))
 	})
 }
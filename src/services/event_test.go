@@ -0,0 +1,115 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder collects what one subscriber is told about each card, across every kind of event it subscribes to
+type recorder struct {
+	mu   sync.Mutex
+	seen map[int][]string // cardID -> event keys and actors, in the order they arrived
+}
+
+func (r *recorder) record(cardID int, event Event, actor string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seen[cardID] = append(r.seen[cardID], event.Key()+" "+actor)
+}
+
+func TestEventServiceDeliversEachCardInOrder(t *testing.T) {
+	events := NewEventService(newTestLogger())
+	subscriber := &recorder{seen: map[int][]string{}}
+
+	// Moves are slow to handle, which would let the other kinds overtake them if they were queued apart
+	events.SubscribeCardMoved("recorder", func(event *CardMovedEvent) {
+		time.Sleep(time.Millisecond)
+		subscriber.record(event.CardID, event, event.Actor)
+	})
+	events.SubscribeCardChanged("recorder", func(event *CardChangedEvent) {
+		subscriber.record(event.CardID, event, event.Actor)
+	})
+	events.SubscribeCardArchived("recorder", func(event *CardArchivedEvent) {
+		subscriber.record(event.CardID, event, event.Actor)
+	})
+	events.SubscribeCardDeleted("recorder", func(event *CardDeletedEvent) {
+		subscriber.record(event.CardID, event, event.Actor)
+	})
+
+	want := map[int][]string{}
+	for i := 0; i < 20; i++ {
+		for cardID := 1; cardID <= 3; cardID++ {
+			actor := fmt.Sprint(i)
+			var event Event
+			switch i % 4 {
+			case 0:
+				event = events.PublishCardMoved(actor, cardID, Cell{ColumnID: 1, LaneID: 1}, Cell{ColumnID: 2, LaneID: 1})
+			case 1:
+				event = events.PublishCardChanged(actor, Card{ID: cardID})
+			case 2:
+				event = events.PublishCardArchived(actor, cardID, Cell{ColumnID: 2, LaneID: 1})
+			default:
+				event = events.PublishCardDeleted(actor, cardID, Cell{ColumnID: 2, LaneID: 1})
+			}
+			want[cardID] = append(want[cardID], event.Key()+" "+actor)
+		}
+	}
+	events.Wait()
+
+	for cardID, wantSeen := range want {
+		if got := subscriber.seen[cardID]; !slices.Equal(got, wantSeen) {
+			t.Errorf("card %d events arrived as\n%v\nwant\n%v", cardID, got, wantSeen)
+		}
+	}
+}
+
+func TestEventServiceSlowSubscriberDoesNotHoldUpOthers(t *testing.T) {
+	events := NewEventService(newTestLogger())
+
+	release := make(chan struct{})
+	events.SubscribeCardChanged("slow", func(event *CardChangedEvent) {
+		<-release
+	})
+	received := make(chan int, 2)
+	events.SubscribeCardChanged("fast", func(event *CardChangedEvent) {
+		received <- event.CardID
+	})
+
+	events.PublishCardChanged("alice", Card{ID: 1})
+	events.PublishCardChanged("alice", Card{ID: 1})
+	for i := 0; i < 2; i++ {
+		select {
+		case <-received:
+		case <-time.After(2 * time.Second):
+			t.Fatal("the fast subscriber waited on the slow one")
+		}
+	}
+	close(release)
+	events.Wait()
+}
+
+func TestEventServiceCarriesOnAfterPanic(t *testing.T) {
+	events := NewEventService(newTestLogger())
+
+	var mu sync.Mutex
+	var changed []int
+	events.SubscribeCardChanged("fragile", func(event *CardChangedEvent) {
+		if event.CardID == 1 {
+			panic("subscriber failed")
+		}
+		mu.Lock()
+		changed = append(changed, event.CardID)
+		mu.Unlock()
+	})
+
+	events.PublishCardChanged("alice", Card{ID: 1})
+	events.PublishCardChanged("alice", Card{ID: 2})
+	events.Wait()
+
+	if !slices.Equal(changed, []int{2}) {
+		t.Errorf("changed = %v, want the event after the panic delivered", changed)
+	}
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type DueWindow string
+
+const (
+	DueAny      DueWindow = ""
+	DueOverdue  DueWindow = "overdue"
+	DueToday    DueWindow = "today"
+	DueThisWeek DueWindow = "week"
+	DueNone     DueWindow = "none"
+)
+
+// DueWindows lists the due windows in the order they're offered to users
+var DueWindows = []DueWindow{DueAny, DueOverdue, DueToday, DueThisWeek, DueNone}
+
+func (d DueWindow) Label() string {
+	switch d {
+	case DueOverdue:
+		return "Overdue"
+	case DueToday:
+		return "Due today"
+	case DueThisWeek:
+		return "Due this week"
+	case DueNone:
+		return "No due date"
+	default:
+		return "Any due date"
+	}
+}
+
+// Filter narrows the board down to the cards matching every one of its criteria
+type Filter struct {
+	ID       int
+	Name     string
+	Text     string
+	Labels   []string
+	Assignee string
+	Due      DueWindow
+}
+
+func (f *Filter) IsEmpty() bool {
+	return f.Text == "" && len(f.Labels) == 0 && f.Assignee == "" && f.Due == DueAny
+}
+
+// filterKey scopes filters to a session's view of one board
+type filterKey struct {
+	SessionID string
+	BoardID   int
+}
+
+// FilterService keeps each session's saved filters and the filter applied to its board view
+type FilterService struct {
+	mu     sync.RWMutex
+	saved  map[filterKey][]Filter
+	active map[filterKey]Filter
+
+	nextFilterID int
+
+	log         *slog.Logger
+	cardService *CardService
+}
+
+func NewFilterService(log *slog.Logger, cardService *CardService) *FilterService {
+	return &FilterService{
+		saved:        make(map[filterKey][]Filter),
+		active:       make(map[filterKey]Filter),
+		nextFilterID: 1,
+		log:          log,
+		cardService:  cardService,
+	}
+}
+
+// GetActive returns the filter applied to the session's view of the board, or nil if it sees every card
+func (f *FilterService) GetActive(sessionID string, boardID int) *Filter {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	filter, exists := f.active[filterKey{sessionID, boardID}]
+	if !exists {
+		return nil
+	}
+	filter.Labels = slices.Clone(filter.Labels)
+	return &filter
+}
+
+func (f *FilterService) SetActive(sessionID string, boardID int, filter Filter) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := filterKey{sessionID, boardID}
+	if filter.IsEmpty() {
+		delete(f.active, key)
+		return
+	}
+	f.active[key] = filter
+}
+
+func (f *FilterService) ClearActive(sessionID string, boardID int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.active, filterKey{sessionID, boardID})
+}
+
+// SaveFilter stores a named filter, replacing any existing filter with the same name
+func (f *FilterService) SaveFilter(sessionID string, boardID int, filter Filter) (*Filter, error) {
+	if filter.Name == "" {
+		return nil, fmt.Errorf("filter name is required")
+	}
+	if filter.IsEmpty() {
+		return nil, fmt.Errorf("filter has no criteria")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := filterKey{sessionID, boardID}
+	filters := f.saved[key]
+	for i, existing := range filters {
+		if strings.EqualFold(existing.Name, filter.Name) {
+			filter.ID = existing.ID
+			filters[i] = filter
+			return &filter, nil
+		}
+	}
+
+	filter.ID = f.nextFilterID
+	f.nextFilterID++
+	f.saved[key] = append(filters, filter)
+
+	f.log.Info("Saved filter", "sessionID", sessionID, "boardID", boardID, "filterID", filter.ID, "name", filter.Name)
+	return &filter, nil
+}
+
+func (f *FilterService) GetSavedFilters(sessionID string, boardID int) []Filter {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return slices.Clone(f.saved[filterKey{sessionID, boardID}])
+}
+
+func (f *FilterService) GetSavedFilter(sessionID string, boardID, filterID int) (*Filter, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	for _, filter := range f.saved[filterKey{sessionID, boardID}] {
+		if filter.ID == filterID {
+			return &filter, nil
+		}
+	}
+	return nil, fmt.Errorf("filter with ID %d not found", filterID)
+}
+
+func (f *FilterService) DeleteSavedFilter(sessionID string, boardID, filterID int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := filterKey{sessionID, boardID}
+	filters := f.saved[key]
+	for i, filter := range filters {
+		if filter.ID == filterID {
+			f.saved[key] = slices.Delete(filters, i, i+1)
+			return nil
+		}
+	}
+	return fmt.Errorf("filter with ID %d not found", filterID)
+}
+
+// Apply returns the cards matching the filter, keeping their order; a nil filter matches everything
+func (f *FilterService) Apply(filter *Filter, boardID int, cards []Card) []Card {
+	if filter == nil || filter.IsEmpty() {
+		return cards
+	}
+
+	var textMatches map[int]bool
+	if filter.Text != "" {
+		textMatches = f.cardService.MatchCards(boardID, filter.Text)
+	}
+
+	now := time.Now()
+	var result []Card
+	for _, card := range cards {
+		if textMatches != nil && !textMatches[card.ID] {
+			continue
+		}
+		if matchesFilter(filter, &card, now) {
+			result = append(result, card)
+		}
+	}
+	return result
+}
+
+func matchesFilter(filter *Filter, card *Card, now time.Time) bool {
+	for _, label := range filter.Labels {
+		if !slices.ContainsFunc(card.Labels, func(cardLabel string) bool {
+			return strings.EqualFold(cardLabel, label)
+		}) {
+			return false
+		}
+	}
+
+	if filter.Assignee != "" && !strings.EqualFold(card.Assignee, filter.Assignee) {
+		return false
+	}
+
+	return matchesDueWindow(filter.Due, card, now)
+}
+
+func matchesDueWindow(window DueWindow, card *Card, now time.Time) bool {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch window {
+	case DueOverdue:
+		return card.IsOverdue(now)
+	case DueToday:
+		return card.HasDueDate() && !card.DueAt.Before(today) && card.DueAt.Before(today.AddDate(0, 0, 1))
+	case DueThisWeek:
+		return card.HasDueDate() && !card.DueAt.Before(today) && card.DueAt.Before(today.AddDate(0, 0, 7))
+	case DueNone:
+		return !card.HasDueDate()
+	default:
+		return true
+	}
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DailySnapshot is how many cards were in each of a board's columns at the end of a day. Archived cards count in
+// the column they were archived from, so that finished work stays finished; deleted cards don't count.
+type DailySnapshot struct {
+	Day    time.Time
+	Counts map[int]int // columnID -> cards
+}
+
+// WeeklyThroughput is how many cards first reached a board's last column in the week starting on Monday Week
+type WeeklyThroughput struct {
+	Week time.Time
+	Done int
+}
+
+// FlowService keeps daily snapshots of every board's columns and its weekly throughput. Both are rebuilt from the
+// card history, so they cover everything since the board was created and not just since the server started.
+type FlowService struct {
+	mu         sync.Mutex
+	snapshots  map[int][]DailySnapshot    // boardID -> snapshots, a day apart, oldest first
+	throughput map[int][]WeeklyThroughput // boardID -> weeks with cards done, oldest first
+	builtFrom  int                        // how many events the snapshots were built from
+	builtOn    time.Time                  // the day they were built
+
+	log         *slog.Logger
+	cardService *CardService
+}
+
+func NewFlowService(log *slog.Logger, cardService *CardService) *FlowService {
+	f := &FlowService{
+		snapshots:   make(map[int][]DailySnapshot),
+		throughput:  make(map[int][]WeeklyThroughput),
+		log:         log,
+		cardService: cardService,
+	}
+	f.Rebuild(time.Now())
+	return f
+}
+
+// refresh rebuilds the snapshots if anything has happened, or a day has passed, since they were last built
+func (f *FlowService) refresh(now time.Time) {
+	f.mu.Lock()
+	current := f.builtFrom == f.cardService.historyLength() && f.builtOn.Equal(startOfDay(now))
+	f.mu.Unlock()
+
+	if !current {
+		f.Rebuild(now)
+	}
+}
+
+// startOfDay is midnight at the start of the local day
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// startOfWeek is midnight at the start of the local Monday
+func startOfWeek(t time.Time) time.Time {
+	day := startOfDay(t)
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+// Rebuild replays the card history a day at a time, snapshotting each board at the end of every day up to today
+func (f *FlowService) Rebuild(now time.Time) {
+	history := f.cardService.History()
+	replay := newCardService(f.log, nil, f.cardService.wordService)
+
+	snapshots := make(map[int][]DailySnapshot)
+	weeks := make(map[int]map[time.Time]int) // boardID -> week -> cards done
+	reachedLast := make(map[int]bool)        // cardIDs that have been counted as done
+
+	var day time.Time
+	snapshotUntil := func(end time.Time) {
+		for ; day.Before(end); day = day.AddDate(0, 0, 1) {
+			for boardID, counts := range replay.countCards() {
+				snapshots[boardID] = append(snapshots[boardID], DailySnapshot{Day: day, Counts: counts})
+			}
+		}
+	}
+
+	for _, recorded := range history {
+		if day.IsZero() {
+			day = startOfDay(recorded.Time)
+		}
+		snapshotUntil(startOfDay(recorded.Time))
+		recorded.Event.apply(replay)
+
+		if cardID, columnID, entered := enteredColumn(recorded.Event); entered && !reachedLast[cardID] {
+			if boardID, isLast := replay.isLastColumn(columnID); isLast {
+				reachedLast[cardID] = true
+				if weeks[boardID] == nil {
+					weeks[boardID] = make(map[time.Time]int)
+				}
+				weeks[boardID][startOfWeek(recorded.Time)]++
+			}
+		}
+	}
+	if !day.IsZero() {
+		snapshotUntil(startOfDay(now).AddDate(0, 0, 1))
+	}
+
+	throughput := make(map[int][]WeeklyThroughput)
+	for boardID, counts := range weeks {
+		for _, week := range slices.SortedFunc(maps.Keys(counts), time.Time.Compare) {
+			throughput[boardID] = append(throughput[boardID], WeeklyThroughput{Week: week, Done: counts[week]})
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.snapshots = snapshots
+	f.throughput = throughput
+	f.builtFrom = len(history)
+	f.builtOn = startOfDay(now)
+	f.log.Debug("Rebuilt flow snapshots", "events", len(history))
+}
+
+// enteredColumn says which column a card was put into by the event, if any
+func enteredColumn(event DomainEvent) (int, int, bool) {
+	switch event := event.(type) {
+	case *CardCreated:
+		return event.Card.ID, event.Card.ColumnID, true
+	case *CardMoved:
+		return event.CardID, event.To.ColumnID, event.From.ColumnID != event.To.ColumnID
+	default:
+		return 0, 0, false
+	}
+}
+
+// countCards counts the cards in each column of each board, including archived ones
+func (c *CardService) countCards() map[int]map[int]int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	counts := make(map[int]map[int]int) // boardID -> columnID -> cards
+	for _, column := range c.columns {
+		if counts[column.BoardID] == nil {
+			counts[column.BoardID] = make(map[int]int)
+		}
+		counts[column.BoardID][column.ID] = 0
+	}
+	for _, card := range c.cards {
+		if column, exists := c.columns[card.ColumnID]; exists {
+			counts[column.BoardID][column.ID]++
+		}
+	}
+	return counts
+}
+
+// isLastColumn says whether the column comes last on its board by order, and which board that is
+func (c *CardService) isLastColumn(columnID int) (int, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	column, exists := c.columns[columnID]
+	if !exists {
+		return 0, false
+	}
+	columns := c.getSortedColumns(column.BoardID)
+	return column.BoardID, columns[len(columns)-1].ID == columnID
+}
+
+// GetSnapshots returns the board's daily snapshots from the day of from up to the day before to, with today's as
+// the board is now
+func (f *FlowService) GetSnapshots(boardID int, from, to time.Time) []DailySnapshot {
+	f.refresh(time.Now())
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var snapshots []DailySnapshot
+	for _, snapshot := range f.snapshots[boardID] {
+		if snapshot.Day.Before(startOfDay(from)) || !snapshot.Day.Before(to) {
+			continue
+		}
+		snapshots = append(snapshots, DailySnapshot{Day: snapshot.Day, Counts: maps.Clone(snapshot.Counts)})
+	}
+	return snapshots
+}
+
+// GetThroughput returns the cards done in each week overlapping the range, including weeks when none were
+func (f *FlowService) GetThroughput(boardID int, from, to time.Time) []WeeklyThroughput {
+	f.refresh(time.Now())
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	done := make(map[time.Time]int)
+	for _, week := range f.throughput[boardID] {
+		done[week.Week] = week.Done
+	}
+
+	var weeks []WeeklyThroughput
+	for week := startOfWeek(from); week.Before(to); week = week.AddDate(0, 0, 7) {
+		weeks = append(weeks, WeeklyThroughput{Week: week, Done: done[week]})
+	}
+	return weeks
+}
@@ -0,0 +1,507 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DomainEvent is a change to boards, columns, lanes or cards carrying everything needed to make the change again.
+// CardService's state is nothing more than its history of domain events applied in order, which is what makes
+// replaying it, persisting it and looking back at the board as it was possible.
+type DomainEvent interface {
+	Kind() string
+	// apply changes the state; the caller holds the lock and the event has already been validated
+	apply(c *CardService)
+}
+
+const (
+	BoardCreatedKind   = "board-created"
+	ColumnCreatedKind  = "column-created"
+	WIPLimitSetKind    = "wip-limit-set"
+	LaneCreatedKind    = "lane-created"
+	LaneDeletedKind    = "lane-deleted"
+	CardCreatedKind    = "card-created"
+	CardUpdatedKind    = "card-updated"
+	CardMovedKind      = "card-moved"
+	CardDeletedKind    = "card-deleted"
+	CardRestoredKind   = "card-restored"
+	CardPurgedKind     = "card-purged"
+	CardArchivedKind   = "card-archived"
+	CardUnarchivedKind = "card-unarchived"
+	CommentAddedKind   = "comment-added"
+)
+
+// domainEventTypes makes an empty event of each kind, for decoding stored history
+var domainEventTypes = map[string]func() DomainEvent{
+	BoardCreatedKind:   func() DomainEvent { return &BoardCreated{} },
+	ColumnCreatedKind:  func() DomainEvent { return &ColumnCreated{} },
+	WIPLimitSetKind:    func() DomainEvent { return &WIPLimitSet{} },
+	LaneCreatedKind:    func() DomainEvent { return &LaneCreated{} },
+	LaneDeletedKind:    func() DomainEvent { return &LaneDeleted{} },
+	CardCreatedKind:    func() DomainEvent { return &CardCreated{} },
+	CardUpdatedKind:    func() DomainEvent { return &CardUpdated{} },
+	CardMovedKind:      func() DomainEvent { return &CardMoved{} },
+	CardDeletedKind:    func() DomainEvent { return &CardDeleted{} },
+	CardRestoredKind:   func() DomainEvent { return &CardRestored{} },
+	CardPurgedKind:     func() DomainEvent { return &CardPurged{} },
+	CardArchivedKind:   func() DomainEvent { return &CardArchived{} },
+	CardUnarchivedKind: func() DomainEvent { return &CardUnarchived{} },
+	CommentAddedKind:   func() DomainEvent { return &CommentAdded{} },
+}
+
+// RecordedEvent is a domain event as it was recorded, numbered from 1 in the order it was applied
+type RecordedEvent struct {
+	Sequence int
+	Time     time.Time
+	Event    DomainEvent
+}
+
+type recordedEventJSON struct {
+	Sequence int             `json:"sequence"`
+	Time     time.Time       `json:"time"`
+	Kind     string          `json:"kind"`
+	Event    json.RawMessage `json:"event"`
+}
+
+func (r RecordedEvent) MarshalJSON() ([]byte, error) {
+	event, err := json.Marshal(r.Event)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(recordedEventJSON{
+		Sequence: r.Sequence,
+		Time:     r.Time,
+		Kind:     r.Event.Kind(),
+		Event:    event,
+	})
+}
+
+func (r *RecordedEvent) UnmarshalJSON(data []byte) error {
+	var raw recordedEventJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	newEvent, exists := domainEventTypes[raw.Kind]
+	if !exists {
+		return fmt.Errorf("unknown event kind %q", raw.Kind)
+	}
+	event := newEvent()
+	if err := json.Unmarshal(raw.Event, event); err != nil {
+		return fmt.Errorf("event %d (%s): %w", raw.Sequence, raw.Kind, err)
+	}
+
+	r.Sequence = raw.Sequence
+	r.Time = raw.Time
+	r.Event = event
+	return nil
+}
+
+type BoardCreated struct {
+	Board Board
+}
+
+func (e *BoardCreated) Kind() string { return BoardCreatedKind }
+
+func (e *BoardCreated) apply(c *CardService) {
+	board := e.Board
+	c.boards[board.ID] = &board
+	c.nextBoardID = max(c.nextBoardID, board.ID+1)
+}
+
+type ColumnCreated struct {
+	Column Column
+}
+
+func (e *ColumnCreated) Kind() string { return ColumnCreatedKind }
+
+func (e *ColumnCreated) apply(c *CardService) {
+	column := e.Column
+	c.columns[column.ID] = &column
+	c.nextColumnID = max(c.nextColumnID, column.ID+1)
+}
+
+type WIPLimitSet struct {
+	ColumnID int
+	Limit    int
+	Mode     WIPMode
+}
+
+func (e *WIPLimitSet) Kind() string { return WIPLimitSetKind }
+
+func (e *WIPLimitSet) apply(c *CardService) {
+	if column, exists := c.columns[e.ColumnID]; exists {
+		column.WIPLimit = e.Limit
+		column.WIPMode = e.Mode
+	}
+}
+
+type LaneCreated struct {
+	Lane Lane
+}
+
+func (e *LaneCreated) Kind() string { return LaneCreatedKind }
+
+func (e *LaneCreated) apply(c *CardService) {
+	lane := e.Lane
+	c.lanes[lane.ID] = &lane
+	c.nextLaneID = max(c.nextLaneID, lane.ID+1)
+}
+
+type LaneDeleted struct {
+	Lane Lane
+}
+
+func (e *LaneDeleted) Kind() string { return LaneDeletedKind }
+
+func (e *LaneDeleted) apply(c *CardService) {
+	delete(c.lanes, e.Lane.ID)
+	for _, column := range c.getSortedColumns(e.Lane.BoardID) {
+		delete(c.cellCards, Cell{ColumnID: column.ID, LaneID: e.Lane.ID})
+	}
+
+	// Close the gap so that lane order stays contiguous
+	for _, other := range c.lanes {
+		if other.BoardID == e.Lane.BoardID && other.Order > e.Lane.Order {
+			other.Order--
+		}
+	}
+}
+
+// CardCreated adds the card at Position within its cell
+type CardCreated struct {
+	Card     Card
+	Position int
+}
+
+func (e *CardCreated) Kind() string { return CardCreatedKind }
+
+func (e *CardCreated) apply(c *CardService) {
+	card := e.Card
+	card.Labels = slices.Clone(card.Labels)
+	c.cards[card.ID] = &card
+	if !card.IsArchived() {
+		c.insertCardInCell(card.ID, card.Cell(), e.Position)
+	}
+	c.index.Index(&card, c.comments[card.ID])
+	c.nextCardID = max(c.nextCardID, card.ID+1)
+}
+
+type CardUpdated struct {
+	CardID int
+	Before CardDetails
+	After  CardDetails
+}
+
+func (e *CardUpdated) Kind() string { return CardUpdatedKind }
+
+func (e *CardUpdated) apply(c *CardService) {
+	if card, exists := c.cards[e.CardID]; exists {
+		details := e.After
+		details.Labels = slices.Clone(details.Labels)
+		card.setDetails(details)
+		c.index.Index(card, c.comments[card.ID])
+	}
+}
+
+type CardMoved struct {
+	CardID       int
+	From         Cell
+	FromPosition int
+	To           Cell
+	ToPosition   int
+}
+
+func (e *CardMoved) Kind() string { return CardMovedKind }
+
+func (e *CardMoved) apply(c *CardService) {
+	card, exists := c.cards[e.CardID]
+	if !exists {
+		return
+	}
+	c.removeCardFromCell(card.ID, e.From)
+	c.insertCardInCell(card.ID, e.To, e.ToPosition)
+	card.ColumnID = e.To.ColumnID
+	card.LaneID = e.To.LaneID
+}
+
+// CardDeleted moves the card, as it was when deleted, to the trash
+type CardDeleted struct {
+	Card      Card
+	Position  int
+	DeletedAt time.Time
+}
+
+func (e *CardDeleted) Kind() string { return CardDeletedKind }
+
+func (e *CardDeleted) apply(c *CardService) {
+	c.removeCardFromCell(e.Card.ID, e.Card.Cell())
+	delete(c.cards, e.Card.ID)
+	c.index.Remove(e.Card.ID)
+
+	card := e.Card
+	card.Labels = slices.Clone(card.Labels)
+	c.trash[card.ID] = &TrashedCard{
+		Card:      card,
+		Position:  e.Position,
+		DeletedAt: e.DeletedAt,
+	}
+}
+
+// CardRestored takes the card out of the trash into Cell, which differs from where it was if its lane has gone
+type CardRestored struct {
+	CardID   int
+	Cell     Cell
+	Position int
+}
+
+func (e *CardRestored) Kind() string { return CardRestoredKind }
+
+func (e *CardRestored) apply(c *CardService) {
+	trashedCard, exists := c.trash[e.CardID]
+	if !exists {
+		return
+	}
+
+	card := trashedCard.Card
+	card.ColumnID = e.Cell.ColumnID
+	card.LaneID = e.Cell.LaneID
+	c.cards[card.ID] = &card
+	if !card.IsArchived() {
+		c.insertCardInCell(card.ID, card.Cell(), e.Position)
+	}
+	c.index.Index(&card, c.comments[card.ID])
+	delete(c.trash, card.ID)
+}
+
+type CardPurged struct {
+	CardID int
+}
+
+func (e *CardPurged) Kind() string { return CardPurgedKind }
+
+func (e *CardPurged) apply(c *CardService) {
+	delete(c.trash, e.CardID)
+	delete(c.comments, e.CardID)
+}
+
+type CardArchived struct {
+	CardID     int
+	Cell       Cell
+	Position   int
+	ArchivedAt time.Time
+}
+
+func (e *CardArchived) Kind() string { return CardArchivedKind }
+
+func (e *CardArchived) apply(c *CardService) {
+	if card, exists := c.cards[e.CardID]; exists {
+		c.removeCardFromCell(card.ID, card.Cell())
+		card.ArchivedAt = e.ArchivedAt
+	}
+}
+
+// CardUnarchived returns the card to Position in its cell
+type CardUnarchived struct {
+	CardID   int
+	Position int
+}
+
+func (e *CardUnarchived) Kind() string { return CardUnarchivedKind }
+
+func (e *CardUnarchived) apply(c *CardService) {
+	if card, exists := c.cards[e.CardID]; exists {
+		c.insertCardInCell(card.ID, card.Cell(), e.Position)
+		card.ArchivedAt = time.Time{}
+	}
+}
+
+type CommentAdded struct {
+	Comment Comment
+}
+
+func (e *CommentAdded) Kind() string { return CommentAddedKind }
+
+func (e *CommentAdded) apply(c *CardService) {
+	c.comments[e.Comment.CardID] = append(c.comments[e.Comment.CardID], e.Comment)
+	c.nextCommentID = max(c.nextCommentID, e.Comment.ID+1)
+	if card, exists := c.cards[e.Comment.CardID]; exists {
+		c.index.Index(card, c.comments[card.ID])
+	}
+}
+
+// record applies the event and appends it to the history; the caller holds the lock
+func (c *CardService) record(event DomainEvent, now time.Time) {
+	event.apply(c)
+	c.history = append(c.history, RecordedEvent{
+		Sequence: len(c.history) + 1,
+		Time:     now,
+		Event:    event,
+	})
+}
+
+// History returns every domain event recorded so far, oldest first
+func (c *CardService) History() []RecordedEvent {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return slices.Clone(c.history)
+}
+
+func (c *CardService) historyLength() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return len(c.history)
+}
+
+// HistoryUntil returns the domain events recorded up to and including the given time
+func (c *CardService) HistoryUntil(until time.Time) []RecordedEvent {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	end, _ := slices.BinarySearchFunc(c.history, until, func(event RecordedEvent, until time.Time) int {
+		if event.Time.After(until) {
+			return 1
+		}
+		return -1
+	})
+	return slices.Clone(c.history[:end])
+}
+
+// ReplayCardService builds a card service whose state is the given history applied in order, for restoring
+// persisted state or looking at the board as it was. Mutating it carries on from the end of the history.
+func ReplayCardService(
+	log *slog.Logger,
+	eventService *EventService,
+	wordService *WordService,
+	history []RecordedEvent,
+) (*CardService, error) {
+	service := newCardService(log, eventService, wordService)
+	for i, recorded := range history {
+		if recorded.Sequence != i+1 {
+			return nil, fmt.Errorf("event %d is out of sequence, expected %d", recorded.Sequence, i+1)
+		}
+		if recorded.Event == nil {
+			return nil, fmt.Errorf("event %d is missing", recorded.Sequence)
+		}
+		recorded.Event.apply(service)
+		service.history = append(service.history, recorded)
+	}
+	return service, nil
+}
+
+// AsOf replays the history up to the given time, giving the boards as they were then. The result is for reading
+// only: it has no event service, so nothing would hear about changes made to it, and it may be shared with other
+// callers looking at the same point in the history.
+func (c *CardService) AsOf(at time.Time) (*CardService, error) {
+	history := c.HistoryUntil(at)
+	if replay, cached := c.replays.get(len(history)); cached {
+		return replay, nil
+	}
+
+	replay, err := ReplayCardService(c.log, nil, c.wordService, history)
+	if err != nil {
+		return nil, err
+	}
+	c.replays.put(len(history), replay)
+	return replay, nil
+}
+
+// replayCacheSize is how many points in the history AsOf keeps the boards for
+const replayCacheSize = 32
+
+// replayCache keeps replays of the first so many events of the history. History is only ever appended to, so a
+// replay never goes out of date.
+type replayCache struct {
+	mu      sync.Mutex
+	replays map[int]*CardService // number of events -> the boards after them
+	order   []int                // numbers of events, least recently used first
+}
+
+func (r *replayCache) get(events int) (*CardService, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	replay, exists := r.replays[events]
+	if exists {
+		r.order = append(slices.DeleteFunc(r.order, func(n int) bool { return n == events }), events)
+	}
+	return replay, exists
+}
+
+func (r *replayCache) put(events int, replay *CardService) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.replays == nil {
+		r.replays = make(map[int]*CardService)
+	}
+	if _, exists := r.replays[events]; !exists {
+		r.order = append(r.order, events)
+	}
+	r.replays[events] = replay
+	if len(r.order) > replayCacheSize {
+		delete(r.replays, r.order[0])
+		r.order = r.order[1:]
+	}
+}
+
+// BoardHistory returns the domain events that changed the board, oldest first
+func (c *CardService) BoardHistory(boardID int) []RecordedEvent {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	columnBoards := make(map[int]int) // columnID -> boardID
+	cardBoards := make(map[int]int)   // cardID -> boardID
+	var history []RecordedEvent
+	for _, recorded := range c.history {
+		if eventBoard(recorded.Event, columnBoards, cardBoards) == boardID {
+			history = append(history, recorded)
+		}
+	}
+	return history
+}
+
+// eventBoard says which board the event changed, learning the boards of columns and cards as they're created.
+// Cards never move between boards, so the board a card was created on is the board of everything that happens to it.
+func eventBoard(event DomainEvent, columnBoards, cardBoards map[int]int) int {
+	switch event := event.(type) {
+	case *BoardCreated:
+		return event.Board.ID
+	case *ColumnCreated:
+		columnBoards[event.Column.ID] = event.Column.BoardID
+		return event.Column.BoardID
+	case *WIPLimitSet:
+		return columnBoards[event.ColumnID]
+	case *LaneCreated:
+		return event.Lane.BoardID
+	case *LaneDeleted:
+		return event.Lane.BoardID
+	case *CardCreated:
+		cardBoards[event.Card.ID] = columnBoards[event.Card.ColumnID]
+		return cardBoards[event.Card.ID]
+	case *CardUpdated:
+		return cardBoards[event.CardID]
+	case *CardMoved:
+		return cardBoards[event.CardID]
+	case *CardDeleted:
+		return cardBoards[event.Card.ID]
+	case *CardRestored:
+		return cardBoards[event.CardID]
+	case *CardPurged:
+		return cardBoards[event.CardID]
+	case *CardArchived:
+		return cardBoards[event.CardID]
+	case *CardUnarchived:
+		return cardBoards[event.CardID]
+	case *CommentAdded:
+		return cardBoards[event.Comment.CardID]
+	default:
+		return 0
+	}
+}
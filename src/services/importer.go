@@ -0,0 +1,514 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ImportIssue is something that went wrong with one card of an import from another tool
+type ImportIssue struct {
+	Card   string // the card's title, or where it was found if it has none
+	Reason string
+}
+
+// ImportSummary is what became of an import from another tool. Skipped cards weren't imported at all; flagged
+// ones were, but with something changed to make them fit, like prohibited words or labels over the limit dropped.
+type ImportSummary struct {
+	Board    *Board
+	Imported int
+	Skipped  []ImportIssue
+	Flagged  []ImportIssue
+}
+
+func (s *ImportSummary) skip(card, format string, args ...any) {
+	s.Skipped = append(s.Skipped, ImportIssue{Card: card, Reason: fmt.Sprintf(format, args...)})
+}
+
+func (s *ImportSummary) flag(card, format string, args ...any) {
+	s.Flagged = append(s.Flagged, ImportIssue{Card: card, Reason: fmt.Sprintf(format, args...)})
+}
+
+// ImportConverted creates a new board from a document converted from another tool, adding to the converter's
+// summary. Unlike ImportBoard, which refuses a document with anything wrong in it, each card is made to fit where
+// it can and skipped where it can't, with everything done to it reported in the summary.
+func (c *CardService) ImportConverted(document *BoardDocument, summary *ImportSummary) error {
+	cleaned := c.cleanDocument(document, summary)
+	if len(cleaned.Board.Columns) == 0 {
+		return fmt.Errorf("there are no columns to import")
+	}
+
+	board, err := c.ImportBoard(cleaned)
+	if err != nil {
+		return err
+	}
+	summary.Board = board
+	summary.Imported = len(cleaned.Board.Cards)
+	return nil
+}
+
+// cleanDocument makes a copy of the document that ImportBoard will accept, noting every change in the summary
+func (c *CardService) cleanDocument(document *BoardDocument, summary *ImportSummary) *BoardDocument {
+	cleaned := &BoardDocument{
+		Version: BoardDocumentVersion,
+		Board: BoardSnapshot{
+			Title: c.cleanName(document.Board.Title, "Imported board"),
+		},
+	}
+
+	columnIDs := make(map[int]bool)
+	for i, column := range document.Board.Columns {
+		if columnIDs[column.ID] {
+			continue
+		}
+		columnIDs[column.ID] = true
+		column.Title = c.cleanName(column.Title, fmt.Sprintf("Column %d", i+1))
+		if column.WIPLimit < 0 {
+			column.WIPLimit = 0
+		}
+		cleaned.Board.Columns = append(cleaned.Board.Columns, column)
+	}
+
+	laneIDs := make(map[int]bool)
+	for i, lane := range document.Board.Lanes {
+		if laneIDs[lane.ID] {
+			continue
+		}
+		laneIDs[lane.ID] = true
+		lane.Title = c.cleanName(lane.Title, fmt.Sprintf("Lane %d", i+1))
+		cleaned.Board.Lanes = append(cleaned.Board.Lanes, lane)
+	}
+	if len(cleaned.Board.Lanes) == 0 {
+		cleaned.Board.Lanes = []LaneSnapshot{{ID: 1, Title: "Cards"}}
+		laneIDs[1] = true
+	}
+
+	columnTitles := make(map[int]string) // column ID -> title
+	for _, column := range cleaned.Board.Columns {
+		columnTitles[column.ID] = column.Title
+	}
+	for _, card := range document.Board.Cards {
+		name := strings.TrimSpace(card.Title)
+		if name == "" {
+			summary.skip(fmt.Sprintf("untitled card in %s", columnTitles[card.ColumnID]), "it has no title")
+			continue
+		}
+		if !columnIDs[card.ColumnID] {
+			summary.skip(name, "its column isn't being imported")
+			continue
+		}
+		if !laneIDs[card.LaneID] {
+			card.LaneID = cleaned.Board.Lanes[0].ID
+		}
+		if card, ok := c.cleanCard(name, card, summary); ok {
+			cleaned.Board.Cards = append(cleaned.Board.Cards, card)
+		}
+	}
+	return cleaned
+}
+
+// cleanName fits a board, column or lane title, falling back when there's nothing usable left of it
+func (c *CardService) cleanName(title, fallback string) string {
+	title = truncate(strings.TrimSpace(title), 100)
+	if title == "" || c.wordService.Filter(title) != "" {
+		return fallback
+	}
+	return title
+}
+
+// cleanCard fits the card to the limits the card form has, or says it can't be imported
+func (c *CardService) cleanCard(name string, card CardSnapshot, summary *ImportSummary) (CardSnapshot, bool) {
+	if word := c.wordService.Filter(name); word != "" {
+		summary.skip(name, "its title contains the prohibited word %q", word)
+		return card, false
+	}
+	card.Title = name
+	if len(card.Title) > 100 {
+		card.Title = truncate(card.Title, 100)
+		summary.flag(name, "title shortened to 100 characters")
+	}
+
+	if word := c.wordService.Filter(card.Content); word != "" {
+		card.Content = ""
+		summary.flag(name, "description dropped for the prohibited word %q", word)
+	} else if len(card.Content) > 1000 {
+		card.Content = truncate(card.Content, 1000)
+		summary.flag(name, "description shortened to 1000 characters")
+	}
+
+	card.Assignee = strings.TrimSpace(card.Assignee)
+	if word := c.wordService.Filter(card.Assignee); word != "" {
+		card.Assignee = ""
+		summary.flag(name, "assignee dropped for the prohibited word %q", word)
+	} else if len(card.Assignee) > 50 {
+		card.Assignee = truncate(card.Assignee, 50)
+		summary.flag(name, "assignee shortened to 50 characters")
+	}
+
+	var labels []string
+	for _, label := range card.Labels {
+		label = strings.TrimSpace(label)
+		switch {
+		case label == "" || slices.Contains(labels, label):
+			continue
+		case len(label) > 30:
+			summary.flag(name, "label %q dropped for being longer than 30 characters", label)
+		case c.wordService.Filter(label) != "":
+			summary.flag(name, "label dropped for the prohibited word %q", c.wordService.Filter(label))
+		case len(labels) == 10:
+			summary.flag(name, "label %q dropped, as cards can have at most 10", label)
+		default:
+			labels = append(labels, label)
+		}
+	}
+	card.Labels = labels
+
+	if card.DueAt != "" {
+		if _, err := time.Parse(DueDateLayout, card.DueAt); err != nil {
+			summary.flag(name, "due date %q isn't a date", card.DueAt)
+			card.DueAt = ""
+		}
+	}
+	if card.ArchivedAt != "" {
+		if _, err := time.Parse(time.RFC3339, card.ArchivedAt); err != nil {
+			card.ArchivedAt = time.Now().UTC().Format(time.RFC3339)
+		}
+	}
+
+	var comments []CommentSnapshot
+	for _, comment := range card.Comments {
+		comment.Body = strings.TrimSpace(comment.Body)
+		if comment.Body == "" {
+			continue
+		}
+		if word := c.wordService.Filter(comment.Body); word != "" {
+			summary.flag(name, "comment dropped for the prohibited word %q", word)
+			continue
+		}
+		if len(comment.Body) > 500 {
+			comment.Body = truncate(comment.Body, 500)
+			summary.flag(name, "comment shortened to 500 characters")
+		}
+		comment.Author = truncate(strings.TrimSpace(comment.Author), 50)
+		if comment.Author == "" || c.wordService.Filter(comment.Author) != "" {
+			comment.Author = "Imported"
+		}
+		if _, err := time.Parse(time.RFC3339, comment.CreatedAt); err != nil {
+			comment.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+		}
+		comments = append(comments, comment)
+	}
+	card.Comments = comments
+	return card, true
+}
+
+// truncate cuts the text to at most limit bytes without splitting a character
+func truncate(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	text = text[:limit]
+	for !utf8.ValidString(text) {
+		text = text[:len(text)-1]
+	}
+	return strings.TrimSpace(text)
+}
+
+// trelloBoard is the part of Trello's JSON board export that gets imported
+type trelloBoard struct {
+	Name   string `json:"name"`
+	Labels []struct {
+		ID    string `json:"id"`
+		Name  string `json:"name"`
+		Color string `json:"color"`
+	} `json:"labels"`
+	Lists []struct {
+		ID     string  `json:"id"`
+		Name   string  `json:"name"`
+		Closed bool    `json:"closed"`
+		Pos    float64 `json:"pos"`
+	} `json:"lists"`
+	Cards []struct {
+		ID               string   `json:"id"`
+		Name             string   `json:"name"`
+		Desc             string   `json:"desc"`
+		IDList           string   `json:"idList"`
+		IDLabels         []string `json:"idLabels"`
+		IDMembers        []string `json:"idMembers"`
+		Due              *string  `json:"due"`
+		Closed           bool     `json:"closed"`
+		Pos              float64  `json:"pos"`
+		DateLastActivity string   `json:"dateLastActivity"`
+	} `json:"cards"`
+	Checklists []struct {
+		IDCard     string  `json:"idCard"`
+		Name       string  `json:"name"`
+		Pos        float64 `json:"pos"`
+		CheckItems []struct {
+			Name  string  `json:"name"`
+			State string  `json:"state"`
+			Pos   float64 `json:"pos"`
+		} `json:"checkItems"`
+	} `json:"checklists"`
+	Members []struct {
+		ID       string `json:"id"`
+		FullName string `json:"fullName"`
+		Username string `json:"username"`
+	} `json:"members"`
+	Actions []struct {
+		Type string `json:"type"`
+		Date string `json:"date"`
+		Data struct {
+			Text string `json:"text"`
+			Card struct {
+				ID string `json:"id"`
+			} `json:"card"`
+		} `json:"data"`
+		MemberCreator struct {
+			FullName string `json:"fullName"`
+		} `json:"memberCreator"`
+	} `json:"actions"`
+}
+
+// ConvertTrelloBoard turns Trello's JSON export of a board into a document for ImportConverted. Lists become
+// columns, in the one lane Trello has; cards keep their labels, first member, due date and comments, and their
+// checklists are added to the description as task lists. Archived lists are left out, and cards on them skipped.
+func ConvertTrelloBoard(data []byte, summary *ImportSummary) (*BoardDocument, error) {
+	var trello trelloBoard
+	if err := json.Unmarshal(data, &trello); err != nil {
+		return nil, fmt.Errorf("not a Trello board export: %w", err)
+	}
+	if len(trello.Lists) == 0 {
+		return nil, fmt.Errorf("not a Trello board export: it has no lists")
+	}
+
+	document := &BoardDocument{
+		Version: BoardDocumentVersion,
+		Board: BoardSnapshot{
+			Title: trello.Name,
+			Lanes: []LaneSnapshot{{ID: 1, Title: "Cards"}},
+		},
+	}
+
+	lists := slices.Clone(trello.Lists)
+	sort.SliceStable(lists, func(i, j int) bool {
+		return lists[i].Pos < lists[j].Pos
+	})
+	columnIDs := make(map[string]int)        // list ID -> column ID
+	archivedLists := make(map[string]string) // list ID -> name
+	for _, list := range lists {
+		if list.Closed {
+			archivedLists[list.ID] = list.Name
+			continue
+		}
+		columnIDs[list.ID] = len(document.Board.Columns) + 1
+		document.Board.Columns = append(document.Board.Columns, ColumnSnapshot{ID: columnIDs[list.ID], Title: list.Name})
+	}
+
+	labelNames := make(map[string]string) // label ID -> name, or colour for unnamed labels
+	for _, label := range trello.Labels {
+		labelNames[label.ID] = label.Name
+		if label.Name == "" {
+			labelNames[label.ID] = label.Color
+		}
+	}
+	memberNames := make(map[string]string) // member ID -> name
+	for _, member := range trello.Members {
+		memberNames[member.ID] = member.FullName
+		if member.FullName == "" {
+			memberNames[member.ID] = member.Username
+		}
+	}
+
+	checklists := make(map[string][]string) // card ID -> markdown, one per checklist
+	sort.SliceStable(trello.Checklists, func(i, j int) bool {
+		return trello.Checklists[i].Pos < trello.Checklists[j].Pos
+	})
+	for _, checklist := range trello.Checklists {
+		items := slices.Clone(checklist.CheckItems)
+		sort.SliceStable(items, func(i, j int) bool {
+			return items[i].Pos < items[j].Pos
+		})
+		lines := []string{"**" + checklist.Name + "**"}
+		for _, item := range items {
+			mark := " "
+			if item.State == "complete" {
+				mark = "x"
+			}
+			lines = append(lines, fmt.Sprintf("- [%s] %s", mark, item.Name))
+		}
+		checklists[checklist.IDCard] = append(checklists[checklist.IDCard], strings.Join(lines, "\n"))
+	}
+
+	comments := make(map[string][]CommentSnapshot) // card ID -> comments, oldest first
+	for _, action := range trello.Actions {
+		if action.Type == "commentCard" {
+			comments[action.Data.Card.ID] = append(comments[action.Data.Card.ID], CommentSnapshot{
+				Author:    action.MemberCreator.FullName,
+				Body:      action.Data.Text,
+				CreatedAt: action.Date,
+			})
+		}
+	}
+	for _, cardComments := range comments {
+		sort.SliceStable(cardComments, func(i, j int) bool {
+			return cardComments[i].CreatedAt < cardComments[j].CreatedAt
+		})
+	}
+
+	cards := slices.Clone(trello.Cards)
+	sort.SliceStable(cards, func(i, j int) bool {
+		return cards[i].Pos < cards[j].Pos
+	})
+	for i, trelloCard := range cards {
+		if list, archived := archivedLists[trelloCard.IDList]; archived {
+			summary.skip(trelloCard.Name, "its list %q is archived", list)
+			continue
+		}
+
+		card := CardSnapshot{
+			ID:       i + 1,
+			Title:    trelloCard.Name,
+			Content:  strings.Join(append([]string{trelloCard.Desc}, checklists[trelloCard.ID]...), "\n\n"),
+			ColumnID: columnIDs[trelloCard.IDList],
+			LaneID:   1,
+			Comments: comments[trelloCard.ID],
+		}
+		card.Content = strings.TrimSpace(card.Content)
+		for _, labelID := range trelloCard.IDLabels {
+			card.Labels = append(card.Labels, labelNames[labelID])
+		}
+		if len(trelloCard.IDMembers) > 0 {
+			card.Assignee = memberNames[trelloCard.IDMembers[0]]
+		}
+		if trelloCard.Due != nil && *trelloCard.Due != "" {
+			if due, err := time.Parse(time.RFC3339, *trelloCard.Due); err == nil {
+				card.DueAt = due.Local().Format(DueDateLayout)
+			} else {
+				card.DueAt = *trelloCard.Due
+			}
+		}
+		if trelloCard.Closed {
+			card.ArchivedAt = trelloCard.DateLastActivity
+		}
+		document.Board.Cards = append(document.Board.Cards, card)
+	}
+	return document, nil
+}
+
+// CSVMapping says which of a CSV file's columns, by header, holds each of a card's fields. Only Title is
+// required; without Column every card goes in the first column of the board, and without Lane in its only lane,
+// as do cards with nothing in those columns.
+type CSVMapping struct {
+	Title    string
+	Content  string
+	Column   string
+	Lane     string
+	Labels   string // labels separated by commas
+	Assignee string
+	DueAt    string // dates like 2006-01-02
+}
+
+// ReadCSVHeader returns the header row of a CSV file, for choosing a mapping
+func ReadCSVHeader(data []byte) ([]string, error) {
+	header, err := csv.NewReader(bytes.NewReader(data)).Read()
+	if err != nil {
+		return nil, fmt.Errorf("could not read the CSV header: %w", err)
+	}
+	return header, nil
+}
+
+// ConvertCSV turns a CSV file with a header row into a document for ImportConverted, a card for each row. The
+// board's columns and lanes are the distinct values of the mapped columns, in the order they first appear.
+func ConvertCSV(data []byte, title string, mapping CSVMapping, summary *ImportSummary) (*BoardDocument, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not read the CSV file: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("the CSV file is empty")
+	}
+
+	fields := make(map[string]int) // header -> index
+	for i, header := range rows[0] {
+		if _, exists := fields[header]; !exists {
+			fields[header] = i
+		}
+	}
+	if _, exists := fields[mapping.Title]; !exists {
+		return nil, fmt.Errorf("choose which column holds the card titles")
+	}
+	for _, header := range []string{mapping.Content, mapping.Column, mapping.Lane, mapping.Labels, mapping.Assignee, mapping.DueAt} {
+		if _, exists := fields[header]; header != "" && !exists {
+			return nil, fmt.Errorf("the CSV file has no column %q", header)
+		}
+	}
+	value := func(row []string, header string) string {
+		if i, exists := fields[header]; header != "" && exists && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	document := &BoardDocument{
+		Version: BoardDocumentVersion,
+		Board:   BoardSnapshot{Title: title},
+	}
+	columnIDs := make(map[string]int) // value -> column ID
+	laneIDs := make(map[string]int)   // value -> lane ID
+	// A card with no column or lane goes in the first one, which is named fallback if it's the first card
+	idFor := func(ids map[string]int, name, fallback string) (int, string) {
+		if name == "" && len(ids) > 0 {
+			return 1, ""
+		}
+		if name == "" {
+			name = fallback
+		}
+		if _, exists := ids[name]; !exists {
+			ids[name] = len(ids) + 1
+			return ids[name], name
+		}
+		return ids[name], ""
+	}
+
+	for i, row := range rows[1:] {
+		if slices.IndexFunc(row, func(field string) bool { return strings.TrimSpace(field) != "" }) == -1 {
+			continue
+		}
+		name := value(row, mapping.Title)
+		if name == "" {
+			summary.skip(fmt.Sprintf("row %d", i+2), "it has no title")
+			continue
+		}
+
+		card := CardSnapshot{
+			ID:       len(document.Board.Cards) + 1,
+			Title:    name,
+			Content:  value(row, mapping.Content),
+			Assignee: value(row, mapping.Assignee),
+			DueAt:    value(row, mapping.DueAt),
+		}
+		var newColumn, newLane string
+		card.ColumnID, newColumn = idFor(columnIDs, value(row, mapping.Column), "To Do")
+		if newColumn != "" {
+			document.Board.Columns = append(document.Board.Columns, ColumnSnapshot{ID: card.ColumnID, Title: newColumn})
+		}
+		card.LaneID, newLane = idFor(laneIDs, value(row, mapping.Lane), "Cards")
+		if newLane != "" {
+			document.Board.Lanes = append(document.Board.Lanes, LaneSnapshot{ID: card.LaneID, Title: newLane})
+		}
+		if labels := value(row, mapping.Labels); labels != "" {
+			card.Labels = strings.Split(labels, ",")
+		}
+		document.Board.Cards = append(document.Board.Cards, card)
+	}
+	return document, nil
+}
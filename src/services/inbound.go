@@ -0,0 +1,329 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// InboundMapping says how to build a card out of a JSON payload. Paths are dot-separated object keys and array
+// indexes, such as "alerts.0.labels.severity"; templates fill {{path}} placeholders from the payload.
+type InboundMapping struct {
+	Title      string   // template
+	Content    string   // template
+	LabelsPath string   // a string of comma-separated labels or an array of strings
+	Labels     []string // added to every card
+	ColumnPath string   // names a column by title; cards go into ColumnID if it's missing or matches none
+}
+
+// InboundHook lets whoever holds its token create cards on the board by POSTing JSON
+type InboundHook struct {
+	ID         int
+	BoardID    int
+	Name       string
+	Token      string
+	Mapping    InboundMapping
+	ColumnID   int
+	LaneID     int
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+	Created    int
+}
+
+// InboundCard is what a mapping makes of a payload, still to be validated like any other new card
+type InboundCard struct {
+	Title    string
+	Content  string
+	Labels   []string
+	ColumnID int
+	LaneID   int
+}
+
+var inboundPlaceholder = regexp.MustCompile(`\{\{\s*([^{}\s]+)\s*\}\}`)
+
+type InboundService struct {
+	mu    sync.Mutex
+	hooks map[int]*InboundHook // hookID -> InboundHook
+
+	nextHookID int
+
+	log         *slog.Logger
+	cardService *CardService
+}
+
+func NewInboundService(log *slog.Logger, cardService *CardService) *InboundService {
+	return &InboundService{
+		hooks:       make(map[int]*InboundHook),
+		nextHookID:  1,
+		log:         log,
+		cardService: cardService,
+	}
+}
+
+// AddHook creates a hook with a fresh token, checking its mapping can work before anything is posted to it
+func (s *InboundService) AddHook(hook InboundHook) (*InboundHook, error) {
+	if hook.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if strings.TrimSpace(hook.Mapping.Title) == "" {
+		return nil, fmt.Errorf("title mapping is required")
+	}
+	for _, path := range s.mappingPaths(hook.Mapping) {
+		if strings.Trim(path, ".") != path || strings.Contains(path, "..") {
+			return nil, fmt.Errorf("invalid path %q", path)
+		}
+	}
+
+	column, err := s.cardService.GetColumn(hook.ColumnID)
+	if err != nil || column.Column.BoardID != hook.BoardID {
+		return nil, fmt.Errorf("column with ID %d not found", hook.ColumnID)
+	}
+	lane, err := s.cardService.GetLane(hook.LaneID)
+	if err != nil || lane.BoardID != hook.BoardID {
+		return nil, fmt.Errorf("lane with ID %d not found", hook.LaneID)
+	}
+
+	token := make([]byte, 24)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hook.ID = s.nextHookID
+	hook.Token = hex.EncodeToString(token)
+	hook.CreatedAt = time.Now()
+	hook.LastUsedAt = time.Time{}
+	hook.Created = 0
+	s.hooks[hook.ID] = &hook
+	s.nextHookID++
+
+	s.log.Info("Added inbound hook", "hookID", hook.ID, "boardID", hook.BoardID, "name", hook.Name)
+	copied := hook
+	return &copied, nil
+}
+
+func (s *InboundService) mappingPaths(mapping InboundMapping) []string {
+	var paths []string
+	for _, template := range []string{mapping.Title, mapping.Content} {
+		for _, match := range inboundPlaceholder.FindAllStringSubmatch(template, -1) {
+			paths = append(paths, match[1])
+		}
+	}
+	for _, path := range []string{mapping.LabelsPath, mapping.ColumnPath} {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+func (s *InboundService) DeleteHook(hookID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.hooks[hookID]; !exists {
+		return fmt.Errorf("hook with ID %d not found", hookID)
+	}
+	delete(s.hooks, hookID)
+	return nil
+}
+
+func (s *InboundService) GetHook(hookID int) (*InboundHook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hook, exists := s.hooks[hookID]
+	if !exists {
+		return nil, fmt.Errorf("hook with ID %d not found", hookID)
+	}
+	copied := *hook
+	return &copied, nil
+}
+
+func (s *InboundService) GetHooks(boardID int) []InboundHook {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var hooks []InboundHook
+	for _, hook := range s.hooks {
+		if hook.BoardID == boardID {
+			hooks = append(hooks, *hook)
+		}
+	}
+	slices.SortFunc(hooks, func(a, b InboundHook) int {
+		return a.ID - b.ID
+	})
+	return hooks
+}
+
+// Authenticate returns the hook the token belongs to, comparing against every hook's token in constant time
+func (s *InboundService) Authenticate(token string) (*InboundHook, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var found *InboundHook
+	for _, hook := range s.hooks {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(hook.Token)) == 1 {
+			found = hook
+		}
+	}
+	if found == nil || token == "" {
+		return nil, false
+	}
+	copied := *found
+	return &copied, true
+}
+
+// RecordUse counts a card created through the hook
+func (s *InboundService) RecordUse(hookID int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if hook, exists := s.hooks[hookID]; exists {
+		hook.Created++
+		hook.LastUsedAt = time.Now()
+	}
+}
+
+// Map applies the hook's mapping to a decoded JSON payload
+func (s *InboundService) Map(hook *InboundHook, payload any) (*InboundCard, error) {
+	title, err := fillInboundTemplate(hook.Mapping.Title, payload)
+	if err != nil {
+		return nil, err
+	}
+	content, err := fillInboundTemplate(hook.Mapping.Content, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	card := &InboundCard{
+		Title:    title,
+		Content:  content,
+		Labels:   slices.Clone(hook.Mapping.Labels),
+		ColumnID: hook.ColumnID,
+		LaneID:   hook.LaneID,
+	}
+
+	if hook.Mapping.LabelsPath != "" {
+		// Like placeholders, a payload without labels just doesn't add any
+		value, _ := LookupJSONPath(payload, hook.Mapping.LabelsPath)
+		switch value := value.(type) {
+		case nil:
+		case []any:
+			for i, item := range value {
+				label, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("%s.%d: expected a string, got %s", hook.Mapping.LabelsPath, i, jsonType(item))
+				}
+				card.Labels = append(card.Labels, label)
+			}
+		case string:
+			card.Labels = append(card.Labels, strings.Split(value, ",")...)
+		default:
+			return nil, fmt.Errorf("%s: expected a string or an array of strings, got %s", hook.Mapping.LabelsPath, jsonType(value))
+		}
+	}
+
+	if hook.Mapping.ColumnPath != "" {
+		value, err := LookupJSONPath(payload, hook.Mapping.ColumnPath)
+		if err == nil {
+			if title, ok := value.(string); ok {
+				for _, column := range s.cardService.GetColumns(hook.BoardID) {
+					if strings.EqualFold(column.Column.Title, strings.TrimSpace(title)) {
+						card.ColumnID = column.Column.ID
+					}
+				}
+			}
+		}
+	}
+
+	return card, nil
+}
+
+// fillInboundTemplate replaces each {{path}} with the payload's value there; missing paths are left empty
+func fillInboundTemplate(template string, payload any) (string, error) {
+	var fillErr error
+	filled := inboundPlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		path := inboundPlaceholder.FindStringSubmatch(placeholder)[1]
+		value, err := LookupJSONPath(payload, path)
+		if err != nil {
+			return ""
+		}
+
+		switch value := value.(type) {
+		case nil:
+			return ""
+		case string:
+			return value
+		case float64:
+			return strconv.FormatFloat(value, 'f', -1, 64)
+		case bool:
+			return strconv.FormatBool(value)
+		default:
+			if fillErr == nil {
+				fillErr = fmt.Errorf("%s: expected a string, number or boolean, got %s", path, jsonType(value))
+			}
+			return ""
+		}
+	})
+	return strings.TrimSpace(filled), fillErr
+}
+
+// LookupJSONPath walks a decoded JSON value along a dot-separated path of object keys and array indexes,
+// reporting how far it got when the path doesn't exist
+func LookupJSONPath(value any, path string) (any, error) {
+	walked := "$"
+	for _, key := range strings.Split(path, ".") {
+		switch current := value.(type) {
+		case map[string]any:
+			next, exists := current[key]
+			if !exists {
+				return nil, fmt.Errorf("%s: no field %q", walked, key)
+			}
+			value = next
+		case []any:
+			index, err := strconv.Atoi(key)
+			if err != nil {
+				return nil, fmt.Errorf("%s: expected an array index, got %q", walked, key)
+			}
+			if index < 0 || index >= len(current) {
+				return nil, fmt.Errorf("%s: index %d is out of range, the array has %d items", walked, index, len(current))
+			}
+			value = current[index]
+		default:
+			return nil, fmt.Errorf("%s: can't look up %q in %s", walked, key, jsonType(current))
+		}
+		walked += "." + key
+	}
+	return value, nil
+}
+
+func jsonType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "an array"
+	case string:
+		return "a string"
+	case float64, json.Number:
+		return "a number"
+	case bool:
+		return "a boolean"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"html"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+var (
+	orderedItemPattern   = regexp.MustCompile(`^\d{1,9}[.)]\s+`)
+	unorderedItemPattern = regexp.MustCompile(`^[-*+]\s+`)
+	taskPattern          = regexp.MustCompile(`^\[([ xX])\]\s+`)
+	linkPattern          = regexp.MustCompile(`^\[([^\]]+)\]\(([^)\s]+)\)`)
+)
+
+// MarkdownService renders card content as a small, safe subset of Markdown:
+// paragraphs, ordered and unordered lists, task checkboxes, code, links and emphasis
+type MarkdownService struct {
+	log       *slog.Logger
+	sanitiser *HTMLSanitiser
+}
+
+func NewMarkdownService(log *slog.Logger, sanitiser *HTMLSanitiser) *MarkdownService {
+	return &MarkdownService{
+		log:       log,
+		sanitiser: sanitiser,
+	}
+}
+
+// Render converts the input to HTML and passes the result through the sanitiser
+func (m *MarkdownService) Render(input string) string {
+	if strings.TrimSpace(input) == "" {
+		return ""
+	}
+	return m.sanitiser.Sanitise(m.renderBlocks(input))
+}
+
+func (m *MarkdownService) renderBlocks(input string) string {
+	var result strings.Builder
+	var paragraph []string
+	var listTag string
+
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			result.WriteString("<p>" + strings.Join(paragraph, "<br>") + "</p>")
+			paragraph = nil
+		}
+	}
+	closeList := func() {
+		if listTag != "" {
+			result.WriteString("</" + listTag + ">")
+			listTag = ""
+		}
+	}
+
+	lines := strings.Split(strings.ReplaceAll(input, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+		trimmed := strings.TrimLeft(line, " \t")
+
+		// Fenced code blocks run until the closing fence or the end of the input
+		if strings.HasPrefix(trimmed, "```") {
+			flushParagraph()
+			closeList()
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			result.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>")
+			continue
+		}
+
+		if trimmed == "" {
+			flushParagraph()
+			closeList()
+			continue
+		}
+
+		if marker := unorderedItemPattern.FindString(trimmed); marker != "" {
+			flushParagraph()
+			m.openList(&result, &listTag, "ul")
+			result.WriteString(m.renderListItem(trimmed[len(marker):]))
+			continue
+		}
+
+		if marker := orderedItemPattern.FindString(trimmed); marker != "" {
+			flushParagraph()
+			m.openList(&result, &listTag, "ol")
+			result.WriteString(m.renderListItem(trimmed[len(marker):]))
+			continue
+		}
+
+		closeList()
+		paragraph = append(paragraph, m.renderInline(trimmed))
+	}
+
+	flushParagraph()
+	closeList()
+	return result.String()
+}
+
+func (m *MarkdownService) openList(result *strings.Builder, listTag *string, tag string) {
+	if *listTag == tag {
+		return
+	}
+	if *listTag != "" {
+		result.WriteString("</" + *listTag + ">")
+	}
+	result.WriteString("<" + tag + ">")
+	*listTag = tag
+}
+
+func (m *MarkdownService) renderListItem(item string) string {
+	if match := taskPattern.FindStringSubmatch(item); match != nil {
+		checkbox := `<input type="checkbox">`
+		if match[1] != " " {
+			checkbox = `<input type="checkbox" checked>`
+		}
+		return `<li class="task">` + checkbox + " " + m.renderInline(item[len(match[0]):]) + "</li>"
+	}
+	return "<li>" + m.renderInline(item) + "</li>"
+}
+
+// renderInline handles code spans, links and emphasis, escaping everything else
+func (m *MarkdownService) renderInline(input string) string {
+	var result strings.Builder
+
+	for i := 0; i < len(input); {
+		rest := input[i:]
+
+		switch {
+		case rest[0] == '`':
+			if end := strings.IndexByte(rest[1:], '`'); end >= 0 {
+				result.WriteString("<code>" + html.EscapeString(rest[1:end+1]) + "</code>")
+				i += end + 2
+				continue
+			}
+		case rest[0] == '[':
+			if match := linkPattern.FindStringSubmatch(rest); match != nil {
+				result.WriteString(`<a href="` + html.EscapeString(match[2]) + `">` + m.renderInline(match[1]) + "</a>")
+				i += len(match[0])
+				continue
+			}
+		case strings.HasPrefix(rest, "**"):
+			if end := strings.Index(rest[2:], "**"); end > 0 {
+				result.WriteString("<strong>" + m.renderInline(rest[2:end+2]) + "</strong>")
+				i += end + 4
+				continue
+			}
+		case rest[0] == '*' || rest[0] == '_':
+			// Underscores inside words, as in snake_case, are not emphasis
+			if rest[0] == '_' && i > 0 && isAlphanumeric(input[i-1]) {
+				break
+			}
+			if len(rest) > 1 && rest[1] != ' ' {
+				if end := strings.IndexByte(rest[1:], rest[0]); end > 0 {
+					result.WriteString("<em>" + m.renderInline(rest[1:end+1]) + "</em>")
+					i += end + 2
+					continue
+				}
+			}
+		}
+
+		result.WriteString(html.EscapeString(rest[:1]))
+		i++
+	}
+
+	return result.String()
+}
+
+func isAlphanumeric(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
@@ -0,0 +1,155 @@
+package services
+
+import "strings"
+
+// MatchMode decides how much of the text around a blacklist entry has to be a word boundary for it to count
+type MatchMode int
+
+const (
+	MatchSubstring MatchMode = iota // the entry appears anywhere, even across spaces: "shit" in "bullshit" and "s h i t"
+	MatchWord                       // the entry is a whole word, or words, of the text: "ass" but not "assess"
+	MatchStem                       // a word of the text starts with the entry: "fuck" in "fucking"
+)
+
+// BlacklistEntry is one line of the blacklist. Entries match anywhere in the text, as they always have, unless
+// written as "word" to match whole words only or stem* to match words starting with the stem. Writing an entry as
+// *substring* spells out the default.
+type BlacklistEntry struct {
+	Word string
+	Mode MatchMode
+}
+
+// ParseBlacklistEntry reads a line of the blacklist, ignoring blank lines and # comments
+func ParseBlacklistEntry(line string) (BlacklistEntry, bool) {
+	line = strings.ToLower(strings.TrimSpace(line))
+	entry := BlacklistEntry{Mode: MatchSubstring}
+	switch {
+	case line == "" || strings.HasPrefix(line, "#"):
+		return entry, false
+	case len(line) > 2 && strings.HasPrefix(line, `"`) && strings.HasSuffix(line, `"`):
+		entry.Mode = MatchWord
+		line = line[1 : len(line)-1]
+	case len(line) > 2 && strings.HasPrefix(line, "*") && strings.HasSuffix(line, "*"):
+		line = line[1 : len(line)-1]
+	case strings.HasSuffix(line, "*"):
+		entry.Mode = MatchStem
+		line = line[:len(line)-1]
+	}
+
+	entry.Word = strings.Join(strings.Fields(line), " ")
+	return entry, entry.Word != ""
+}
+
+// matcherAlphabet is what the automaton reads: the lowercase letters and digits the text is normalised to, and space
+const matcherAlphabet = 37
+
+func matcherSymbol(b byte) int {
+	switch {
+	case b >= 'a' && b <= 'z':
+		return int(b - 'a')
+	case b >= '0' && b <= '9':
+		return int(b-'0') + 26
+	case b == ' ':
+		return 36
+	default:
+		return -1
+	}
+}
+
+// Matcher finds blacklist entries in text with an Aho-Corasick automaton, so it reads the text once however long
+// the blacklist is. Transitions are precomputed for every node, so reading a character is a single lookup.
+type Matcher struct {
+	next    [][matcherAlphabet]int32 // node -> symbol -> node
+	outputs [][]int32                // node -> entries ending there
+	dict    []int32                  // node -> nearest node down its failure links with outputs, or -1
+	entries []BlacklistEntry
+}
+
+// NewMatcher builds the automaton for the entries; their words should already be normalised like the text
+func NewMatcher(entries []BlacklistEntry) *Matcher {
+	m := &Matcher{entries: entries}
+	m.addNode()
+
+	for i, entry := range entries {
+		node := int32(0)
+		for j := 0; j < len(entry.Word); j++ {
+			symbol := matcherSymbol(entry.Word[j])
+			if symbol < 0 {
+				continue
+			}
+			if m.next[node][symbol] == 0 {
+				m.next[node][symbol] = m.addNode()
+			}
+			node = m.next[node][symbol]
+		}
+		if node != 0 {
+			m.outputs[node] = append(m.outputs[node], int32(i))
+		}
+	}
+
+	// Breadth first, each node's failure link is the longest proper suffix of its path that is also a path, and
+	// missing transitions are borrowed from there, turning the trie into a complete automaton
+	fail := make([]int32, len(m.next))
+	queue := make([]int32, 0, len(m.next))
+	for symbol := 0; symbol < matcherAlphabet; symbol++ {
+		if child := m.next[0][symbol]; child != 0 {
+			queue = append(queue, child)
+		}
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+
+		if len(m.outputs[fail[node]]) > 0 {
+			m.dict[node] = fail[node]
+		} else {
+			m.dict[node] = m.dict[fail[node]]
+		}
+
+		for symbol := 0; symbol < matcherAlphabet; symbol++ {
+			child := m.next[node][symbol]
+			if child == 0 {
+				m.next[node][symbol] = m.next[fail[node]][symbol]
+				continue
+			}
+			fail[child] = m.next[fail[node]][symbol]
+			queue = append(queue, child)
+		}
+	}
+	return m
+}
+
+func (m *Matcher) addNode() int32 {
+	m.next = append(m.next, [matcherAlphabet]int32{})
+	m.outputs = append(m.outputs, nil)
+	m.dict = append(m.dict, -1)
+	return int32(len(m.next) - 1)
+}
+
+// Find returns the first entry, by where it ends, found in the text that accept agrees to. It's given each match
+// with the bytes of text it covers, to check what's around it.
+func (m *Matcher) Find(text string, accept func(entry BlacklistEntry, start, end int) bool) (BlacklistEntry, bool) {
+	if len(m.entries) == 0 {
+		return BlacklistEntry{}, false
+	}
+
+	node := int32(0)
+	for i := 0; i < len(text); i++ {
+		symbol := matcherSymbol(text[i])
+		if symbol < 0 {
+			node = 0
+			continue
+		}
+		node = m.next[node][symbol]
+
+		for found := node; found > 0; found = m.dict[found] {
+			for _, index := range m.outputs[found] {
+				entry := m.entries[index]
+				if accept(entry, i+1-len(entry.Word), i+1) {
+					return entry, true
+				}
+			}
+		}
+	}
+	return BlacklistEntry{}, false
+}
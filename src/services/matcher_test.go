@@ -0,0 +1,109 @@
+package services
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseBlacklistEntry(t *testing.T) {
+	tests := []struct {
+		line   string
+		want   BlacklistEntry
+		wantOK bool
+	}{
+		{line: "shit", want: BlacklistEntry{Word: "shit", Mode: MatchSubstring}, wantOK: true},
+		{line: "*shit*", want: BlacklistEntry{Word: "shit", Mode: MatchSubstring}, wantOK: true},
+		{line: `"Ass"`, want: BlacklistEntry{Word: "ass", Mode: MatchWord}, wantOK: true},
+		{line: "fuck*", want: BlacklistEntry{Word: "fuck", Mode: MatchStem}, wantOK: true},
+		{line: "  son  of   a  ", want: BlacklistEntry{Word: "son of a", Mode: MatchSubstring}, wantOK: true},
+		{line: "", wantOK: false},
+		{line: "# comment", wantOK: false},
+	}
+	for _, test := range tests {
+		got, ok := ParseBlacklistEntry(test.line)
+		if ok != test.wantOK || (ok && got != test.want) {
+			t.Errorf("ParseBlacklistEntry(%q) = %+v, %v, want %+v, %v", test.line, got, ok, test.want, test.wantOK)
+		}
+	}
+}
+
+func TestWordServiceMatchModes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blacklist.txt")
+	if err := os.WriteFile(path, []byte("shit\n\"ass\"\nfuck*\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	words, err := NewWordService(newTestLogger(), &Config{BlacklistPath: path, Leetspeak: defaultLeetspeak})
+	if err != nil {
+		t.Fatalf("NewWordService: %v", err)
+	}
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "what bullshit", want: "shit"},
+		{input: "s.h.i.t", want: "shit"},
+		{input: "kiss my ass", want: "ass"},
+		{input: "@$$", want: "ass"},
+		{input: "4$$", want: "ass"},
+		{input: "assess the classic", want: ""},
+		{input: "fucking", want: "fuck"},
+		{input: "clean text", want: ""},
+	}
+	for _, test := range tests {
+		if got := words.Filter(test.input); got != test.want {
+			t.Errorf("Filter(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+// benchmarkBlacklist is a large list of random substring entries, with text that contains none of them
+func benchmarkBlacklist() ([]BlacklistEntry, string) {
+	random := rand.New(rand.NewSource(1))
+	entries := make([]BlacklistEntry, 10000)
+	for i := range entries {
+		word := make([]byte, 5+random.Intn(6))
+		for j := range word {
+			word[j] = byte('a' + random.Intn(26))
+		}
+		entries[i] = BlacklistEntry{Word: string(word), Mode: MatchSubstring}
+	}
+	text := strings.Repeat("the quick brown fox jumps over the lazy dog ", 17)
+	return entries, strings.ReplaceAll(text, " ", "")
+}
+
+// BenchmarkContainsLoop is how the blacklist used to be checked, a strings.Contains for every entry
+func BenchmarkContainsLoop(b *testing.B) {
+	entries, text := benchmarkBlacklist()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, entry := range entries {
+			if strings.Contains(text, entry.Word) {
+				b.Fatalf("unexpected match %q", entry.Word)
+			}
+		}
+	}
+}
+
+func BenchmarkMatcher(b *testing.B) {
+	entries, text := benchmarkBlacklist()
+	matcher := NewMatcher(entries)
+	accept := func(BlacklistEntry, int, int) bool { return true }
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if entry, found := matcher.Find(text, accept); found {
+			b.Fatalf("unexpected match %q", entry.Word)
+		}
+	}
+}
+
+func BenchmarkNewMatcher(b *testing.B) {
+	entries, _ := benchmarkBlacklist()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewMatcher(entries)
+	}
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ModerationMode decides what happens to a board's cards when they contain blacklisted words
+type ModerationMode string
+
+const (
+	ModerationOff   ModerationMode = "off"   // nothing is checked
+	ModerationBlock ModerationMode = "block" // the card is refused until the words are taken out
+	ModerationMask  ModerationMode = "mask"  // the words are saved as asterisks
+	ModerationHold  ModerationMode = "hold"  // the card waits in the moderation queue until an admin approves it
+)
+
+// ModerationModes are the modes in the order they're offered
+var ModerationModes = []ModerationMode{ModerationOff, ModerationBlock, ModerationMask, ModerationHold}
+
+func (m ModerationMode) Label() string {
+	switch m {
+	case ModerationOff:
+		return "Off"
+	case ModerationBlock:
+		return "Block"
+	case ModerationMask:
+		return "Mask with asterisks"
+	case ModerationHold:
+		return "Hold for review"
+	default:
+		return string(m)
+	}
+}
+
+// ModerationPolicy is how a board treats blacklisted words, and the words it allows whatever the blacklist says
+type ModerationPolicy struct {
+	Mode    ModerationMode
+	Allowed []string
+}
+
+// ModerationError is returned when a card contains a blacklisted word its board's policy doesn't let through
+type ModerationError struct {
+	BoardID int
+	Field   string // title, content, labels or assignee
+	Word    string
+	Held    bool // the policy holds the card for review rather than refusing it
+}
+
+func (e *ModerationError) Error() string {
+	verb := "contains"
+	if e.Field == "labels" {
+		verb = "contain"
+	}
+	if e.Held {
+		return fmt.Sprintf("held for review: %s %s prohibited word: %s", e.Field, verb, e.Word)
+	}
+	return fmt.Sprintf("%s %s prohibited word: %s", e.Field, verb, e.Word)
+}
+
+// HeldCard is a new card, or changes to a card, waiting in its board's moderation queue
+type HeldCard struct {
+	ID       int
+	BoardID  int
+	CardID   int // the card the changes are for, or zero for a new card
+	ColumnID int
+	LaneID   int
+	Details  CardDetails
+	Field    string // where the blacklisted word was found
+	Word     string
+	Author   string
+	HeldAt   time.Time
+}
+
+// IsNew reports whether the held card would be added to the board, rather than change a card already on it
+func (h *HeldCard) IsNew() bool {
+	return h.CardID == 0
+}
+
+// ModerationService keeps each board's moderation policy and the cards held for review under it
+type ModerationService struct {
+	mu       sync.RWMutex
+	policies map[int]ModerationPolicy // boardID -> policy; boards without one block
+	held     map[int]*HeldCard        // heldID -> HeldCard
+
+	nextHeldID int
+
+	log         *slog.Logger
+	wordService *WordService
+}
+
+func NewModerationService(log *slog.Logger, wordService *WordService) *ModerationService {
+	return &ModerationService{
+		policies:    make(map[int]ModerationPolicy),
+		held:        make(map[int]*HeldCard),
+		nextHeldID:  1,
+		log:         log,
+		wordService: wordService,
+	}
+}
+
+// GetPolicy returns the board's policy, which blocks blacklisted words until it's changed
+func (m *ModerationService) GetPolicy(boardID int) ModerationPolicy {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if policy, exists := m.policies[boardID]; exists {
+		return ModerationPolicy{Mode: policy.Mode, Allowed: slices.Clone(policy.Allowed)}
+	}
+	return ModerationPolicy{Mode: ModerationBlock}
+}
+
+// SetPolicy replaces the board's policy, dropping blank and repeated allowed words
+func (m *ModerationService) SetPolicy(boardID int, policy ModerationPolicy) error {
+	if !slices.Contains(ModerationModes, policy.Mode) {
+		return fmt.Errorf("unknown moderation mode %q", policy.Mode)
+	}
+
+	var allowed []string
+	for _, word := range policy.Allowed {
+		word = strings.TrimSpace(word)
+		if word == "" || slices.Contains(allowed, word) {
+			continue
+		}
+		if len(word) > 50 {
+			return fmt.Errorf("allowed words must be less than 50 characters")
+		}
+		allowed = append(allowed, word)
+	}
+	policy.Allowed = allowed
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.policies[boardID] = policy
+	m.log.Info("Set moderation policy", "boardID", boardID, "mode", policy.Mode, "allowed", len(allowed))
+	return nil
+}
+
+// Check applies the board's policy to a card's details, returning them as they should be saved: as they are, or
+// with blacklisted words masked. A *ModerationError says the card is refused or should be held instead.
+func (m *ModerationService) Check(boardID int, details CardDetails) (CardDetails, error) {
+	policy := m.GetPolicy(boardID)
+	switch policy.Mode {
+	case ModerationOff:
+		return details, nil
+	case ModerationMask:
+		details.Title = m.wordService.Mask(details.Title, policy.Allowed)
+		details.Content = m.wordService.Mask(details.Content, policy.Allowed)
+		labels := make([]string, 0, len(details.Labels))
+		for _, label := range details.Labels {
+			labels = append(labels, m.wordService.Mask(label, policy.Allowed))
+		}
+		details.Labels = labels
+		details.Assignee = m.wordService.Mask(details.Assignee, policy.Allowed)
+		return details, nil
+	}
+
+	fields := []struct {
+		name string
+		text string
+	}{
+		{"title", details.Title},
+		{"content", details.Content},
+		{"labels", strings.Join(details.Labels, " ")},
+		{"assignee", details.Assignee},
+	}
+	for _, field := range fields {
+		if word := m.wordService.FilterAllowing(field.text, policy.Allowed); word != "" {
+			return details, &ModerationError{BoardID: boardID, Field: field.name, Word: word, Held: policy.Mode == ModerationHold}
+		}
+	}
+	return details, nil
+}
+
+// CheckText applies the board's policy to text that can't be held for review, like a comment, which is refused
+// instead
+func (m *ModerationService) CheckText(boardID int, field, text string) (string, error) {
+	policy := m.GetPolicy(boardID)
+	switch policy.Mode {
+	case ModerationOff:
+		return text, nil
+	case ModerationMask:
+		return m.wordService.Mask(text, policy.Allowed), nil
+	}
+
+	if word := m.wordService.FilterAllowing(text, policy.Allowed); word != "" {
+		return text, &ModerationError{BoardID: boardID, Field: field, Word: word}
+	}
+	return text, nil
+}
+
+// Hold puts a card in its board's moderation queue
+func (m *ModerationService) Hold(held HeldCard) *HeldCard {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	held.ID = m.nextHeldID
+	held.HeldAt = time.Now()
+	m.held[held.ID] = &held
+	m.nextHeldID++
+
+	m.log.Info("Held card for review", "heldID", held.ID, "boardID", held.BoardID, "cardID", held.CardID, "word", held.Word)
+	return &held
+}
+
+// GetQueue returns the cards held for review on the board, oldest first
+func (m *ModerationService) GetQueue(boardID int) []HeldCard {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var queue []HeldCard
+	for _, held := range m.held {
+		if held.BoardID == boardID {
+			queue = append(queue, *held)
+		}
+	}
+	sort.Slice(queue, func(i, j int) bool {
+		return queue[i].ID < queue[j].ID
+	})
+	return queue
+}
+
+func (m *ModerationService) GetHeldCard(heldID int) (*HeldCard, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if held, exists := m.held[heldID]; exists {
+		copied := *held
+		return &copied, nil
+	}
+	return nil, fmt.Errorf("held card with ID %d not found", heldID)
+}
+
+// Take takes a card out of the queue to approve or reject it, failing if it has already been taken, so a card
+// is only reviewed once however many admins approve it at the same time
+func (m *ModerationService) Take(heldID int) (*HeldCard, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	held, exists := m.held[heldID]
+	if !exists {
+		return nil, fmt.Errorf("held card with ID %d not found", heldID)
+	}
+	delete(m.held, heldID)
+	return held, nil
+}
+
+// Return puts a card taken out of the queue back in it, as it was, when it couldn't be approved
+func (m *ModerationService) Return(held *HeldCard) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.held[held.ID] = held
+}
@@ -0,0 +1,25 @@
+package services
+
+import "testing"
+
+func TestModerationServiceTakesHeldCardOnce(t *testing.T) {
+	moderation := NewModerationService(newTestLogger(), nil)
+	held := moderation.Hold(HeldCard{BoardID: 1, Details: CardDetails{Title: "Held"}})
+
+	taken, err := moderation.Take(held.ID)
+	if err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+	if _, err := moderation.Take(held.ID); err == nil {
+		t.Error("second Take succeeded, want the card already taken")
+	}
+	if queue := moderation.GetQueue(1); len(queue) != 0 {
+		t.Errorf("queue = %+v, want it empty while the card is taken", queue)
+	}
+
+	moderation.Return(taken)
+	queue := moderation.GetQueue(1)
+	if len(queue) != 1 || queue[0].ID != held.ID || queue[0].Details.Title != "Held" {
+		t.Errorf("queue = %+v, want the returned card", queue)
+	}
+}
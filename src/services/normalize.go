@@ -0,0 +1,274 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// letters maps Latin letters that compatibility decomposition (NFKD) keeps whole to how they're spelled in ASCII.
+// Only lowercase letters are listed where their capitals fold to the same letter capitalised.
+var letters = buildFolds([][2]string{
+	{"l", "ł"},
+	{"d", "đð"},
+	{"h", "ħ"},
+	{"i", "ı"},
+	{"o", "ø"},
+	{"t", "ŧ"},
+	{"ae", "æ"},
+	{"oe", "œ"},
+	{"ss", "ß"},
+	{"th", "þ"},
+})
+
+// confusables maps lookalikes, mostly from other scripts, to the ASCII letters they're made to look like.
+// Capitals that look like a different letter than their lowercase are listed on their own.
+var confusables = buildFolds([][2]string{
+	{"a", "аɑα"},
+	{"b", "ьƀ"},
+	{"c", "сϲ"},
+	{"d", "ԁ"},
+	{"e", "еҽ"},
+	{"g", "ɡց"},
+	{"h", "һհ"},
+	{"i", "іɩιɨ"},
+	{"j", "ј"},
+	{"k", "κĸк"},
+	{"l", "ӏ"},
+	{"m", "м"},
+	{"n", "ηո"},
+	{"o", "оοօ"},
+	{"p", "рρ"},
+	{"q", "ԛ"},
+	{"s", "ѕ"},
+	{"t", "т"},
+	{"u", "υս"},
+	{"v", "ν"},
+	{"w", "ԝ"},
+	{"x", "хχ"},
+	{"y", "уүγ"},
+	{"A", "АΑ"},
+	{"B", "ВΒ"},
+	{"C", "С"},
+	{"E", "ЕΕ"},
+	{"H", "НΗ"},
+	{"I", "ІΙ"},
+	{"J", "Ј"},
+	{"K", "КΚ"},
+	{"M", "МΜ"},
+	{"N", "Ν"},
+	{"O", "ОΟ"},
+	{"P", "РΡ"},
+	{"S", "Ѕ"},
+	{"T", "ТΤ"},
+	{"X", "ХΧ"},
+	{"Y", "ҮΥ"},
+	{"Z", "Ζ"},
+})
+
+func buildFolds(table [][2]string) map[rune]string {
+	folds := make(map[rune]string)
+	for _, fold := range table {
+		for _, r := range fold[1] {
+			folds[r] = fold[0]
+		}
+	}
+	return folds
+}
+
+// foldToASCII reads the text as ASCII a character at a time, see foldRune
+func foldToASCII(input string, lookalikes bool) string {
+	var result strings.Builder
+	result.Grow(len(input))
+	for _, r := range input {
+		result.WriteString(foldRune(r, lookalikes))
+	}
+	return result.String()
+}
+
+// foldRune is the ASCII a character is read as: its compatibility decomposition (NFKD) without accents, with
+// lookalikes the letter it's made to look like, or a space for spaces and punctuation. Accents written as separate
+// combining marks and invisible formatting characters are dropped, and so is anything else with no ASCII reading.
+func foldRune(r rune, lookalikes bool) string {
+	if r <= unicode.MaxASCII {
+		return string(r)
+	}
+
+	decomposed := norm.NFKD.String(string(r))
+	if len(decomposed) == 1 {
+		return decomposed // fullwidth punctuation, no-break spaces and such
+	}
+	var result strings.Builder
+	for _, d := range decomposed {
+		switch {
+		case d <= unicode.MaxASCII:
+			// The letters and digits of a decomposition like ⒜ or ℅, without the brackets and slashes around them
+			if isASCIILetter(byte(d)) || isASCIIDigit(byte(d)) {
+				result.WriteRune(d)
+			}
+		case unicode.Is(unicode.Mn, d):
+		default:
+			result.WriteString(foldLetter(d, lookalikes))
+		}
+	}
+	if result.Len() > 0 {
+		return result.String()
+	}
+
+	// Letters NFKD leaves alone, repeated across a block in alphabetical order
+	switch {
+	case r >= 0x1F150 && r <= 0x1F169, r >= 0x1F170 && r <= 0x1F189: // negative circled and negative squared capitals
+		return string('A' + (r-0x1F150)%32)
+	case r >= 0x1F1E6 && r <= 0x1F1FF: // regional indicators
+		return string('A' + r - 0x1F1E6)
+	}
+
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return ""
+	case unicode.IsSpace(r) || unicode.IsPunct(r):
+		return " "
+	}
+	return ""
+}
+
+// foldLetter is the ASCII for a letter NFKD leaves whole, if it has one
+func foldLetter(r rune, lookalikes bool) string {
+	fold := func(r rune) (string, bool) {
+		if fold, ok := letters[r]; ok {
+			return fold, true
+		}
+		fold, ok := confusables[r]
+		return fold, ok && lookalikes
+	}
+	if fold, ok := fold(r); ok {
+		return fold
+	}
+	if lower := unicode.ToLower(r); lower != r {
+		if fold, ok := fold(lower); ok {
+			return strings.ToUpper(fold)
+		}
+	}
+	return ""
+}
+
+// leetspeak maps characters written in place of letters to the letters they could stand for, in order of how
+// likely they are
+type leetspeak map[byte][]byte
+
+// defaultLeetspeak is the substitutions used unless MESH_LEETSPEAK is set
+var defaultLeetspeak = []string{"0=o", "1=i/l", "3=e", "4=a", "5=s", "7=t", "8=b", "9=g", "@=a", "$=s", "!=i", "|=l", "+=t"}
+
+// parseLeetspeak reads substitutions written as a character, = and the letters it can stand for separated by /,
+// like 1=i/l. A single "none" turns substitution off.
+func parseLeetspeak(items []string) (leetspeak, error) {
+	substitutions := leetspeak{}
+	if len(items) == 1 && items[0] == "none" {
+		return substitutions, nil
+	}
+
+	for _, item := range items {
+		from, to, ok := strings.Cut(item, "=")
+		if !ok || len(from) != 1 || from[0] > unicode.MaxASCII || isASCIILetter(from[0]) || from[0] == ' ' || to == "" {
+			return nil, fmt.Errorf("leetspeak substitution %q should be a character, = and the letters it stands for separated by /, like 1=i/l", item)
+		}
+		for _, letter := range strings.Split(strings.ToLower(to), "/") {
+			if len(letter) != 1 || !isASCIILetter(letter[0]) {
+				return nil, fmt.Errorf("leetspeak substitution %q should only stand for single letters", item)
+			}
+			substitutions[from[0]] = append(substitutions[from[0]], letter[0])
+		}
+	}
+	return substitutions, nil
+}
+
+// maxLeetspeakReadings bounds how many ways a text is read, since each character standing for several letters
+// multiplies them
+const maxLeetspeakReadings = 64
+
+// variants returns the text, then the text read as leetspeak. Every substitution is first read as its first letter,
+// then its second and so on, so 1=i/l gives one reading with every 1 as i and another with every 1 as l. Then the
+// letters are mixed, a character at a time, so "k1ll3d" can be read with one 1 as i and the other as l, up to
+// maxLeetspeakReadings in all. Only words with a letter, or a symbol standing for one like @ or $, are read this
+// way, which leaves plain numbers like 455 alone but still reads @$$.
+func (l leetspeak) variants(text string) []string {
+	variants := []string{text}
+
+	var positions []int // bytes of the words read as leetspeak that stand for letters
+	alternatives := 0
+	for start := 0; start < len(text); {
+		if isASCIISpace(text[start]) {
+			start++
+			continue
+		}
+		end, letters, most := start, false, 0
+		for ; end < len(text) && !isASCIISpace(text[end]); end++ {
+			letters = letters || isASCIILetter(text[end]) || (len(l[text[end]]) > 0 && !isASCIIDigit(text[end]))
+			most = max(most, len(l[text[end]]))
+		}
+		if letters && most > 0 {
+			for i := start; i < end; i++ {
+				if len(l[text[i]]) > 0 {
+					positions = append(positions, i)
+				}
+			}
+			alternatives = max(alternatives, most)
+		}
+		start = end
+	}
+	if len(positions) == 0 {
+		return variants
+	}
+
+	seen := map[string]bool{text: true}
+	read := func(choice func(k int) int) {
+		variant := []byte(text)
+		for k, i := range positions {
+			variant[i] = l[text[i]][choice(k)]
+		}
+		if !seen[string(variant)] {
+			seen[string(variant)] = true
+			variants = append(variants, string(variant))
+		}
+	}
+
+	for alternative := 0; alternative < alternatives && len(variants) < maxLeetspeakReadings; alternative++ {
+		read(func(k int) int {
+			return min(alternative, len(l[text[positions[k]]])-1)
+		})
+	}
+
+	// Counting through the letters for each character like an odometer, the last character turning fastest
+	choices := make([]int, len(positions))
+	for len(variants) < maxLeetspeakReadings {
+		read(func(k int) int {
+			return choices[k]
+		})
+
+		k := len(choices) - 1
+		for ; k >= 0; k-- {
+			if choices[k]++; choices[k] < len(l[text[positions[k]]]) {
+				break
+			}
+			choices[k] = 0
+		}
+		if k < 0 {
+			break
+		}
+	}
+	return variants
+}
+
+func isASCIILetter(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+func isASCIIDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+func isASCIISpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFoldToASCII(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		lookalikes bool
+		want       string
+	}{
+		{name: "accents", input: "Crème brûlée", want: "Creme brulee"},
+		{name: "combining marks", input: "Café", want: "Cafe"},
+		{name: "capital accents", input: "ÅNGSTRÖM", want: "ANGSTROM"},
+		{name: "letters NFKD keeps whole", input: "Łódź straße Æsir", want: "Lodz strasse AEsir"},
+		{name: "fullwidth", input: "ｓｈｉｔ！", want: "shit!"},
+		{name: "mathematical bold", input: "𝐬𝐡𝐢𝐭", want: "shit"},
+		{name: "mathematical script", input: "𝓈𝒽𝒾𝓉", want: "shit"},
+		{name: "circled", input: "ⓢⓗⓘⓣ", want: "shit"},
+		{name: "parenthesized", input: "⒮⒣⒤⒯", want: "shit"},
+		{name: "squared", input: "🄰🅂🅂", want: "ASS"},
+		{name: "negative circled", input: "🅐🅢🅢", want: "ASS"},
+		{name: "ligatures", input: "ﬁﬂ ĳ", want: "fifl ij"},
+		{name: "superscripts", input: "x² H₂O", want: "x2 H2O"},
+		{name: "Kelvin sign", input: "K", want: "K"},
+		{name: "no-break space", input: "a b", want: "a b"},
+		{name: "punctuation", input: "a—b", want: "a b"},
+		{name: "formatting", input: "s​h‍i­t", want: "shit"},
+		{name: "confusables", input: "ѕһіt", lookalikes: true, want: "shit"},
+		{name: "confusable capitals", input: "АЅЅ", lookalikes: true, want: "ASS"},
+		{name: "accented confusables", input: "ё", lookalikes: true, want: "e"},
+		{name: "confusables left alone", input: "ѕһіt", want: "t"},
+		{name: "other scripts", input: "日本", lookalikes: true, want: ""},
+	}
+	for _, test := range tests {
+		if got := foldToASCII(test.input, test.lookalikes); got != test.want {
+			t.Errorf("%s: foldToASCII(%q, %v) = %q, want %q", test.name, test.input, test.lookalikes, got, test.want)
+		}
+	}
+}
+
+func TestLeetspeakVariants(t *testing.T) {
+	substitutions, err := parseLeetspeak(defaultLeetspeak)
+	if err != nil {
+		t.Fatalf("parseLeetspeak: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  []string // readings that must be among the variants
+		not   []string // readings that mustn't
+	}{
+		{name: "plain", input: "sh1t", want: []string{"sh1t", "shit", "shlt"}},
+		{name: "every substitution", input: "5h17", want: []string{"shit"}},
+		{name: "mixed alternatives", input: "k1ll 1ll1", want: []string{"kill", "illi", "illl"}},
+		{name: "mixed in one word", input: "1d1l", want: []string{"idil", "ldil", "idll"}},
+		{name: "numbers", input: "455 4ss", want: []string{"455 ass"}, not: []string{"ass ass"}},
+		{name: "numbers with punctuation", input: "1,000", not: []string{"i,ooo", "l,ooo"}},
+		{name: "only symbols", input: "@$$", want: []string{"ass"}},
+		{name: "digits and symbols", input: "4$$", want: []string{"ass"}},
+	}
+	for _, test := range tests {
+		variants := substitutions.variants(test.input)
+		for _, want := range test.want {
+			if !slices.ContainsFunc(variants, func(variant string) bool { return containsWord(variant, want) }) {
+				t.Errorf("%s: variants(%q) = %q, want a reading with %q", test.name, test.input, variants, want)
+			}
+		}
+		for _, not := range test.not {
+			if slices.Contains(variants, not) {
+				t.Errorf("%s: variants(%q) = %q, want no %q", test.name, test.input, variants, not)
+			}
+		}
+	}
+}
+
+func TestLeetspeakVariantsAreBounded(t *testing.T) {
+	substitutions, err := parseLeetspeak(defaultLeetspeak)
+	if err != nil {
+		t.Fatalf("parseLeetspeak: %v", err)
+	}
+
+	variants := substitutions.variants("a1111111111111111")
+	if len(variants) != maxLeetspeakReadings {
+		t.Errorf("got %d readings, want %d", len(variants), maxLeetspeakReadings)
+	}
+	if !slices.Contains(variants, "aiiiiiiiiiiiiiiii") || !slices.Contains(variants, "allllllllllllllll") {
+		t.Errorf("variants = %q, want each letter read throughout", variants)
+	}
+}
+
+// containsWord says whether the word is one of the words of the text, or the whole of it
+func containsWord(text, word string) bool {
+	return slices.Contains(append(strings.Fields(text), text), word)
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"log/slog"
+	"time"
+)
+
+const retentionActor = "retention"
+
+// RetentionService periodically purges cards that have been in the trash for too long
+type RetentionService struct {
+	log          *slog.Logger
+	cardService  *CardService
+	eventService *EventService
+	retention    time.Duration
+	interval     time.Duration
+}
+
+func NewRetentionService(
+	log *slog.Logger,
+	cardService *CardService,
+	eventService *EventService,
+	config *Config,
+) *RetentionService {
+	return &RetentionService{
+		log:          log,
+		cardService:  cardService,
+		eventService: eventService,
+		retention:    time.Duration(config.TrashRetentionDays) * 24 * time.Hour,
+		interval:     time.Hour,
+	}
+}
+
+// Start runs the purge in the background; a retention of zero keeps trashed cards forever
+func (s *RetentionService) Start() {
+	if s.retention <= 0 {
+		s.log.Info("Trash retention disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		s.PurgeExpired()
+		for range ticker.C {
+			s.PurgeExpired()
+		}
+	}()
+}
+
+func (s *RetentionService) PurgeExpired() {
+	for _, cardID := range s.cardService.GetExpiredTrash(time.Now().Add(-s.retention)) {
+		if err := s.cardService.PurgeCard(cardID); err != nil {
+			s.log.Error("Failed to purge card", "cardID", cardID, "error", err)
+			continue
+		}
+		s.log.Info("Purged card from trash", "cardID", cardID)
+		s.eventService.PublishCardPurged(retentionActor, cardID)
+	}
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// HTMLSanitiser strips everything from an HTML fragment that is not on its allow-list
+type HTMLSanitiser struct {
+	elements map[string][]string // tag -> allowed attributes
+	discard  []string            // tags whose content is dropped along with the tag
+	schemes  []string
+}
+
+func NewHTMLSanitiser() *HTMLSanitiser {
+	return &HTMLSanitiser{
+		elements: map[string][]string{
+			"p":      {},
+			"br":     {},
+			"ul":     {},
+			"ol":     {},
+			"li":     {"class"},
+			"pre":    {},
+			"code":   {},
+			"strong": {},
+			"em":     {},
+			"a":      {"href", "title"},
+			"input":  {"type", "checked"},
+		},
+		discard: []string{"script", "style", "iframe", "object", "embed", "template", "svg", "math", "noscript", "textarea", "title"},
+		schemes: []string{"http", "https", "mailto"},
+	}
+}
+
+// Sanitise re-serialises the fragment keeping only allowed elements, attributes and URLs
+func (s *HTMLSanitiser) Sanitise(input string) string {
+	var result strings.Builder
+	var open []string
+	discarding := 0
+
+	tokenizer := html.NewTokenizer(strings.NewReader(input))
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			if tokenizer.Err() != io.EOF {
+				return ""
+			}
+			break
+		}
+
+		token := tokenizer.Token()
+		switch tokenType {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if slices.Contains(s.discard, token.Data) {
+				if tokenType == html.StartTagToken {
+					discarding++
+				}
+				continue
+			}
+			if discarding > 0 {
+				continue
+			}
+			if allowed, exists := s.elements[token.Data]; exists {
+				result.WriteString(s.startTag(token, allowed))
+				if tokenType == html.StartTagToken && !isVoidElement(token.Data) {
+					open = append(open, token.Data)
+				}
+			}
+		case html.EndTagToken:
+			if slices.Contains(s.discard, token.Data) {
+				if discarding > 0 {
+					discarding--
+				}
+				continue
+			}
+			if discarding > 0 {
+				continue
+			}
+			// Only close elements we opened, closing anything left unbalanced in between
+			if i := slices.Index(open, token.Data); i >= 0 {
+				for j := len(open) - 1; j >= i; j-- {
+					result.WriteString("</" + open[j] + ">")
+				}
+				open = open[:i]
+			}
+		case html.TextToken:
+			if discarding == 0 {
+				result.WriteString(html.EscapeString(token.Data))
+			}
+		}
+	}
+
+	for i := len(open) - 1; i >= 0; i-- {
+		result.WriteString("</" + open[i] + ">")
+	}
+
+	return result.String()
+}
+
+func (s *HTMLSanitiser) startTag(token html.Token, allowed []string) string {
+	var attrs []html.Attribute
+	for _, attr := range token.Attr {
+		if attr.Namespace != "" || !slices.Contains(allowed, attr.Key) {
+			continue
+		}
+		if attr.Key == "href" && !s.isSafeURL(attr.Val) {
+			continue
+		}
+		if attr.Key == "type" && attr.Val != "checkbox" {
+			continue
+		}
+		if attr.Key == "class" && attr.Val != "task" {
+			continue
+		}
+		attrs = append(attrs, attr)
+	}
+
+	switch token.Data {
+	case "a":
+		attrs = append(attrs,
+			html.Attribute{Key: "rel", Val: "nofollow noopener noreferrer"},
+			html.Attribute{Key: "target", Val: "_blank"},
+		)
+	case "input":
+		// Inputs are only ever rendered as read-only checkboxes
+		attrs = append(attrs, html.Attribute{Key: "disabled"})
+		if !slices.ContainsFunc(attrs, func(attr html.Attribute) bool { return attr.Key == "type" }) {
+			attrs = append(attrs, html.Attribute{Key: "type", Val: "checkbox"})
+		}
+	}
+
+	sanitised := html.Token{Type: html.StartTagToken, Data: token.Data, Attr: attrs}
+	return sanitised.String()
+}
+
+func (s *HTMLSanitiser) isSafeURL(raw string) bool {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return false
+	}
+	return slices.Contains(s.schemes, strings.ToLower(parsed.Scheme))
+}
+
+func isVoidElement(tag string) bool {
+	return tag == "br" || tag == "input"
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scheduleHorizon bounds the search for the next run, so an expression that can never match doesn't loop forever
+const scheduleHorizon = 5 * 366 * 24 * time.Hour
+
+// scheduleField is the set of values a cron field allows, as a bitmask
+type scheduleField uint64
+
+func (f scheduleField) has(value int) bool {
+	return f&(1<<uint(value)) != 0
+}
+
+type scheduleFieldRange struct {
+	name     string
+	min, max int
+}
+
+var scheduleFieldRanges = []scheduleFieldRange{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+var scheduleAliases = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// Schedule is a cron-like expression of minute, hour, day of month, month and day of week
+type Schedule struct {
+	Expression string
+
+	minutes, hours, days, months, weekdays scheduleField
+	anyDay, anyWeekday                     bool
+}
+
+// DailySchedule runs every day at the given time
+func DailySchedule(hour, minute int) *Schedule {
+	schedule, _ := ParseSchedule(fmt.Sprintf("%d %d * * *", minute, hour))
+	return schedule
+}
+
+// WeeklySchedule runs once a week on the given day at the given time
+func WeeklySchedule(weekday time.Weekday, hour, minute int) *Schedule {
+	schedule, _ := ParseSchedule(fmt.Sprintf("%d %d * * %d", minute, hour, weekday))
+	return schedule
+}
+
+// ParseSchedule parses a five-field cron expression; each field takes *, numbers, ranges, lists and steps
+func ParseSchedule(expression string) (*Schedule, error) {
+	expression = strings.TrimSpace(expression)
+	fieldsExpression := expression
+	if alias, exists := scheduleAliases[expression]; exists {
+		fieldsExpression = alias
+	}
+
+	fields := strings.Fields(fieldsExpression)
+	if len(fields) != len(scheduleFieldRanges) {
+		return nil, fmt.Errorf("schedule needs %d fields, got %d", len(scheduleFieldRanges), len(fields))
+	}
+
+	var parsed [5]scheduleField
+	for i, field := range fields {
+		value, err := parseScheduleField(field, scheduleFieldRanges[i])
+		if err != nil {
+			return nil, err
+		}
+		parsed[i] = value
+	}
+
+	return &Schedule{
+		Expression: expression,
+		minutes:    parsed[0],
+		hours:      parsed[1],
+		days:       parsed[2],
+		months:     parsed[3],
+		weekdays:   parsed[4],
+		anyDay:     fields[2] == "*",
+		anyWeekday: fields[4] == "*",
+	}, nil
+}
+
+func parseScheduleField(field string, valid scheduleFieldRange) (scheduleField, error) {
+	var result scheduleField
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if base, stepString, found := strings.Cut(part, "/"); found {
+			var err error
+			step, err = strconv.Atoi(stepString)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q in %s", stepString, valid.name)
+			}
+			part = base
+		}
+
+		low, high := valid.min, valid.max
+		if part != "*" {
+			lowString, highString, isRange := strings.Cut(part, "-")
+			var err error
+			if low, err = strconv.Atoi(lowString); err != nil {
+				return 0, fmt.Errorf("invalid %s %q", valid.name, part)
+			}
+			high = low
+			if isRange {
+				if high, err = strconv.Atoi(highString); err != nil {
+					return 0, fmt.Errorf("invalid %s %q", valid.name, part)
+				}
+			}
+		}
+
+		if low < valid.min || high > valid.max || low > high {
+			return 0, fmt.Errorf("%s %q is out of range %d-%d", valid.name, part, valid.min, valid.max)
+		}
+		for value := low; value <= high; value += step {
+			result |= 1 << uint(value)
+		}
+	}
+	return result, nil
+}
+
+// Next returns the first time after the given one that the schedule matches, or the zero time if there is none
+func (s *Schedule) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := after.Add(scheduleHorizon)
+
+	for t.Before(limit) {
+		if !s.months.has(int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.hours.has(t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.minutes.has(t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// matchesDay follows cron: when both day fields are restricted, either one matching is enough
+func (s *Schedule) matchesDay(t time.Time) bool {
+	day := s.days.has(t.Day())
+	weekday := s.weekdays.has(int(t.Weekday()))
+
+	switch {
+	case s.anyDay && s.anyWeekday:
+		return true
+	case s.anyDay:
+		return weekday
+	case s.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
+
+// Describe puts the common daily and weekly schedules into words
+func (s *Schedule) Describe() string {
+	fields := strings.Fields(s.Expression)
+	if len(fields) != 5 || fields[2] != "*" || fields[3] != "*" {
+		return "Cron " + s.Expression
+	}
+
+	minute, minuteErr := strconv.Atoi(fields[0])
+	hour, hourErr := strconv.Atoi(fields[1])
+	if minuteErr != nil || hourErr != nil {
+		return "Cron " + s.Expression
+	}
+
+	at := fmt.Sprintf("%02d:%02d", hour, minute)
+	if fields[4] == "*" {
+		return "Every day at " + at
+	}
+	if weekday, err := strconv.Atoi(fields[4]); err == nil && weekday >= 0 && weekday <= 6 {
+		return fmt.Sprintf("Every %s at %s", time.Weekday(weekday), at)
+	}
+	return "Cron " + s.Expression
+}
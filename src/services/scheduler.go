@@ -0,0 +1,49 @@
+package services
+
+import (
+	"log/slog"
+	"time"
+)
+
+const schedulerActor = "scheduler"
+
+// SchedulerService creates cards from templates as their recurrences come due
+type SchedulerService struct {
+	log             *slog.Logger
+	templateService *TemplateService
+	eventService    *EventService
+	interval        time.Duration
+}
+
+func NewSchedulerService(log *slog.Logger, templateService *TemplateService, eventService *EventService) *SchedulerService {
+	return &SchedulerService{
+		log:             log,
+		templateService: templateService,
+		eventService:    eventService,
+		interval:        time.Minute,
+	}
+}
+
+// Start checks for due recurrences in the background; schedules have minute resolution, so that's how often
+func (s *SchedulerService) Start() {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for now := range ticker.C {
+			s.RunDue(now)
+		}
+	}()
+}
+
+func (s *SchedulerService) RunDue(now time.Time) {
+	for _, recurrence := range s.templateService.TakeDueRecurrences(now) {
+		card, err := s.templateService.CreateFromTemplate(recurrence.TemplateID, recurrence.ColumnID, recurrence.LaneID, now)
+		if err != nil {
+			s.log.Error("Failed to create recurring card", "recurrenceID", recurrence.ID, "templateID", recurrence.TemplateID, "error", err)
+			continue
+		}
+		s.log.Info("Created recurring card", "recurrenceID", recurrence.ID, "cardID", card.ID)
+		s.eventService.PublishCardChanged(schedulerActor, *card)
+	}
+}
@@ -0,0 +1,360 @@
+package services
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const snippetLength = 160
+
+// SearchSegment is a run of text that is either all match or no match, for highlighting
+type SearchSegment struct {
+	Text  string `json:"text"`
+	Match bool   `json:"match"`
+}
+
+type SearchResult struct {
+	Card        Card            `json:"-"`
+	ColumnTitle string          `json:"column"`
+	LaneTitle   string          `json:"lane"`
+	Title       []SearchSegment `json:"title"`
+	Snippet     []SearchSegment `json:"snippet"`
+}
+
+// SearchQuery is a parsed search: every term and phrase must match, as must the qualifiers
+type SearchQuery struct {
+	Terms   []string
+	Phrases [][]string
+	Columns []string
+	Labels  []string
+}
+
+func (q *SearchQuery) IsEmpty() bool {
+	return len(q.Terms) == 0 && len(q.Phrases) == 0 && len(q.Columns) == 0 && len(q.Labels) == 0
+}
+
+// highlightTerms returns every term worth highlighting, including those in phrases
+func (q *SearchQuery) highlightTerms() []string {
+	terms := slices.Clone(q.Terms)
+	for _, phrase := range q.Phrases {
+		terms = append(terms, phrase...)
+	}
+	return terms
+}
+
+// searchToken is a normalised word and where it came from in the original text
+type searchToken struct {
+	Term  string
+	Start int
+	End   int
+}
+
+type searchDocument struct {
+	fields [][]searchToken // title, content, labels, then one per comment
+	labels []string
+}
+
+// SearchIndex is an inverted index from normalised terms to the cards that contain them
+type SearchIndex struct {
+	postings  map[string]map[int]struct{} // term -> set of cardIDs
+	documents map[int]*searchDocument     // cardID -> document
+}
+
+func NewSearchIndex() *SearchIndex {
+	return &SearchIndex{
+		postings:  make(map[string]map[int]struct{}),
+		documents: make(map[int]*searchDocument),
+	}
+}
+
+// Index replaces whatever was indexed for the card
+func (s *SearchIndex) Index(card *Card, comments []Comment) {
+	s.Remove(card.ID)
+
+	document := &searchDocument{}
+	texts := []string{card.Title, card.Content, strings.Join(card.Labels, " ")}
+	for _, comment := range comments {
+		texts = append(texts, comment.Body)
+	}
+	for _, text := range texts {
+		document.fields = append(document.fields, s.tokenize(text))
+	}
+	for _, label := range card.Labels {
+		document.labels = append(document.labels, s.normalize(label))
+	}
+
+	for _, field := range document.fields {
+		for _, token := range field {
+			if s.postings[token.Term] == nil {
+				s.postings[token.Term] = make(map[int]struct{})
+			}
+			s.postings[token.Term][card.ID] = struct{}{}
+		}
+	}
+	s.documents[card.ID] = document
+}
+
+func (s *SearchIndex) Remove(cardID int) {
+	document, exists := s.documents[cardID]
+	if !exists {
+		return
+	}
+
+	for _, field := range document.fields {
+		for _, token := range field {
+			delete(s.postings[token.Term], cardID)
+			if len(s.postings[token.Term]) == 0 {
+				delete(s.postings, token.Term)
+			}
+		}
+	}
+	delete(s.documents, cardID)
+}
+
+// Match returns the IDs of cards containing every term and phrase and carrying every label, in ID order
+func (s *SearchIndex) Match(query *SearchQuery) []int {
+	var candidates map[int]struct{}
+	if len(query.Terms) == 0 && len(query.Phrases) == 0 {
+		candidates = make(map[int]struct{}, len(s.documents))
+		for cardID := range s.documents {
+			candidates[cardID] = struct{}{}
+		}
+	}
+
+	required := query.highlightTerms()
+	for _, term := range required {
+		postings := s.postings[term]
+		if candidates == nil {
+			candidates = make(map[int]struct{}, len(postings))
+			for cardID := range postings {
+				candidates[cardID] = struct{}{}
+			}
+			continue
+		}
+		for cardID := range candidates {
+			if _, exists := postings[cardID]; !exists {
+				delete(candidates, cardID)
+			}
+		}
+	}
+
+	var matches []int
+	for cardID := range candidates {
+		document := s.documents[cardID]
+		if !document.hasLabels(query.Labels) || !document.hasPhrases(query.Phrases) {
+			continue
+		}
+		matches = append(matches, cardID)
+	}
+	sort.Ints(matches)
+	return matches
+}
+
+func (d *searchDocument) hasLabels(labels []string) bool {
+	for _, label := range labels {
+		if !slices.Contains(d.labels, label) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasPhrases checks that each phrase appears as consecutive words within a single field
+func (d *searchDocument) hasPhrases(phrases [][]string) bool {
+	for _, phrase := range phrases {
+		found := false
+		for _, field := range d.fields {
+			if findPhrase(field, phrase) >= 0 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func findPhrase(tokens []searchToken, phrase []string) int {
+	for i := 0; i+len(phrase) <= len(tokens); i++ {
+		matched := true
+		for j, term := range phrase {
+			if tokens[i+j].Term != term {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return i
+		}
+	}
+	return -1
+}
+
+// ParseQuery splits a query into terms, "quoted phrases" and column:/label: qualifiers
+func (s *SearchIndex) ParseQuery(input string) *SearchQuery {
+	query := &SearchQuery{}
+
+	for _, part := range splitQuery(input) {
+		quoted := strings.HasPrefix(part, `"`)
+		value := strings.Trim(part, `"`)
+
+		if !quoted {
+			if qualifier, rest, found := strings.Cut(value, ":"); found && rest != "" {
+				rest = strings.Trim(rest, `"`)
+				switch strings.ToLower(qualifier) {
+				case "column":
+					query.Columns = append(query.Columns, s.normalize(rest))
+					continue
+				case "label":
+					query.Labels = append(query.Labels, s.normalize(rest))
+					continue
+				}
+			}
+		}
+
+		var terms []string
+		for _, token := range s.tokenize(value) {
+			terms = append(terms, token.Term)
+		}
+		if quoted && len(terms) > 1 {
+			query.Phrases = append(query.Phrases, terms)
+		} else {
+			query.Terms = append(query.Terms, terms...)
+		}
+	}
+
+	return query
+}
+
+// splitQuery splits on whitespace, keeping quoted text together, including after a qualifier
+func splitQuery(input string) []string {
+	var parts []string
+	var current strings.Builder
+	inQuotes := false
+
+	for _, r := range input {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			current.WriteRune(r)
+		case unicode.IsSpace(r) && !inQuotes:
+			if current.Len() > 0 {
+				parts = append(parts, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		parts = append(parts, current.String())
+	}
+	return parts
+}
+
+// tokenize splits text into words, folding accents and case so that "Café" finds "cafe"
+func (s *SearchIndex) tokenize(text string) []searchToken {
+	var tokens []searchToken
+	start := -1
+
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		if term := s.normalize(text[start:end]); term != "" {
+			tokens = append(tokens, searchToken{Term: term, Start: start, End: end})
+		}
+		start = -1
+	}
+
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+		} else {
+			flush(i)
+		}
+	}
+	flush(len(text))
+	return tokens
+}
+
+// normalize folds a word the way the blacklist does, see WordService.normalizeToASCII, except that lookalikes
+// from other scripts are left alone. The blacklist reads Cyrillic о as o to catch words disguised with it, but
+// here that would turn real Russian or Greek words into fragments of Latin ones, so they are indexed as written.
+func (s *SearchIndex) normalize(word string) string {
+	word = strings.TrimSpace(word)
+	if folded := foldToASCII(word, false); folded != "" {
+		return strings.ToLower(folded)
+	}
+	// Scripts with no ASCII equivalent are still searchable, just without folding
+	return strings.ToLower(word)
+}
+
+// Highlight splits text into segments, marking the words that match any of the terms
+func (s *SearchIndex) Highlight(text string, terms []string) []SearchSegment {
+	var segments []SearchSegment
+	last := 0
+	for _, token := range s.tokenize(text) {
+		if !slices.Contains(terms, token.Term) {
+			continue
+		}
+		if token.Start > last {
+			segments = append(segments, SearchSegment{Text: text[last:token.Start]})
+		}
+		segments = append(segments, SearchSegment{Text: text[token.Start:token.End], Match: true})
+		last = token.End
+	}
+	if last < len(text) {
+		segments = append(segments, SearchSegment{Text: text[last:]})
+	}
+	return segments
+}
+
+// Snippet returns a highlighted excerpt of the first text that matches, or the start of the first text
+func (s *SearchIndex) Snippet(texts []string, terms []string) []SearchSegment {
+	for _, text := range texts {
+		for _, token := range s.tokenize(text) {
+			if !slices.Contains(terms, token.Term) {
+				continue
+			}
+			start := max(0, token.Start-snippetLength/2)
+			end := min(len(text), start+snippetLength)
+			return s.Highlight(excerpt(text, start, end), terms)
+		}
+	}
+
+	if len(texts) == 0 {
+		return nil
+	}
+	return s.Highlight(excerpt(texts[0], 0, min(len(texts[0]), snippetLength)), terms)
+}
+
+// excerpt cuts text at word boundaries near the given byte offsets, adding ellipses where it was cut
+func excerpt(text string, start, end int) string {
+	for start > 0 && !isSpaceByte(text[start-1]) {
+		start--
+	}
+	for end < len(text) && !isSpaceByte(text[end]) {
+		end++
+	}
+
+	result := strings.TrimSpace(text[start:end])
+	if start > 0 {
+		result = "…" + result
+	}
+	if end < len(text) {
+		result += "…"
+	}
+	return result
+}
+
+// isSpaceByte only looks at ASCII whitespace, so it never splits a multi-byte character
+func isSpaceByte(b byte) bool {
+	return b == ' ' || b == '\n' || b == '\t' || b == '\r'
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const sessionCookieName = "mesh-session"
+
+type Session struct {
+	ID        string
+	Name      string
+	CreatedAt time.Time
+	IsAdmin   bool
+}
+
+// SessionService identifies each browser with a cookie so actions can be attributed to someone
+type SessionService struct {
+	mu       sync.Mutex
+	sessions map[string]*Session // sessionID -> Session
+
+	log        *slog.Logger
+	adminToken string
+}
+
+func NewSessionService(log *slog.Logger, config *Config) *SessionService {
+	return &SessionService{
+		sessions:   make(map[string]*Session),
+		log:        log,
+		adminToken: config.AdminToken,
+	}
+}
+
+// Session returns the session for the request, starting a new one if the client doesn't have one yet
+func (s *SessionService) Session(w http.ResponseWriter, r *http.Request) *Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		if session, exists := s.sessions[cookie.Value]; exists {
+			return session
+		}
+	}
+
+	id := uuid.New().String()
+	session := &Session{
+		ID:        id,
+		Name:      "guest-" + id[:6],
+		CreatedAt: time.Now(),
+	}
+	s.sessions[id] = session
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    id,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	s.log.Info("Started session", "sessionID", id, "name", session.Name)
+	return session
+}
+
+// IsAdminToken says whether the token grants admin access, which it never does without a configured token
+func (s *SessionService) IsAdminToken(token string) bool {
+	return s.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) == 1
+}
+
+// Elevate makes the session an admin if the token matches; admin access is disabled without a configured token
+func (s *SessionService) Elevate(session *Session, token string) bool {
+	if !s.IsAdminToken(token) {
+		s.log.Info("Refused admin access", "sessionID", session.ID)
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session.IsAdmin = true
+	s.log.Info("Granted admin access", "sessionID", session.ID, "name", session.Name)
+	return true
+}
+
+func (s *SessionService) Demote(session *Session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session.IsAdmin = false
+}
@@ -24,42 +24,102 @@ type UpdateBatch struct {
 	Updates []BatchedUpdate `json:"updates"`
 }
 
+// sessionStreamPrefix names the stream each session's clients listen on, so updates can be tailored to it
+const sessionStreamPrefix = "session-"
+
 type SSEService struct {
 	log            *slog.Logger
 	server         *sse.Server
-	pendingUpdates []BatchedUpdate
+	sessionService *SessionService
+	pendingUpdates map[string][]BatchedUpdate // streamID -> updates
 	batchMutex     sync.Mutex
 	batchTimer     *time.Timer
 	batchDuration  time.Duration
+	streams        map[string]int // streamID -> connected clients
+	streamsMutex   sync.Mutex
 }
 
-func NewSSEService(log *slog.Logger) *SSEService {
+func NewSSEService(log *slog.Logger, sessionService *SessionService) *SSEService {
 	server := sse.New()
 
 	server.AutoReplay = false
 	server.AutoStream = true
 
+	s := &SSEService{
+		log:            log,
+		server:         server,
+		sessionService: sessionService,
+		pendingUpdates: make(map[string][]BatchedUpdate),
+		batchDuration:  50 * time.Millisecond,
+		streams:        make(map[string]int),
+	}
+
 	server.OnSubscribe = func(streamID string, sub *sse.Subscriber) {
 		log.Info("SSE client connected", "streamID", streamID)
+		s.streamsMutex.Lock()
+		defer s.streamsMutex.Unlock()
+		s.streams[streamID]++
 	}
 
 	server.OnUnsubscribe = func(streamID string, sub *sse.Subscriber) {
 		log.Info("SSE client disconnected", "streamID", streamID)
+		s.streamsMutex.Lock()
+		defer s.streamsMutex.Unlock()
+		if s.streams[streamID]--; s.streams[streamID] <= 0 {
+			delete(s.streams, streamID)
+		}
 	}
 
-	return &SSEService{
-		log:           log,
-		server:        server,
-		batchDuration: 50 * time.Millisecond,
-	}
+	return s
 }
 
+// BroadcastOOBUpdate sends the same update to every connected client
 func (s *SSEService) BroadcastOOBUpdate(component templ.Component) {
+	update, ok := s.renderUpdate(component)
+	if !ok {
+		return
+	}
+
+	for _, streamID := range s.connectedStreams() {
+		s.addToBatch(streamID, update)
+	}
+}
+
+// BroadcastOOBUpdatePerSession renders the update separately for each connected session, for views that differ
+// between sessions
+func (s *SSEService) BroadcastOOBUpdatePerSession(render func(sessionID string) templ.Component) {
+	for _, streamID := range s.connectedStreams() {
+		update, ok := s.renderUpdate(render(strings.TrimPrefix(streamID, sessionStreamPrefix)))
+		if ok {
+			s.addToBatch(streamID, update)
+		}
+	}
+}
+
+// SendOOBUpdate sends an update to the clients of a single session
+func (s *SSEService) SendOOBUpdate(sessionID string, component templ.Component) {
+	if update, ok := s.renderUpdate(component); ok {
+		s.addToBatch(sessionStreamPrefix+sessionID, update)
+	}
+}
+
+func (s *SSEService) connectedStreams() []string {
+	s.streamsMutex.Lock()
+	defer s.streamsMutex.Unlock()
+
+	streamIDs := make([]string, 0, len(s.streams))
+	for streamID := range s.streams {
+		streamIDs = append(streamIDs, streamID)
+	}
+	return streamIDs
+}
+
+func (s *SSEService) renderUpdate(component templ.Component) (BatchedUpdate, bool) {
 	var buf strings.Builder
 	err := component.Render(context.Background(), &buf)
 	if err != nil {
 		s.log.Error("Failed to render component for SSE broadcast", "error", err)
-		return
+		return BatchedUpdate{}, false
 	}
 
 	html := buf.String()
@@ -73,34 +133,33 @@ func (s *SSEService) BroadcastOOBUpdate(component templ.Component) {
 	}
 	if componentID == "?" {
 		s.log.Error("Failed to find component ID for OOB update")
-		return
+		return BatchedUpdate{}, false
 	}
 
 	s.log.Info("Queueing OOB update for batch", "componentID", componentID)
 
-	update := BatchedUpdate{
+	return BatchedUpdate{
 		ID:   componentID,
 		HTML: html,
-	}
-
-	s.addToBatch(update)
+	}, true
 }
 
-func (s *SSEService) addToBatch(update BatchedUpdate) {
+func (s *SSEService) addToBatch(streamID string, update BatchedUpdate) {
 	s.batchMutex.Lock()
 	defer s.batchMutex.Unlock()
 
 	found := false
-	for i, existing := range s.pendingUpdates {
+	pending := s.pendingUpdates[streamID]
+	for i, existing := range pending {
 		if existing.ID == update.ID {
-			s.pendingUpdates[i] = update
+			pending[i] = update
 			found = true
 			break
 		}
 	}
 
 	if !found {
-		s.pendingUpdates = append(s.pendingUpdates, update)
+		s.pendingUpdates[streamID] = append(pending, update)
 	}
 
 	if s.batchTimer != nil {
@@ -119,32 +178,39 @@ func (s *SSEService) flushBatch() {
 		return
 	}
 
-	updates := make([]BatchedUpdate, len(s.pendingUpdates))
-	copy(updates, s.pendingUpdates)
-	s.pendingUpdates = s.pendingUpdates[:0] // Clear the slice
+	pendingUpdates := s.pendingUpdates
+	s.pendingUpdates = make(map[string][]BatchedUpdate)
 	s.batchMutex.Unlock()
 
-	batch := UpdateBatch{
-		BatchID: uuid.New().String(),
-		Updates: updates,
-	}
-
-	batchData, err := json.Marshal(batch)
-	if err != nil {
-		s.log.Error("Failed to serialize batch", "error", err)
-		return
-	}
+	for streamID, updates := range pendingUpdates {
+		batch := UpdateBatch{
+			BatchID: uuid.New().String(),
+			Updates: updates,
+		}
 
-	s.log.Info("Broadcasting batch", "batchID", batch.BatchID, "updateCount", len(updates))
+		batchData, err := json.Marshal(batch)
+		if err != nil {
+			s.log.Error("Failed to serialize batch", "error", err)
+			continue
+		}
 
-	s.server.Publish("oob-updates", &sse.Event{
-		Event: []byte("oob-batch"),
-		Data:  batchData,
-	})
+		s.log.Info("Broadcasting batch", "batchID", batch.BatchID, "streamID", streamID, "updateCount", len(updates))
 
-	s.log.Debug("Broadcasted batch to all clients", "batchID", batch.BatchID, "count", len(updates))
+		// Publishing to a stream that has gone away since the update was queued does nothing
+		s.server.Publish(streamID, &sse.Event{
+			Event: []byte("oob-batch"),
+			Data:  batchData,
+		})
+	}
 }
 
+// ServeSSE subscribes the client to its session's stream, whatever stream it asked for
 func (s *SSEService) ServeSSE(w http.ResponseWriter, r *http.Request) {
+	session := s.sessionService.Session(w, r)
+
+	query := r.URL.Query()
+	query.Set("stream", sessionStreamPrefix+session.ID)
+	r.URL.RawQuery = query.Encode()
+
 	s.server.ServeHTTP(w, r)
 }
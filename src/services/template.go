@@ -0,0 +1,283 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CardTemplate is the skeleton of a card that gets created over and over again
+type CardTemplate struct {
+	ID        int
+	BoardID   int
+	Name      string
+	Title     string
+	Content   string
+	Labels    []string
+	Checklist []string
+}
+
+// Instantiate fills in the template's placeholders, such as {{date}}, for the given time
+func (t *CardTemplate) Instantiate(now time.Time) CardDetails {
+	_, week := now.ISOWeek()
+	placeholders := strings.NewReplacer(
+		"{{date}}", now.Format(DueDateLayout),
+		"{{time}}", now.Format("15:04"),
+		"{{weekday}}", now.Weekday().String(),
+		"{{week}}", strconv.Itoa(week),
+		"{{month}}", now.Month().String(),
+		"{{year}}", strconv.Itoa(now.Year()),
+	)
+
+	content := placeholders.Replace(t.Content)
+	if len(t.Checklist) > 0 {
+		var checklist []string
+		for _, item := range t.Checklist {
+			checklist = append(checklist, "- [ ] "+placeholders.Replace(item))
+		}
+		content = strings.TrimSpace(content + "\n\n" + strings.Join(checklist, "\n"))
+	}
+
+	var labels []string
+	for _, label := range t.Labels {
+		labels = append(labels, placeholders.Replace(label))
+	}
+
+	return CardDetails{
+		Title:   placeholders.Replace(t.Title),
+		Content: content,
+		Labels:  labels,
+	}
+}
+
+// Recurrence creates a card from a template into a cell whenever its schedule comes round
+type Recurrence struct {
+	ID         int
+	TemplateID int
+	ColumnID   int
+	LaneID     int
+	Schedule   *Schedule
+	NextRun    time.Time
+	LastRun    time.Time
+}
+
+// TemplateService keeps each board's card templates and the recurrences that instantiate them
+type TemplateService struct {
+	mu          sync.RWMutex
+	templates   map[int]*CardTemplate // templateID -> CardTemplate
+	recurrences map[int]*Recurrence   // recurrenceID -> Recurrence
+
+	nextTemplateID   int
+	nextRecurrenceID int
+
+	log         *slog.Logger
+	cardService *CardService
+}
+
+func NewTemplateService(log *slog.Logger, cardService *CardService) *TemplateService {
+	return &TemplateService{
+		templates:        make(map[int]*CardTemplate),
+		recurrences:      make(map[int]*Recurrence),
+		nextTemplateID:   1,
+		nextRecurrenceID: 1,
+		log:              log,
+		cardService:      cardService,
+	}
+}
+
+func (t *TemplateService) AddTemplate(template CardTemplate) (*CardTemplate, error) {
+	if _, err := t.cardService.GetBoard(template.BoardID); err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	template.ID = t.nextTemplateID
+	t.templates[template.ID] = &template
+	t.nextTemplateID++
+
+	t.log.Info("Added card template", "templateID", template.ID, "boardID", template.BoardID, "name", template.Name)
+	return &template, nil
+}
+
+// UpdateTemplate replaces the template's contents; its board and recurrences stay the same
+func (t *TemplateService) UpdateTemplate(template CardTemplate) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	existing, exists := t.templates[template.ID]
+	if !exists {
+		return fmt.Errorf("template with ID %d not found", template.ID)
+	}
+
+	template.BoardID = existing.BoardID
+	*existing = template
+	return nil
+}
+
+// DeleteTemplate removes the template along with its recurrences
+func (t *TemplateService) DeleteTemplate(templateID int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, exists := t.templates[templateID]; !exists {
+		return fmt.Errorf("template with ID %d not found", templateID)
+	}
+
+	delete(t.templates, templateID)
+	for id, recurrence := range t.recurrences {
+		if recurrence.TemplateID == templateID {
+			delete(t.recurrences, id)
+		}
+	}
+	return nil
+}
+
+func (t *TemplateService) GetTemplate(templateID int) (*CardTemplate, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	template, exists := t.templates[templateID]
+	if !exists {
+		return nil, fmt.Errorf("template with ID %d not found", templateID)
+	}
+	copied := *template
+	return &copied, nil
+}
+
+// GetTemplates returns the board's templates sorted by name
+func (t *TemplateService) GetTemplates(boardID int) []CardTemplate {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var templates []CardTemplate
+	for _, template := range t.templates {
+		if template.BoardID == boardID {
+			templates = append(templates, *template)
+		}
+	}
+	slices.SortFunc(templates, func(a, b CardTemplate) int {
+		if order := strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)); order != 0 {
+			return order
+		}
+		return a.ID - b.ID
+	})
+	return templates
+}
+
+// CreateFromTemplate adds a card built from the template to the cell
+func (t *TemplateService) CreateFromTemplate(templateID, columnID, laneID int, now time.Time) (*Card, error) {
+	template, err := t.GetTemplate(templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	column, err := t.cardService.GetColumn(columnID)
+	if err != nil {
+		return nil, err
+	}
+	if column.Column.BoardID != template.BoardID {
+		return nil, fmt.Errorf("template %d belongs to another board", templateID)
+	}
+
+	return t.cardService.AddCard(template.Instantiate(now), columnID, laneID)
+}
+
+// AddRecurrence schedules the template to be instantiated into the cell, starting from the next matching time
+func (t *TemplateService) AddRecurrence(templateID, columnID, laneID int, schedule *Schedule, now time.Time) (*Recurrence, error) {
+	nextRun := schedule.Next(now)
+	if nextRun.IsZero() {
+		return nil, fmt.Errorf("schedule %q never runs", schedule.Expression)
+	}
+
+	template, err := t.GetTemplate(templateID)
+	if err != nil {
+		return nil, err
+	}
+	column, err := t.cardService.GetColumn(columnID)
+	if err != nil {
+		return nil, err
+	}
+	lane, err := t.cardService.GetLane(laneID)
+	if err != nil {
+		return nil, err
+	}
+	if column.Column.BoardID != template.BoardID || lane.BoardID != template.BoardID {
+		return nil, fmt.Errorf("recurrence must target the template's board")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	recurrence := &Recurrence{
+		ID:         t.nextRecurrenceID,
+		TemplateID: templateID,
+		ColumnID:   columnID,
+		LaneID:     laneID,
+		Schedule:   schedule,
+		NextRun:    nextRun,
+	}
+	t.recurrences[recurrence.ID] = recurrence
+	t.nextRecurrenceID++
+
+	t.log.Info("Added recurrence", "recurrenceID", recurrence.ID, "templateID", templateID, "schedule", schedule.Expression, "nextRun", nextRun)
+	return recurrence, nil
+}
+
+func (t *TemplateService) DeleteRecurrence(recurrenceID int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, exists := t.recurrences[recurrenceID]; !exists {
+		return fmt.Errorf("recurrence with ID %d not found", recurrenceID)
+	}
+	delete(t.recurrences, recurrenceID)
+	return nil
+}
+
+// GetRecurrences returns the template's recurrences, soonest first
+func (t *TemplateService) GetRecurrences(templateID int) []Recurrence {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var recurrences []Recurrence
+	for _, recurrence := range t.recurrences {
+		if recurrence.TemplateID == templateID {
+			recurrences = append(recurrences, *recurrence)
+		}
+	}
+	slices.SortFunc(recurrences, func(a, b Recurrence) int {
+		return a.NextRun.Compare(b.NextRun)
+	})
+	return recurrences
+}
+
+// TakeDueRecurrences returns the recurrences due by now and moves each on to its next run
+func (t *TemplateService) TakeDueRecurrences(now time.Time) []Recurrence {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var due []Recurrence
+	for id, recurrence := range t.recurrences {
+		if recurrence.NextRun.After(now) {
+			continue
+		}
+		due = append(due, *recurrence)
+
+		// Runs missed while the server was down are not caught up, there's just the one card
+		recurrence.LastRun = now
+		recurrence.NextRun = recurrence.Schedule.Next(now)
+		if recurrence.NextRun.IsZero() {
+			delete(t.recurrences, id)
+		}
+	}
+	slices.SortFunc(due, func(a, b Recurrence) int {
+		return a.ID - b.ID
+	})
+	return due
+}
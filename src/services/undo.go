@@ -0,0 +1,243 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+const undoLimit = 50
+
+// Operation is a reversible change to the board, recorded so that it can be undone and redone
+type Operation interface {
+	Undo(cardService *CardService, eventService *EventService, actor string) error
+	Redo(cardService *CardService, eventService *EventService, actor string) error
+	Describe() string
+}
+
+type CardAddedOperation struct {
+	Card Card
+}
+
+func (o *CardAddedOperation) Undo(c *CardService, e *EventService, actor string) error {
+	if err := c.DeleteCard(o.Card.ID); err != nil {
+		return err
+	}
+	e.PublishCardDeleted(actor, o.Card.ID, o.Card.Cell())
+	return nil
+}
+
+func (o *CardAddedOperation) Redo(c *CardService, e *EventService, actor string) error {
+	card, err := c.RestoreCard(o.Card.ID)
+	if err != nil {
+		return err
+	}
+	e.PublishCardRestored(actor, card.ID, card.Cell())
+	return nil
+}
+
+func (o *CardAddedOperation) Describe() string {
+	return fmt.Sprintf("add “%s”", o.Card.Title)
+}
+
+type CardUpdatedOperation struct {
+	Before Card
+	After  Card
+}
+
+func (o *CardUpdatedOperation) Undo(c *CardService, e *EventService, actor string) error {
+	if err := c.UpdateCard(o.Before.ID, o.Before.Details()); err != nil {
+		return err
+	}
+	card, err := c.GetCard(o.Before.ID)
+	if err != nil {
+		return err
+	}
+	e.PublishCardChanged(actor, *card)
+	return nil
+}
+
+func (o *CardUpdatedOperation) Redo(c *CardService, e *EventService, actor string) error {
+	if err := c.UpdateCard(o.After.ID, o.After.Details()); err != nil {
+		return err
+	}
+	card, err := c.GetCard(o.After.ID)
+	if err != nil {
+		return err
+	}
+	e.PublishCardChanged(actor, *card)
+	return nil
+}
+
+func (o *CardUpdatedOperation) Describe() string {
+	return fmt.Sprintf("edit “%s”", o.After.Title)
+}
+
+type CardMovedOperation struct {
+	CardID       int
+	Title        string
+	From         Cell
+	FromPosition int
+	To           Cell
+	ToPosition   int
+}
+
+func (o *CardMovedOperation) Undo(c *CardService, e *EventService, actor string) error {
+	if _, _, err := c.MoveCard(o.CardID, o.From.ColumnID, o.From.LaneID, o.FromPosition); err != nil {
+		return err
+	}
+	e.PublishCardMoved(actor, o.CardID, o.To, o.From)
+	return nil
+}
+
+func (o *CardMovedOperation) Redo(c *CardService, e *EventService, actor string) error {
+	if _, _, err := c.MoveCard(o.CardID, o.To.ColumnID, o.To.LaneID, o.ToPosition); err != nil {
+		return err
+	}
+	e.PublishCardMoved(actor, o.CardID, o.From, o.To)
+	return nil
+}
+
+func (o *CardMovedOperation) Describe() string {
+	return fmt.Sprintf("move “%s”", o.Title)
+}
+
+type CardDeletedOperation struct {
+	Card Card
+}
+
+func (o *CardDeletedOperation) Undo(c *CardService, e *EventService, actor string) error {
+	card, err := c.RestoreCard(o.Card.ID)
+	if err != nil {
+		return err
+	}
+	e.PublishCardRestored(actor, card.ID, card.Cell())
+	return nil
+}
+
+func (o *CardDeletedOperation) Redo(c *CardService, e *EventService, actor string) error {
+	if err := c.DeleteCard(o.Card.ID); err != nil {
+		return err
+	}
+	e.PublishCardDeleted(actor, o.Card.ID, o.Card.Cell())
+	return nil
+}
+
+func (o *CardDeletedOperation) Describe() string {
+	return fmt.Sprintf("delete “%s”", o.Card.Title)
+}
+
+type undoStacks struct {
+	undo []Operation
+	redo []Operation
+}
+
+// UndoService keeps an undo and redo stack for each session
+type UndoService struct {
+	mu     sync.Mutex
+	stacks map[string]*undoStacks // sessionID -> stacks
+
+	log          *slog.Logger
+	cardService  *CardService
+	eventService *EventService
+}
+
+func NewUndoService(log *slog.Logger, cardService *CardService, eventService *EventService) *UndoService {
+	return &UndoService{
+		stacks:       make(map[string]*undoStacks),
+		log:          log,
+		cardService:  cardService,
+		eventService: eventService,
+	}
+}
+
+func (u *UndoService) getStacks(sessionID string) *undoStacks {
+	stacks, exists := u.stacks[sessionID]
+	if !exists {
+		stacks = &undoStacks{}
+		u.stacks[sessionID] = stacks
+	}
+	return stacks
+}
+
+// Record pushes a new operation, which invalidates anything that could have been redone
+func (u *UndoService) Record(sessionID string, operation Operation) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	stacks := u.getStacks(sessionID)
+	stacks.undo = append(stacks.undo, operation)
+	if len(stacks.undo) > undoLimit {
+		stacks.undo = stacks.undo[len(stacks.undo)-undoLimit:]
+	}
+	stacks.redo = nil
+}
+
+func (u *UndoService) CanUndo(sessionID string) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	return len(u.getStacks(sessionID).undo) > 0
+}
+
+func (u *UndoService) CanRedo(sessionID string) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	return len(u.getStacks(sessionID).redo) > 0
+}
+
+// Undo reverts the session's most recent operation and returns it
+func (u *UndoService) Undo(sessionID, actor string) (Operation, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	stacks := u.getStacks(sessionID)
+	if len(stacks.undo) == 0 {
+		return nil, fmt.Errorf("nothing to undo")
+	}
+
+	operation := stacks.undo[len(stacks.undo)-1]
+	stacks.undo = stacks.undo[:len(stacks.undo)-1]
+
+	// If the board has moved on, e.g. someone else deleted the card, the operation is dropped. A full column
+	// only gets in the way until a card leaves it, so those operations stay to be tried again.
+	if err := operation.Undo(u.cardService, u.eventService, actor); err != nil {
+		var limitErr *WIPLimitError
+		if errors.As(err, &limitErr) {
+			stacks.undo = append(stacks.undo, operation)
+		}
+		u.log.Info("Could not undo operation", "sessionID", sessionID, "operation", operation.Describe(), "error", err)
+		return nil, fmt.Errorf("could not undo %s: %w", operation.Describe(), err)
+	}
+
+	stacks.redo = append(stacks.redo, operation)
+	return operation, nil
+}
+
+// Redo reapplies the session's most recently undone operation and returns it
+func (u *UndoService) Redo(sessionID, actor string) (Operation, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	stacks := u.getStacks(sessionID)
+	if len(stacks.redo) == 0 {
+		return nil, fmt.Errorf("nothing to redo")
+	}
+
+	operation := stacks.redo[len(stacks.redo)-1]
+	stacks.redo = stacks.redo[:len(stacks.redo)-1]
+
+	if err := operation.Redo(u.cardService, u.eventService, actor); err != nil {
+		var limitErr *WIPLimitError
+		if errors.As(err, &limitErr) {
+			stacks.redo = append(stacks.redo, operation)
+		}
+		u.log.Info("Could not redo operation", "sessionID", sessionID, "operation", operation.Describe(), "error", err)
+		return nil, fmt.Errorf("could not redo %s: %w", operation.Describe(), err)
+	}
+
+	stacks.undo = append(stacks.undo, operation)
+	return operation, nil
+}
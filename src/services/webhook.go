@@ -0,0 +1,546 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// WebhookSignatureHeader carries "sha256=" followed by the hex HMAC-SHA256 of the body, keyed with the secret
+	WebhookSignatureHeader = "X-Mesh-Signature"
+	WebhookEventHeader     = "X-Mesh-Event"
+	WebhookDeliveryHeader  = "X-Mesh-Delivery"
+
+	webhookDeliveryLimit = 500
+)
+
+// WebhookEvents are the events a webhook can subscribe to
+var WebhookEvents = []string{CardMovedEventKey, CardChangedEventKey, CardDeletedEventKey}
+
+type Webhook struct {
+	ID        int
+	BoardID   int
+	URL       string
+	Secret    string
+	Events    []string
+	CreatedAt time.Time
+}
+
+func (w *Webhook) Subscribes(eventKey string) bool {
+	return slices.Contains(w.Events, eventKey)
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	DeliveryDead      DeliveryStatus = "dead"
+)
+
+// WebhookDelivery is one event on its way to one webhook, kept after it's done as the delivery log
+type WebhookDelivery struct {
+	ID          int
+	WebhookID   int
+	BoardID     int
+	Event       string
+	Payload     []byte
+	Status      DeliveryStatus
+	Attempts    int
+	StatusCode  int
+	LastError   string
+	NextAttempt time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type WebhookCell struct {
+	ColumnID int `json:"columnID"`
+	LaneID   int `json:"laneID"`
+}
+
+type WebhookCard struct {
+	ID       int      `json:"id"`
+	Title    string   `json:"title"`
+	Content  string   `json:"content"`
+	ColumnID int      `json:"columnID"`
+	LaneID   int      `json:"laneID"`
+	Labels   []string `json:"labels"`
+	Assignee string   `json:"assignee,omitempty"`
+	DueAt    string   `json:"dueAt,omitempty"`
+}
+
+// WebhookPayload is the JSON body POSTed to webhooks
+type WebhookPayload struct {
+	Event      string       `json:"event"`
+	BoardID    int          `json:"boardID"`
+	Actor      string       `json:"actor"`
+	OccurredAt time.Time    `json:"occurredAt"`
+	Card       *WebhookCard `json:"card,omitempty"`
+	From       *WebhookCell `json:"from,omitempty"`
+	To         *WebhookCell `json:"to,omitempty"`
+}
+
+// SignWebhookPayload returns the signature header value receivers should compare against, in constant time
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookService sends board events to subscribed URLs, retrying failed deliveries with exponential backoff
+// until they succeed or run out of attempts and land in the dead letters
+type WebhookService struct {
+	mu         sync.Mutex
+	webhooks   map[int]*Webhook         // webhookID -> Webhook
+	deliveries map[int]*WebhookDelivery // deliveryID -> WebhookDelivery
+	order      []int                    // deliveryIDs, oldest first
+	sending    map[int]bool             // webhookIDs with deliveries in flight
+
+	nextWebhookID  int
+	nextDeliveryID int
+
+	log         *slog.Logger
+	cardService *CardService
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	interval    time.Duration
+	wake        chan struct{}
+}
+
+func NewWebhookService(log *slog.Logger, cardService *CardService, eventService *EventService, config *Config) *WebhookService {
+	service := &WebhookService{
+		webhooks:       make(map[int]*Webhook),
+		deliveries:     make(map[int]*WebhookDelivery),
+		sending:        make(map[int]bool),
+		nextWebhookID:  1,
+		nextDeliveryID: 1,
+		log:            log,
+		cardService:    cardService,
+		client:         &http.Client{Timeout: config.WebhookTimeout},
+		maxAttempts:    config.WebhookMaxAttempts,
+		backoff:        config.WebhookBackoff,
+		maxBackoff:     time.Hour,
+		interval:       time.Second,
+		wake:           make(chan struct{}, 1),
+	}
+
+	eventService.SubscribeCardMoved("webhooks", service.OnCardMoved)
+	eventService.SubscribeCardChanged("webhooks", service.OnCardChanged)
+	eventService.SubscribeCardDeleted("webhooks", service.OnCardDeleted)
+	return service
+}
+
+// Start delivers queued payloads in the background, as soon as they're queued and whenever a retry comes due
+func (s *WebhookService) Start() {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-s.wake:
+			}
+			go s.DeliverDue(time.Now())
+		}
+	}()
+}
+
+// AddWebhook subscribes the URL to the board's events, generating a secret if none is given
+func (s *WebhookService) AddWebhook(boardID int, rawURL, secret string, events []string) (*Webhook, error) {
+	if _, err := s.cardService.GetBoard(boardID); err != nil {
+		return nil, err
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("URL must be an absolute http or https URL")
+	}
+
+	if len(events) == 0 {
+		return nil, fmt.Errorf("choose at least one event")
+	}
+	for _, event := range events {
+		if !slices.Contains(WebhookEvents, event) {
+			return nil, fmt.Errorf("unknown event %q", event)
+		}
+	}
+
+	if secret == "" {
+		bytes := make([]byte, 16)
+		if _, err := rand.Read(bytes); err != nil {
+			return nil, err
+		}
+		secret = hex.EncodeToString(bytes)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	webhook := &Webhook{
+		ID:        s.nextWebhookID,
+		BoardID:   boardID,
+		URL:       parsed.String(),
+		Secret:    secret,
+		Events:    slices.Clone(events),
+		CreatedAt: time.Now(),
+	}
+	s.webhooks[webhook.ID] = webhook
+	s.nextWebhookID++
+
+	s.log.Info("Added webhook", "webhookID", webhook.ID, "boardID", boardID, "url", webhook.URL)
+	copied := *webhook
+	return &copied, nil
+}
+
+// DeleteWebhook removes the webhook; its delivery log stays, but pending deliveries are abandoned
+func (s *WebhookService) DeleteWebhook(webhookID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.webhooks[webhookID]; !exists {
+		return fmt.Errorf("webhook with ID %d not found", webhookID)
+	}
+	delete(s.webhooks, webhookID)
+	return nil
+}
+
+func (s *WebhookService) GetWebhook(webhookID int) (*Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	webhook, exists := s.webhooks[webhookID]
+	if !exists {
+		return nil, fmt.Errorf("webhook with ID %d not found", webhookID)
+	}
+	copied := *webhook
+	return &copied, nil
+}
+
+func (s *WebhookService) GetWebhooks(boardID int) []Webhook {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var webhooks []Webhook
+	for _, webhook := range s.webhooks {
+		if webhook.BoardID == boardID {
+			webhooks = append(webhooks, *webhook)
+		}
+	}
+	slices.SortFunc(webhooks, func(a, b Webhook) int {
+		return a.ID - b.ID
+	})
+	return webhooks
+}
+
+// GetDeliveries returns the board's most recent deliveries, newest first; an empty status matches every delivery
+func (s *WebhookService) GetDeliveries(boardID int, status DeliveryStatus, limit int) []WebhookDelivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deliveries []WebhookDelivery
+	for i := len(s.order) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		delivery := s.deliveries[s.order[i]]
+		if delivery.BoardID == boardID && (status == "" || delivery.Status == status) {
+			deliveries = append(deliveries, *delivery)
+		}
+	}
+	return deliveries
+}
+
+func (s *WebhookService) GetDelivery(deliveryID int) (*WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delivery, exists := s.deliveries[deliveryID]
+	if !exists {
+		return nil, fmt.Errorf("delivery with ID %d not found", deliveryID)
+	}
+	copied := *delivery
+	return &copied, nil
+}
+
+// Redeliver puts a dead letter back in the queue with a fresh set of attempts
+func (s *WebhookService) Redeliver(deliveryID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delivery, exists := s.deliveries[deliveryID]
+	if !exists {
+		return fmt.Errorf("delivery with ID %d not found", deliveryID)
+	}
+	if delivery.Status != DeliveryDead {
+		return fmt.Errorf("delivery %d is %s, only dead letters can be redelivered", deliveryID, delivery.Status)
+	}
+	if _, exists := s.webhooks[delivery.WebhookID]; !exists {
+		return fmt.Errorf("webhook with ID %d not found", delivery.WebhookID)
+	}
+
+	delivery.Status = DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttempt = time.Now()
+	s.signal()
+	return nil
+}
+
+func (s *WebhookService) OnCardMoved(event *CardMovedEvent) {
+	s.enqueue(CardMovedEventKey, event.Actor, event.ToColumnID, event.CardID, func(payload *WebhookPayload) {
+		payload.From = &WebhookCell{ColumnID: event.FromColumnID, LaneID: event.FromLaneID}
+		payload.To = &WebhookCell{ColumnID: event.ToColumnID, LaneID: event.ToLaneID}
+	})
+}
+
+// OnCardChanged sends the card as it was when it changed, not as it is by the time the event arrives
+func (s *WebhookService) OnCardChanged(event *CardChangedEvent) {
+	s.enqueue(CardChangedEventKey, event.Actor, event.Card.ColumnID, event.CardID, func(payload *WebhookPayload) {
+		payload.Card = newWebhookCard(event.Card)
+	})
+}
+
+func (s *WebhookService) OnCardDeleted(event *CardDeletedEvent) {
+	s.enqueue(CardDeletedEventKey, event.Actor, event.ColumnID, event.CardID, func(payload *WebhookPayload) {
+		payload.From = &WebhookCell{ColumnID: event.ColumnID, LaneID: event.LaneID}
+	})
+}
+
+// enqueue queues a delivery of the event for each of the board's webhooks that subscribes to it
+func (s *WebhookService) enqueue(eventKey, actor string, columnID, cardID int, extra func(payload *WebhookPayload)) {
+	column, err := s.cardService.GetColumn(columnID)
+	if err != nil {
+		return
+	}
+	boardID := column.Column.BoardID
+
+	var subscribed []Webhook
+	for _, webhook := range s.GetWebhooks(boardID) {
+		if webhook.Subscribes(eventKey) {
+			subscribed = append(subscribed, webhook)
+		}
+	}
+	if len(subscribed) == 0 {
+		return
+	}
+
+	payload := &WebhookPayload{
+		Event:      eventKey,
+		BoardID:    boardID,
+		Actor:      actor,
+		OccurredAt: time.Now().UTC(),
+		Card:       s.webhookCard(cardID),
+	}
+	if extra != nil {
+		extra(payload)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		s.log.Error("Failed to encode webhook payload", "event", eventKey, "cardID", cardID, "error", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, webhook := range subscribed {
+		delivery := &WebhookDelivery{
+			ID:          s.nextDeliveryID,
+			WebhookID:   webhook.ID,
+			BoardID:     boardID,
+			Event:       eventKey,
+			Payload:     body,
+			Status:      DeliveryPending,
+			NextAttempt: now,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		s.deliveries[delivery.ID] = delivery
+		s.order = append(s.order, delivery.ID)
+		s.nextDeliveryID++
+	}
+	s.trim()
+	s.signal()
+}
+
+// webhookCard describes the card as it is now, or as it was when it went in the trash
+func (s *WebhookService) webhookCard(cardID int) *WebhookCard {
+	var card Card
+	if live, err := s.cardService.GetCard(cardID); err == nil {
+		card = *live
+	} else if trashed, err := s.cardService.GetTrashedCard(cardID); err == nil {
+		card = trashed.Card
+	} else {
+		return &WebhookCard{ID: cardID, Labels: []string{}}
+	}
+	return newWebhookCard(card)
+}
+
+func newWebhookCard(card Card) *WebhookCard {
+	webhookCard := &WebhookCard{
+		ID:       card.ID,
+		Title:    card.Title,
+		Content:  card.Content,
+		ColumnID: card.ColumnID,
+		LaneID:   card.LaneID,
+		Labels:   append([]string{}, card.Labels...),
+		Assignee: card.Assignee,
+	}
+	if card.HasDueDate() {
+		webhookCard.DueAt = card.DueAt.Format(DueDateLayout)
+	}
+	return webhookCard
+}
+
+// trim forgets the oldest finished deliveries once the log is full; pending ones are always kept
+func (s *WebhookService) trim() {
+	excess := len(s.order) - webhookDeliveryLimit
+	if excess <= 0 {
+		return
+	}
+
+	kept := s.order[:0]
+	for _, deliveryID := range s.order {
+		if excess > 0 && s.deliveries[deliveryID].Status != DeliveryPending {
+			delete(s.deliveries, deliveryID)
+			excess--
+			continue
+		}
+		kept = append(kept, deliveryID)
+	}
+	s.order = kept
+}
+
+// signal wakes the delivery loop without blocking if it's already been woken
+func (s *WebhookService) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// DeliverDue attempts every pending delivery whose next attempt is due by now. Each webhook's deliveries go out
+// in order on a goroutine of their own, so a slow receiver only holds up itself, and webhooks still busy with an
+// earlier round are left until it's done. It returns once this round's attempts have finished.
+func (s *WebhookService) DeliverDue(now time.Time) {
+	s.mu.Lock()
+	webhooks := make(map[int]Webhook)
+	due := make(map[int][]WebhookDelivery) // webhookID -> deliveries, oldest first
+	for _, deliveryID := range s.order {
+		delivery := s.deliveries[deliveryID]
+		if delivery.Status != DeliveryPending || delivery.NextAttempt.After(now) {
+			continue
+		}
+		webhook, exists := s.webhooks[delivery.WebhookID]
+		if !exists {
+			delivery.Status = DeliveryDead
+			delivery.LastError = "webhook was deleted"
+			continue
+		}
+		if s.sending[webhook.ID] {
+			continue
+		}
+		webhooks[webhook.ID] = *webhook
+		due[webhook.ID] = append(due[webhook.ID], *delivery)
+	}
+	for webhookID := range due {
+		s.sending[webhookID] = true
+	}
+	s.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for webhookID, deliveries := range due {
+		wg.Add(1)
+		go func(webhook Webhook, deliveries []WebhookDelivery) {
+			defer wg.Done()
+			for _, delivery := range deliveries {
+				statusCode, err := s.send(&webhook, &delivery)
+				s.finish(delivery.ID, statusCode, err)
+			}
+
+			s.mu.Lock()
+			delete(s.sending, webhook.ID)
+			s.mu.Unlock()
+		}(webhooks[webhookID], deliveries)
+	}
+	wg.Wait()
+}
+
+func (s *WebhookService) send(webhook *Webhook, delivery *WebhookDelivery) (int, error) {
+	request, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "mesh-webhooks")
+	request.Header.Set(WebhookEventHeader, delivery.Event)
+	request.Header.Set(WebhookDeliveryHeader, strconv.Itoa(delivery.ID))
+	request.Header.Set(WebhookSignatureHeader, SignWebhookPayload(webhook.Secret, delivery.Payload))
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("receiver responded %s", response.Status)
+	}
+	return response.StatusCode, nil
+}
+
+// finish records the outcome of an attempt, scheduling a retry or giving up on the delivery
+func (s *WebhookService) finish(deliveryID, statusCode int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delivery, exists := s.deliveries[deliveryID]
+	if !exists {
+		return
+	}
+
+	now := time.Now()
+	delivery.Attempts++
+	delivery.StatusCode = statusCode
+	delivery.UpdatedAt = now
+
+	if err == nil {
+		delivery.Status = DeliveryDelivered
+		delivery.LastError = ""
+		return
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= s.maxAttempts {
+		delivery.Status = DeliveryDead
+		s.log.Warn("Webhook delivery failed for good", "deliveryID", delivery.ID, "webhookID", delivery.WebhookID, "attempts", delivery.Attempts, "error", err)
+		return
+	}
+
+	delivery.NextAttempt = now.Add(s.backoffFor(delivery.Attempts))
+	s.log.Info("Webhook delivery failed, will retry", "deliveryID", delivery.ID, "webhookID", delivery.WebhookID, "attempts", delivery.Attempts, "nextAttempt", delivery.NextAttempt, "error", err)
+}
+
+// backoffFor doubles the wait after each failed attempt, up to maxBackoff
+func (s *WebhookService) backoffFor(attempts int) time.Duration {
+	wait := s.backoff
+	for i := 1; i < attempts && wait < s.maxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, s.maxBackoff)
+}
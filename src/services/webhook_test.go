@@ -0,0 +1,261 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testBoardDocument = `{
+  "version": 1,
+  "board": {
+    "id": 1,
+    "title": "Board",
+    "columns": [{"id": 1, "title": "To Do"}, {"id": 2, "title": "Done"}],
+    "lanes": [{"id": 1, "title": "Product"}],
+    "cards": [{"id": 1, "title": "Write tests", "columnID": 1, "laneID": 1}]
+  }
+}`
+
+func newTestLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// newTestCardService returns a card service holding testBoardDocument, with nothing subscribed to its events
+func newTestCardService(t *testing.T) (*CardService, *EventService) {
+	t.Helper()
+
+	events := NewSynchronousEventService(newTestLogger())
+	return newTestCardServiceWith(t, events), events
+}
+
+// newTestCardServiceWith returns a card service holding testBoardDocument that publishes on the given events
+func newTestCardServiceWith(t *testing.T, events *EventService) *CardService {
+	t.Helper()
+
+	cards := newCardService(newTestLogger(), events, nil)
+	document, err := ParseBoardDocument([]byte(testBoardDocument))
+	if err != nil {
+		t.Fatalf("parsing test board: %v", err)
+	}
+	if _, err := cards.ImportBoard(document); err != nil {
+		t.Fatalf("importing test board: %v", err)
+	}
+	return cards
+}
+
+func newTestWebhookService(t *testing.T, maxAttempts int, backoff time.Duration) *WebhookService {
+	t.Helper()
+
+	cards, events := newTestCardService(t)
+	return NewWebhookService(newTestLogger(), cards, events, &Config{
+		WebhookTimeout:     5 * time.Second,
+		WebhookMaxAttempts: maxAttempts,
+		WebhookBackoff:     backoff,
+	})
+}
+
+// receiver is an httptest server answering each request with the next status, repeating the last one
+type receiver struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	t.Helper()
+
+	r := &receiver{statuses: statuses}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+		body, _ := io.ReadAll(request.Body)
+
+		r.mu.Lock()
+		status := r.statuses[min(len(r.requests), len(r.statuses)-1)]
+		r.requests = append(r.requests, request)
+		r.bodies = append(r.bodies, body)
+		r.mu.Unlock()
+
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+func moveTestCard(service *WebhookService) {
+	service.OnCardMoved(&CardMovedEvent{
+		Actor:        "alice",
+		CardID:       1,
+		FromColumnID: 1,
+		FromLaneID:   1,
+		ToColumnID:   2,
+		ToLaneID:     1,
+	})
+}
+
+func onlyDelivery(t *testing.T, service *WebhookService) WebhookDelivery {
+	t.Helper()
+
+	deliveries := service.GetDeliveries(1, "", 10)
+	if len(deliveries) != 1 {
+		t.Fatalf("got %d deliveries, want 1", len(deliveries))
+	}
+	return deliveries[0]
+}
+
+func TestWebhookSignature(t *testing.T) {
+	service := newTestWebhookService(t, 3, time.Minute)
+	server := newReceiver(t, http.StatusOK)
+
+	webhook, err := service.AddWebhook(1, server.URL, "s3cret", []string{CardMovedEventKey})
+	if err != nil {
+		t.Fatalf("AddWebhook: %v", err)
+	}
+	moveTestCard(service)
+	service.DeliverDue(time.Now())
+
+	if server.count() != 1 {
+		t.Fatalf("receiver got %d requests, want 1", server.count())
+	}
+	request, body := server.requests[0], server.bodies[0]
+
+	mac := hmac.New(sha256.New, []byte(webhook.Secret))
+	mac.Write(body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got := request.Header.Get(WebhookSignatureHeader); got != want {
+		t.Errorf("%s = %q, want %q", WebhookSignatureHeader, got, want)
+	}
+	if got := request.Header.Get(WebhookEventHeader); got != CardMovedEventKey {
+		t.Errorf("%s = %q, want %q", WebhookEventHeader, got, CardMovedEventKey)
+	}
+
+	var payload WebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("decoding payload: %v", err)
+	}
+	if payload.Card == nil || payload.Card.Title != "Write tests" || payload.To == nil || payload.To.ColumnID != 2 {
+		t.Errorf("payload = %s, want the card moved to column 2", body)
+	}
+
+	if delivery := onlyDelivery(t, service); delivery.Status != DeliveryDelivered || delivery.Attempts != 1 {
+		t.Errorf("delivery is %s after %d attempts, want delivered after 1", delivery.Status, delivery.Attempts)
+	}
+}
+
+func TestWebhookRetriesWithBackoff(t *testing.T) {
+	service := newTestWebhookService(t, 5, time.Minute)
+	server := newReceiver(t, http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK)
+
+	if _, err := service.AddWebhook(1, server.URL, "", []string{CardMovedEventKey}); err != nil {
+		t.Fatalf("AddWebhook: %v", err)
+	}
+	moveTestCard(service)
+
+	service.DeliverDue(time.Now())
+	for attempt, wantWait := range []time.Duration{time.Minute, 2 * time.Minute} {
+		delivery := onlyDelivery(t, service)
+		if delivery.Status != DeliveryPending || delivery.Attempts != attempt+1 {
+			t.Fatalf("delivery is %s after %d attempts, want pending after %d", delivery.Status, delivery.Attempts, attempt+1)
+		}
+		if wait := delivery.NextAttempt.Sub(delivery.UpdatedAt); wait != wantWait {
+			t.Errorf("after attempt %d the retry waits %s, want %s", attempt+1, wait, wantWait)
+		}
+
+		// Nothing is sent again until the retry is due
+		service.DeliverDue(delivery.NextAttempt.Add(-time.Second))
+		if server.count() != attempt+1 {
+			t.Fatalf("receiver got %d requests before the retry was due, want %d", server.count(), attempt+1)
+		}
+		service.DeliverDue(delivery.NextAttempt)
+	}
+
+	if delivery := onlyDelivery(t, service); delivery.Status != DeliveryDelivered || delivery.Attempts != 3 {
+		t.Errorf("delivery is %s after %d attempts, want delivered after 3", delivery.Status, delivery.Attempts)
+	}
+}
+
+func TestWebhookDeadLetter(t *testing.T) {
+	service := newTestWebhookService(t, 3, time.Second)
+	server := newReceiver(t, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusOK)
+
+	if _, err := service.AddWebhook(1, server.URL, "", []string{CardMovedEventKey}); err != nil {
+		t.Fatalf("AddWebhook: %v", err)
+	}
+	moveTestCard(service)
+
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		service.DeliverDue(now)
+		now = now.Add(time.Hour)
+	}
+
+	delivery := onlyDelivery(t, service)
+	if delivery.Status != DeliveryDead || delivery.Attempts != 3 {
+		t.Fatalf("delivery is %s after %d attempts, want dead after 3", delivery.Status, delivery.Attempts)
+	}
+	if !strings.Contains(delivery.LastError, "500") {
+		t.Errorf("last error = %q, want the receiver's 500", delivery.LastError)
+	}
+	if server.count() != 3 {
+		t.Errorf("receiver got %d requests, want 3", server.count())
+	}
+	if dead := service.GetDeliveries(1, DeliveryDead, 10); len(dead) != 1 {
+		t.Errorf("got %d dead letters, want 1", len(dead))
+	}
+
+	if err := service.Redeliver(delivery.ID); err != nil {
+		t.Fatalf("Redeliver: %v", err)
+	}
+	service.DeliverDue(time.Now())
+	if delivery := onlyDelivery(t, service); delivery.Status != DeliveryDelivered || delivery.Attempts != 1 {
+		t.Errorf("redelivery is %s after %d attempts, want delivered after 1", delivery.Status, delivery.Attempts)
+	}
+}
+
+func TestWebhookSlowReceiverDoesNotHoldUpOthers(t *testing.T) {
+	service := newTestWebhookService(t, 3, time.Minute)
+
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(slow.Close)
+	t.Cleanup(func() { close(release) })
+
+	received := make(chan struct{}, 1)
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+	}))
+	t.Cleanup(fast.Close)
+
+	for _, url := range []string{slow.URL, fast.URL} {
+		if _, err := service.AddWebhook(1, url, "", []string{CardMovedEventKey}); err != nil {
+			t.Fatalf("AddWebhook: %v", err)
+		}
+	}
+	moveTestCard(service)
+
+	go service.DeliverDue(time.Now())
+	select {
+	case <-received:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the fast receiver waited on the slow one")
+	}
+}
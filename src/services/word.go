@@ -5,24 +5,43 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
-	"regexp"
+	"os/signal"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 type WordService struct {
-	log       *slog.Logger
-	blacklist []string
-	filePath  string
+	mu         sync.RWMutex
+	words      *Matcher // whole word and stem entries, matched against the text's words
+	substrings *Matcher // substring entries, matched against the text with its spaces taken out
+	entries    int
+	fileStat   os.FileInfo // the blacklist file as it was last read, to notice when it changes
+	leetspeak  leetspeak
+
+	log          *slog.Logger
+	filePath     string
+	pollInterval time.Duration
 }
 
-// NewWordService creates a new word service and loads the blacklist from the specified file
-func NewWordService(log *slog.Logger, blacklistFilePath string) (*WordService, error) {
+// NewWordService creates a new word service and loads the blacklist from the configured file
+func NewWordService(log *slog.Logger, config *Config) (*WordService, error) {
+	leetspeak, err := parseLeetspeak(config.Leetspeak)
+	if err != nil {
+		return nil, err
+	}
+
 	service := &WordService{
-		log:      log,
-		filePath: blacklistFilePath,
+		leetspeak:    leetspeak,
+		log:          log,
+		filePath:     config.BlacklistPath,
+		pollInterval: config.BlacklistPollInterval,
 	}
 
-	err := service.loadBlacklist()
+	err = service.loadBlacklist()
 	if err != nil {
 		return nil, err
 	}
@@ -30,7 +49,50 @@ func NewWordService(log *slog.Logger, blacklistFilePath string) (*WordService, e
 	return service, nil
 }
 
-// loadBlacklist reads the newline-delimited blacklist file
+// Start reloads the blacklist when its file changes, checking every poll interval unless that's zero, and
+// whenever the process is sent SIGHUP
+func (w *WordService) Start() {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+
+	go func() {
+		var ticks <-chan time.Time
+		if w.pollInterval > 0 {
+			ticker := time.NewTicker(w.pollInterval)
+			defer ticker.Stop()
+			ticks = ticker.C
+		}
+
+		for {
+			select {
+			case <-ticks:
+				if w.fileChanged() {
+					w.log.Info("Blacklist file changed", "path", w.filePath)
+					w.ReloadBlacklist()
+				}
+			case <-hangups:
+				w.log.Info("Reloading blacklist on SIGHUP", "path", w.filePath)
+				w.ReloadBlacklist()
+			}
+		}
+	}()
+}
+
+// fileChanged says whether the blacklist file has been modified, replaced or removed since it was last read
+func (w *WordService) fileChanged() bool {
+	stat, err := os.Stat(w.filePath)
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if err != nil {
+		return w.fileStat != nil
+	}
+	return w.fileStat == nil || !stat.ModTime().Equal(w.fileStat.ModTime()) || stat.Size() != w.fileStat.Size()
+}
+
+// loadBlacklist reads the newline-delimited blacklist file and builds the matchers for it. The matchers are only
+// swapped in once the whole file has been read and found valid, so a bad file leaves the previous list in place.
 func (w *WordService) loadBlacklist() error {
 	file, err := os.Open(w.filePath)
 	if err != nil {
@@ -39,110 +101,260 @@ func (w *WordService) loadBlacklist() error {
 	}
 	defer file.Close()
 
-	var words []string
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("could not read blacklist file: %w", err)
+	}
+	// Remember the file even if it's invalid, so that polling waits for it to change again before retrying
+	w.mu.Lock()
+	w.fileStat = stat
+	w.mu.Unlock()
+
+	var words, substrings []BlacklistEntry
 	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		word := strings.TrimSpace(scanner.Text())
-		if word != "" {
-			words = append(words, strings.ToLower(word))
+	for line := 1; scanner.Scan(); line++ {
+		if !utf8.ValidString(scanner.Text()) {
+			return fmt.Errorf("blacklist line %d is not valid UTF-8", line)
+		}
+		entry, ok := ParseBlacklistEntry(scanner.Text())
+		if !ok {
+			continue
+		}
+		// Entries are normalised like the text they're matched against, so "Café" blocks "cafe" too
+		entry.Word = w.toWords(w.normalizeToASCII(entry.Word))
+		switch {
+		case entry.Word == "":
+			return fmt.Errorf("blacklist line %d has no letters or digits to match: %q", line, scanner.Text())
+		case entry.Mode == MatchSubstring:
+			entry.Word = strings.ReplaceAll(entry.Word, " ", "")
+			substrings = append(substrings, entry)
+		default:
+			words = append(words, entry)
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		return err
+		return fmt.Errorf("could not read blacklist file: %w", err)
 	}
 
-	w.blacklist = words
-	w.log.Info("Loaded blacklist", "word_count", len(words))
+	wordMatcher, substringMatcher := NewMatcher(words), NewMatcher(substrings)
+	w.mu.Lock()
+	w.words, w.substrings = wordMatcher, substringMatcher
+	w.entries = len(words) + len(substrings)
+	w.mu.Unlock()
+
+	w.log.Info("Loaded blacklist", "word_count", len(words)+len(substrings), "substrings", len(substrings))
 	return nil
 }
 
+// EntryCount is how many entries the blacklist in use has
+func (w *WordService) EntryCount() int {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return w.entries
+}
+
 // Filter processes the input string and returns the first blacklisted word found, or empty string if none
 func (w *WordService) Filter(input string) string {
+	return w.FilterAllowing(input, nil)
+}
+
+// FilterAllowing is Filter for a board that allows some words the blacklist would otherwise match, like "class"
+// on a board that blocks ass
+func (w *WordService) FilterAllowing(input string, allowedWords []string) string {
 	if input == "" {
 		return ""
 	}
 
-	// Step 1: Translate non-ASCII characters to ASCII equivalents
-	normalized := w.normalizeToASCII(input)
+	allowed := w.allowlist(allowedWords)
+	for _, reading := range w.read(input) {
+		var word string
+		w.findMatches(reading.words, allowed, func(entry BlacklistEntry, _, _ int) bool {
+			word = entry.Word
+			return true
+		})
+		if word != "" {
+			return word
+		}
+	}
+	return ""
+}
 
-	// Step 2: Remove all non-alphanumeric characters
-	alphanumeric := w.removeNonAlphanumeric(normalized)
+// Mask returns the input with every character of every blacklisted word it contains replaced with an asterisk,
+// leaving the words the board allows
+func (w *WordService) Mask(input string, allowedWords []string) string {
+	if input == "" {
+		return ""
+	}
 
-	// Step 3: Compare against blacklist
-	return w.checkAgainstBlacklist(alphanumeric)
+	masked := make([]bool, len(input)) // byte offset of a rune in the input -> whether it's part of a match
+	allowed := w.allowlist(allowedWords)
+	for _, reading := range w.read(input) {
+		w.findMatches(reading.words, allowed, func(_ BlacklistEntry, start, end int) bool {
+			for i := start; i < end; i++ {
+				if reading.words[i] != ' ' {
+					masked[reading.sources[i]] = true
+				}
+			}
+			return false
+		})
+	}
+
+	var result strings.Builder
+	previous := false
+	for i, r := range input {
+		switch {
+		case masked[i]:
+			result.WriteByte('*')
+		case previous && unicode.Is(unicode.Mn, r):
+			continue // an accent on a masked letter goes with it
+		default:
+			result.WriteRune(r)
+		}
+		previous = masked[i]
+	}
+	return result.String()
+}
+
+// reading is the input reduced to lowercase words separated by single spaces, read one way, along with where in
+// the input each of its bytes came from
+type reading struct {
+	words   string
+	sources []int // byte of words -> byte offset of the rune in the input it was read from
+}
+
+// read returns the ways the input is checked: translated to ASCII and read as written, then as leetspeak
+func (w *WordService) read(input string) []reading {
+	folded := make([]byte, 0, len(input))
+	sources := make([]int, 0, len(input))
+	for i, r := range input {
+		fold := foldRune(r, true)
+		folded = append(folded, fold...)
+		for range len(fold) {
+			sources = append(sources, i)
+		}
+	}
+
+	var readings []reading
+	for _, variant := range w.leetspeak.variants(string(folded)) {
+		words, wordSources := toWords(variant, sources)
+		readings = append(readings, reading{words: words, sources: wordSources})
+	}
+	return readings
 }
 
-// normalizeToASCII converts non-ASCII characters to their ASCII equivalents
+// normalizeToASCII converts the input to ASCII, reading accented letters without their accents and lookalikes
+// from other scripts as the letters they imitate
 func (w *WordService) normalizeToASCII(input string) string {
-	var result strings.Builder
+	return foldToASCII(input, true)
+}
 
-	for _, r := range input {
-		// Simple ASCII conversion for common accented characters
-		switch r {
-		case 'á', 'à', 'â', 'ä', 'ã', 'å', 'ā', 'ă', 'ą':
-			result.WriteRune('a')
-		case 'Á', 'À', 'Â', 'Ä', 'Ã', 'Å', 'Ā', 'Ă', 'Ą':
-			result.WriteRune('A')
-		case 'é', 'è', 'ê', 'ë', 'ē', 'ĕ', 'ė', 'ę', 'ě':
-			result.WriteRune('e')
-		case 'É', 'È', 'Ê', 'Ë', 'Ē', 'Ĕ', 'Ė', 'Ę', 'Ě':
-			result.WriteRune('E')
-		case 'í', 'ì', 'î', 'ï', 'ĩ', 'ī', 'ĭ', 'į':
-			result.WriteRune('i')
-		case 'Í', 'Ì', 'Î', 'Ï', 'Ĩ', 'Ī', 'Ĭ', 'Į':
-			result.WriteRune('I')
-		case 'ó', 'ò', 'ô', 'ö', 'õ', 'ō', 'ŏ', 'ő':
-			result.WriteRune('o')
-		case 'Ó', 'Ò', 'Ô', 'Ö', 'Õ', 'Ō', 'Ŏ', 'Ő':
-			result.WriteRune('O')
-		case 'ú', 'ù', 'û', 'ü', 'ũ', 'ū', 'ŭ', 'ů', 'ű', 'ų':
-			result.WriteRune('u')
-		case 'Ú', 'Ù', 'Û', 'Ü', 'Ũ', 'Ū', 'Ŭ', 'Ů', 'Ű', 'Ų':
-			result.WriteRune('U')
-		case 'ç', 'ć', 'ĉ', 'ċ', 'č':
-			result.WriteRune('c')
-		case 'Ç', 'Ć', 'Ĉ', 'Ċ', 'Č':
-			result.WriteRune('C')
-		case 'ñ', 'ń', 'ņ', 'ň':
-			result.WriteRune('n')
-		case 'Ñ', 'Ń', 'Ņ', 'Ň':
-			result.WriteRune('N')
-		case 'ý', 'ÿ', 'ŷ':
-			result.WriteRune('y')
-		case 'Ý', 'Ÿ', 'Ŷ':
-			result.WriteRune('Y')
-		default:
-			// If it's ASCII or not handled, keep as is
-			if r <= 127 {
-				result.WriteRune(r)
+// toWords lowercases the input and turns every run of anything but letters and digits into a single space
+func (w *WordService) toWords(input string) string {
+	words, _ := toWords(input, nil)
+	return words
+}
+
+// toWords is WordService.toWords keeping track of where each byte came from, given where each byte of the input
+// came from
+func toWords(input string, sources []int) (string, []int) {
+	var result strings.Builder
+	result.Grow(len(input))
+	var resultSources []int
+	space := false
+	for i := 0; i < len(input); i++ {
+		b := input[i]
+		if b >= 'A' && b <= 'Z' {
+			b += 'a' - 'A'
+		}
+		if (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9') {
+			if space && result.Len() > 0 {
+				result.WriteByte(' ')
+				if sources != nil {
+					resultSources = append(resultSources, sources[i])
+				}
+			}
+			result.WriteByte(b)
+			if sources != nil {
+				resultSources = append(resultSources, sources[i])
 			}
-			// Skip non-ASCII characters that aren't handled above
+			space = false
+		} else {
+			space = true
 		}
 	}
+	return result.String(), resultSources
+}
 
-	return result.String()
+// allowlist is the words and phrases, normalised like the text, that a board allows even though the blacklist
+// matches them
+type allowlist map[string]bool
+
+func (w *WordService) allowlist(words []string) allowlist {
+	allowed := allowlist{}
+	for _, word := range words {
+		if normalized := w.toWords(w.normalizeToASCII(word)); normalized != "" {
+			allowed[normalized] = true
+		}
+	}
+	return allowed
 }
 
-// removeNonAlphanumeric removes all non-alphanumeric characters and converts to lowercase
-func (w *WordService) removeNonAlphanumeric(input string) string {
-	// Use regex to keep only alphanumeric characters
-	reg := regexp.MustCompile("[^a-zA-Z0-9]+")
-	cleaned := reg.ReplaceAllString(input, "")
-	return strings.ToLower(cleaned)
+// covers says whether the whole words around the bytes from start to end are an allowed word or phrase
+func (a allowlist) covers(words string, start, end int) bool {
+	if len(a) == 0 {
+		return false
+	}
+	for start > 0 && words[start-1] != ' ' {
+		start--
+	}
+	for end < len(words) && words[end] != ' ' {
+		end++
+	}
+	return a[words[start:end]]
 }
 
-// checkAgainstBlacklist checks if any blacklisted word appears as a substring
-func (w *WordService) checkAgainstBlacklist(input string) string {
-	for _, blacklistedWord := range w.blacklist {
-		if strings.Contains(input, blacklistedWord) {
-			return blacklistedWord
+// findMatches gives found each blacklist entry in the words, with the bytes of the words it covers, until found
+// returns true. Whole word and stem entries are looked for among the words, then substring entries in the words
+// run together, which is how spaced out or punctuated words are caught. Matches within allowed words are skipped.
+func (w *WordService) findMatches(words string, allowed allowlist, found func(entry BlacklistEntry, start, end int) bool) {
+	w.mu.RLock()
+	wordMatcher, substringMatcher := w.words, w.substrings
+	w.mu.RUnlock()
+
+	if _, stopped := wordMatcher.Find(words, func(entry BlacklistEntry, start, end int) bool {
+		if start > 0 && words[start-1] != ' ' {
+			return false
+		}
+		if entry.Mode != MatchStem && end < len(words) && words[end] != ' ' {
+			return false
 		}
+		return !allowed.covers(words, start, end) && found(entry, start, end)
+	}); stopped {
+		return
 	}
-	return ""
+
+	// Positions in the words run together are mapped back to the words, to check them against the allowed words
+	joined := make([]byte, 0, len(words))
+	positions := make([]int, 0, len(words))
+	for i := 0; i < len(words); i++ {
+		if words[i] != ' ' {
+			joined = append(joined, words[i])
+			positions = append(positions, i)
+		}
+	}
+	substringMatcher.Find(string(joined), func(entry BlacklistEntry, start, end int) bool {
+		start, end = positions[start], positions[end-1]+1
+		return !allowed.covers(words, start, end) && found(entry, start, end)
+	})
 }
 
-// ReloadBlacklist reloads the blacklist from the file
+// ReloadBlacklist reloads the blacklist from the file, keeping the current one if the file can't be used
 func (w *WordService) ReloadBlacklist() error {
-	return w.loadBlacklist()
+	if err := w.loadBlacklist(); err != nil {
+		w.log.Error("Kept the previous blacklist", "path", w.filePath, "error", err)
+		return err
+	}
+	return nil
 }
@@ -11,7 +11,7 @@ interface UpdateBatch {
 export class SSEManager {
     private eventSource: EventSource | null = null;
 
-    constructor(private url: string = '/sse?stream=oob-updates') {
+    constructor(private url: string = '/sse') {
         this.connect();
     }
 
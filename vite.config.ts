@@ -14,7 +14,21 @@ export default defineConfig({
                 app: 'src/components/app/app.scss',
                 board: 'src/components/board/board.scss',
                 column: 'src/components/column/column.scss',
+                cell: 'src/components/cell/cell.scss',
                 card: 'src/components/card/card.scss',
+                activity: 'src/components/activity/activity.scss',
+                undo: 'src/components/undo/undo.scss',
+                trash: 'src/components/trash/trash.scss',
+                archive: 'src/components/archive/archive.scss',
+                moderation: 'src/components/moderation/moderation.scss',
+                admin: 'src/components/admin/admin.scss',
+                search: 'src/components/search/search.scss',
+                filter: 'src/components/filter/filter.scss',
+                templates: 'src/components/templates/templates.scss',
+                automations: 'src/components/automations/automations.scss',
+                webhooks: 'src/components/webhooks/webhooks.scss',
+                inbound: 'src/components/inbound/inbound.scss',
+                analytics: 'src/components/analytics/analytics.scss',
             },
             output: {
                 entryFileNames: 'js/[name].[hash].js',